@@ -0,0 +1,342 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://www.nesdev.org/wiki/Family_Computer_Disk_System#Manufacturer_codes
+// FDS disks use the same licensee codes as the old-style Game Boy
+// cartridge header, so this table follows the commonly published
+// list of those codes.
+
+package FDSTool
+
+type FDSManufacturerCode uint8
+
+const (
+	FDS_MANUFACTURER_UNLICENSED                  FDSManufacturerCode = 0x00
+	FDS_MANUFACTURER_NINTENDO                    FDSManufacturerCode = 0x01
+	FDS_MANUFACTURER_CAPCOM                      FDSManufacturerCode = 0x08
+	FDS_MANUFACTURER_HOT_B                       FDSManufacturerCode = 0x09
+	FDS_MANUFACTURER_JALECO                      FDSManufacturerCode = 0x0A
+	FDS_MANUFACTURER_COCONUTS_JAPAN              FDSManufacturerCode = 0x0B
+	FDS_MANUFACTURER_ELITE_SYSTEMS               FDSManufacturerCode = 0x0C
+	FDS_MANUFACTURER_ELECTRONIC_ARTS             FDSManufacturerCode = 0x13
+	FDS_MANUFACTURER_HUDSON_SOFT                 FDSManufacturerCode = 0x18
+	FDS_MANUFACTURER_ITC_ENTERTAINMENT           FDSManufacturerCode = 0x19
+	FDS_MANUFACTURER_YANOMAN                     FDSManufacturerCode = 0x1A
+	FDS_MANUFACTURER_CLARY                       FDSManufacturerCode = 0x1D
+	FDS_MANUFACTURER_VIRGIN_GAMES                FDSManufacturerCode = 0x1F
+	FDS_MANUFACTURER_PCM_COMPLETE                FDSManufacturerCode = 0x24
+	FDS_MANUFACTURER_SAN_X                       FDSManufacturerCode = 0x25
+	FDS_MANUFACTURER_KOTOBUKI_SYSTEMS            FDSManufacturerCode = 0x28
+	FDS_MANUFACTURER_SETA                        FDSManufacturerCode = 0x29
+	FDS_MANUFACTURER_INFOGRAMES                  FDSManufacturerCode = 0x30
+	FDS_MANUFACTURER_NINTENDO_31                 FDSManufacturerCode = 0x31
+	FDS_MANUFACTURER_BANDAI                      FDSManufacturerCode = 0x32
+	FDS_MANUFACTURER_KONAMI                      FDSManufacturerCode = 0x34
+	FDS_MANUFACTURER_HECTORSOFT                  FDSManufacturerCode = 0x35
+	FDS_MANUFACTURER_CAPCOM_38                   FDSManufacturerCode = 0x38
+	FDS_MANUFACTURER_BANPRESTO                   FDSManufacturerCode = 0x39
+	FDS_MANUFACTURER_ENTERTAINMENT_INTERNATIONAL FDSManufacturerCode = 0x3C
+	FDS_MANUFACTURER_GREMLIN                     FDSManufacturerCode = 0x3E
+	FDS_MANUFACTURER_UBISOFT                     FDSManufacturerCode = 0x41
+	FDS_MANUFACTURER_ATLUS                       FDSManufacturerCode = 0x42
+	FDS_MANUFACTURER_MALIBU_INTERACTIVE          FDSManufacturerCode = 0x44
+	FDS_MANUFACTURER_ANGEL                       FDSManufacturerCode = 0x46
+	FDS_MANUFACTURER_SPECTRUM_HOLOBYTE           FDSManufacturerCode = 0x47
+	FDS_MANUFACTURER_IREM                        FDSManufacturerCode = 0x49
+	FDS_MANUFACTURER_VIRGIN_GAMES_4A             FDSManufacturerCode = 0x4A
+	FDS_MANUFACTURER_MALIBU_INTERACTIVE_4D       FDSManufacturerCode = 0x4D
+	FDS_MANUFACTURER_US_GOLD                     FDSManufacturerCode = 0x4F
+	FDS_MANUFACTURER_ABSOLUTE                    FDSManufacturerCode = 0x50
+	FDS_MANUFACTURER_ACCLAIM                     FDSManufacturerCode = 0x51
+	FDS_MANUFACTURER_ACTIVISION                  FDSManufacturerCode = 0x52
+	FDS_MANUFACTURER_SAMMY_USA                   FDSManufacturerCode = 0x53
+	FDS_MANUFACTURER_GAMETEK                     FDSManufacturerCode = 0x54
+	FDS_MANUFACTURER_PARK_PLACE                  FDSManufacturerCode = 0x55
+	FDS_MANUFACTURER_LJN                         FDSManufacturerCode = 0x56
+	FDS_MANUFACTURER_MATCHBOX                    FDSManufacturerCode = 0x57
+	FDS_MANUFACTURER_MILTON_BRADLEY              FDSManufacturerCode = 0x59
+	FDS_MANUFACTURER_MINDSCAPE                   FDSManufacturerCode = 0x5A
+	FDS_MANUFACTURER_ROMSTAR                     FDSManufacturerCode = 0x5B
+	FDS_MANUFACTURER_NAXAT_SOFT                  FDSManufacturerCode = 0x5C
+	FDS_MANUFACTURER_TRADEWEST                   FDSManufacturerCode = 0x5D
+	FDS_MANUFACTURER_TITUS                       FDSManufacturerCode = 0x60
+	FDS_MANUFACTURER_VIRGIN_GAMES_61             FDSManufacturerCode = 0x61
+	FDS_MANUFACTURER_OCEAN                       FDSManufacturerCode = 0x67
+	FDS_MANUFACTURER_ELECTRONIC_ARTS_69          FDSManufacturerCode = 0x69
+	FDS_MANUFACTURER_ELITE_SYSTEMS_6E            FDSManufacturerCode = 0x6E
+	FDS_MANUFACTURER_ELECTRO_BRAIN               FDSManufacturerCode = 0x6F
+	FDS_MANUFACTURER_INFOGRAMES_70               FDSManufacturerCode = 0x70
+	FDS_MANUFACTURER_INTERPLAY                   FDSManufacturerCode = 0x71
+	FDS_MANUFACTURER_BRODERBUND                  FDSManufacturerCode = 0x72
+	FDS_MANUFACTURER_SCULPTURED_SOFTWARE         FDSManufacturerCode = 0x73
+	FDS_MANUFACTURER_THE_SALES_CURVE             FDSManufacturerCode = 0x75
+	FDS_MANUFACTURER_THQ                         FDSManufacturerCode = 0x78
+	FDS_MANUFACTURER_ACCOLADE                    FDSManufacturerCode = 0x79
+	FDS_MANUFACTURER_TRIFFIX_ENTERTAINMENT       FDSManufacturerCode = 0x7A
+	FDS_MANUFACTURER_MICROPROSE                  FDSManufacturerCode = 0x7C
+	FDS_MANUFACTURER_KEMCO                       FDSManufacturerCode = 0x7F
+	FDS_MANUFACTURER_MISAWA_ENTERTAINMENT        FDSManufacturerCode = 0x80
+	FDS_MANUFACTURER_LOZC                        FDSManufacturerCode = 0x83
+	FDS_MANUFACTURER_TOKUMA_SHOTEN               FDSManufacturerCode = 0x86
+	FDS_MANUFACTURER_BULLET_PROOF_SOFTWARE       FDSManufacturerCode = 0x8B
+	FDS_MANUFACTURER_VIC_TOKAI                   FDSManufacturerCode = 0x8C
+	FDS_MANUFACTURER_APE                         FDSManufacturerCode = 0x8E
+	FDS_MANUFACTURER_IMAX                        FDSManufacturerCode = 0x8F
+	FDS_MANUFACTURER_CHUNSOFT                    FDSManufacturerCode = 0x91
+	FDS_MANUFACTURER_VIDEO_SYSTEM                FDSManufacturerCode = 0x92
+	FDS_MANUFACTURER_TSUBURAYA_PRODUCTIONS       FDSManufacturerCode = 0x93
+	FDS_MANUFACTURER_VARIE                       FDSManufacturerCode = 0x95
+	FDS_MANUFACTURER_YONEZAWA_SPAL               FDSManufacturerCode = 0x96
+	FDS_MANUFACTURER_KANEKO                      FDSManufacturerCode = 0x97
+	FDS_MANUFACTURER_PACK_IN_VIDEO               FDSManufacturerCode = 0x99
+	FDS_MANUFACTURER_NICHIBUTSU                  FDSManufacturerCode = 0x9A
+	FDS_MANUFACTURER_TECMO                       FDSManufacturerCode = 0x9B
+	FDS_MANUFACTURER_IMAGINEER                   FDSManufacturerCode = 0x9C
+	FDS_MANUFACTURER_BANPRESTO_9D                FDSManufacturerCode = 0x9D
+	FDS_MANUFACTURER_NOVA                        FDSManufacturerCode = 0x9F
+	FDS_MANUFACTURER_HORI_ELECTRIC               FDSManufacturerCode = 0xA1
+	FDS_MANUFACTURER_BANDAI_A2                   FDSManufacturerCode = 0xA2
+	FDS_MANUFACTURER_KONAMI_A4                   FDSManufacturerCode = 0xA4
+	FDS_MANUFACTURER_KAWADA                      FDSManufacturerCode = 0xA6
+	FDS_MANUFACTURER_TAKARA                      FDSManufacturerCode = 0xA7
+	FDS_MANUFACTURER_TECHNOS_JAPAN               FDSManufacturerCode = 0xA9
+	FDS_MANUFACTURER_VICTOR_MUSICAL_INDUSTRIES   FDSManufacturerCode = 0xAA
+	FDS_MANUFACTURER_TOEI_ANIMATION              FDSManufacturerCode = 0xAC
+	FDS_MANUFACTURER_TOHO                        FDSManufacturerCode = 0xAD
+	FDS_MANUFACTURER_NAMCO                       FDSManufacturerCode = 0xAF
+	FDS_MANUFACTURER_ACCLAIM_B0                  FDSManufacturerCode = 0xB0
+	FDS_MANUFACTURER_ASCII                       FDSManufacturerCode = 0xB1
+	FDS_MANUFACTURER_BANDAI_B2                   FDSManufacturerCode = 0xB2
+	FDS_MANUFACTURER_ENIX                        FDSManufacturerCode = 0xB4
+	FDS_MANUFACTURER_HAL_LABORATORY              FDSManufacturerCode = 0xB6
+	FDS_MANUFACTURER_SNK                         FDSManufacturerCode = 0xB7
+	FDS_MANUFACTURER_PONY_CANYON                 FDSManufacturerCode = 0xB9
+	FDS_MANUFACTURER_CULTURE_BRAIN               FDSManufacturerCode = 0xBA
+	FDS_MANUFACTURER_SUNSOFT                     FDSManufacturerCode = 0xBB
+	FDS_MANUFACTURER_TOSHIBA_EMI                 FDSManufacturerCode = 0xBC
+	FDS_MANUFACTURER_SONY_IMAGESOFT              FDSManufacturerCode = 0xBD
+	FDS_MANUFACTURER_SAMMY                       FDSManufacturerCode = 0xBF
+	FDS_MANUFACTURER_TAITO                       FDSManufacturerCode = 0xC0
+	FDS_MANUFACTURER_KEMCO_C2                    FDSManufacturerCode = 0xC2
+	FDS_MANUFACTURER_SQUARE                      FDSManufacturerCode = 0xC3
+	FDS_MANUFACTURER_TOKUMA_SHOTEN_C4            FDSManufacturerCode = 0xC4
+	FDS_MANUFACTURER_DATA_EAST                   FDSManufacturerCode = 0xC5
+	FDS_MANUFACTURER_TONKIN_HOUSE                FDSManufacturerCode = 0xC6
+	FDS_MANUFACTURER_KOEI                        FDSManufacturerCode = 0xC8
+	FDS_MANUFACTURER_UFL                         FDSManufacturerCode = 0xC9
+	FDS_MANUFACTURER_ULTRA_GAMES                 FDSManufacturerCode = 0xCA
+	FDS_MANUFACTURER_VAP                         FDSManufacturerCode = 0xCB
+	FDS_MANUFACTURER_USE_CORPORATION             FDSManufacturerCode = 0xCC
+	FDS_MANUFACTURER_MELDAC                      FDSManufacturerCode = 0xCD
+	FDS_MANUFACTURER_PONY_CANYON_CE              FDSManufacturerCode = 0xCE
+	FDS_MANUFACTURER_ANGEL_CF                    FDSManufacturerCode = 0xCF
+	FDS_MANUFACTURER_TAITO_D0                    FDSManufacturerCode = 0xD0
+	FDS_MANUFACTURER_SOFEL                       FDSManufacturerCode = 0xD1
+	FDS_MANUFACTURER_QUEST                       FDSManufacturerCode = 0xD2
+	FDS_MANUFACTURER_SIGMA_ENTERPRISES           FDSManufacturerCode = 0xD3
+	FDS_MANUFACTURER_ASK_KODANSHA                FDSManufacturerCode = 0xD4
+	FDS_MANUFACTURER_NAXAT_SOFT_D6               FDSManufacturerCode = 0xD6
+	FDS_MANUFACTURER_COPYA_SYSTEM                FDSManufacturerCode = 0xD7
+	FDS_MANUFACTURER_BANPRESTO_D9                FDSManufacturerCode = 0xD9
+	FDS_MANUFACTURER_TOMY                        FDSManufacturerCode = 0xDA
+	FDS_MANUFACTURER_LJN_DB                      FDSManufacturerCode = 0xDB
+	FDS_MANUFACTURER_NIPPON_COMPUTER_SYSTEMS     FDSManufacturerCode = 0xDD
+	FDS_MANUFACTURER_HUMAN_ENTERTAINMENT         FDSManufacturerCode = 0xDE
+	FDS_MANUFACTURER_ALTRON                      FDSManufacturerCode = 0xDF
+	FDS_MANUFACTURER_JALECO_E0                   FDSManufacturerCode = 0xE0
+	FDS_MANUFACTURER_TOWA_CHIKI                  FDSManufacturerCode = 0xE1
+	FDS_MANUFACTURER_YUTAKA                      FDSManufacturerCode = 0xE2
+	FDS_MANUFACTURER_VARIE_E3                    FDSManufacturerCode = 0xE3
+	FDS_MANUFACTURER_EPOCH                       FDSManufacturerCode = 0xE5
+	FDS_MANUFACTURER_ATHENA                      FDSManufacturerCode = 0xE7
+	FDS_MANUFACTURER_ASMIK                       FDSManufacturerCode = 0xE8
+	FDS_MANUFACTURER_NATSUME                     FDSManufacturerCode = 0xE9
+	FDS_MANUFACTURER_KING_RECORDS                FDSManufacturerCode = 0xEA
+	FDS_MANUFACTURER_ATLUS_EB                    FDSManufacturerCode = 0xEB
+	FDS_MANUFACTURER_EPIC_SONY_RECORDS           FDSManufacturerCode = 0xEC
+	FDS_MANUFACTURER_IGS                         FDSManufacturerCode = 0xEE
+	FDS_MANUFACTURER_A_WAVE                      FDSManufacturerCode = 0xF0
+	FDS_MANUFACTURER_EXTREME_ENTERTAINMENT       FDSManufacturerCode = 0xF3
+	FDS_MANUFACTURER_LJN_FF                      FDSManufacturerCode = 0xFF
+)
+
+var fdsManufacturerNames = map[FDSManufacturerCode]string{
+	FDS_MANUFACTURER_UNLICENSED:                  "<unlicensed>",
+	FDS_MANUFACTURER_NINTENDO:                    "Nintendo",
+	FDS_MANUFACTURER_CAPCOM:                      "Capcom",
+	FDS_MANUFACTURER_HOT_B:                       "Hot-B",
+	FDS_MANUFACTURER_JALECO:                      "Jaleco",
+	FDS_MANUFACTURER_COCONUTS_JAPAN:              "Coconuts Japan",
+	FDS_MANUFACTURER_ELITE_SYSTEMS:               "Elite Systems",
+	FDS_MANUFACTURER_ELECTRONIC_ARTS:             "Electronic Arts",
+	FDS_MANUFACTURER_HUDSON_SOFT:                 "Hudson Soft",
+	FDS_MANUFACTURER_ITC_ENTERTAINMENT:           "ITC Entertainment",
+	FDS_MANUFACTURER_YANOMAN:                     "Yanoman",
+	FDS_MANUFACTURER_CLARY:                       "Clary",
+	FDS_MANUFACTURER_VIRGIN_GAMES:                "Virgin Games",
+	FDS_MANUFACTURER_PCM_COMPLETE:                "PCM Complete",
+	FDS_MANUFACTURER_SAN_X:                       "San-X",
+	FDS_MANUFACTURER_KOTOBUKI_SYSTEMS:            "Kotobuki Systems",
+	FDS_MANUFACTURER_SETA:                        "SETA",
+	FDS_MANUFACTURER_INFOGRAMES:                  "Infogrames",
+	FDS_MANUFACTURER_NINTENDO_31:                 "Nintendo",
+	FDS_MANUFACTURER_BANDAI:                      "Bandai",
+	FDS_MANUFACTURER_KONAMI:                      "Konami",
+	FDS_MANUFACTURER_HECTORSOFT:                  "HectorSoft",
+	FDS_MANUFACTURER_CAPCOM_38:                   "Capcom",
+	FDS_MANUFACTURER_BANPRESTO:                   "Banpresto",
+	FDS_MANUFACTURER_ENTERTAINMENT_INTERNATIONAL: "Entertainment International",
+	FDS_MANUFACTURER_GREMLIN:                     "Gremlin",
+	FDS_MANUFACTURER_UBISOFT:                     "Ubisoft",
+	FDS_MANUFACTURER_ATLUS:                       "Atlus",
+	FDS_MANUFACTURER_MALIBU_INTERACTIVE:          "Malibu Interactive",
+	FDS_MANUFACTURER_ANGEL:                       "Angel",
+	FDS_MANUFACTURER_SPECTRUM_HOLOBYTE:           "Spectrum HoloByte",
+	FDS_MANUFACTURER_IREM:                        "Irem",
+	FDS_MANUFACTURER_VIRGIN_GAMES_4A:             "Virgin Games",
+	FDS_MANUFACTURER_MALIBU_INTERACTIVE_4D:       "Malibu Interactive",
+	FDS_MANUFACTURER_US_GOLD:                     "U.S. Gold",
+	FDS_MANUFACTURER_ABSOLUTE:                    "Absolute",
+	FDS_MANUFACTURER_ACCLAIM:                     "Acclaim",
+	FDS_MANUFACTURER_ACTIVISION:                  "Activision",
+	FDS_MANUFACTURER_SAMMY_USA:                   "Sammy USA",
+	FDS_MANUFACTURER_GAMETEK:                     "GameTek",
+	FDS_MANUFACTURER_PARK_PLACE:                  "Park Place",
+	FDS_MANUFACTURER_LJN:                         "LJN",
+	FDS_MANUFACTURER_MATCHBOX:                    "Matchbox",
+	FDS_MANUFACTURER_MILTON_BRADLEY:              "Milton Bradley",
+	FDS_MANUFACTURER_MINDSCAPE:                   "Mindscape",
+	FDS_MANUFACTURER_ROMSTAR:                     "Romstar",
+	FDS_MANUFACTURER_NAXAT_SOFT:                  "Naxat Soft",
+	FDS_MANUFACTURER_TRADEWEST:                   "Tradewest",
+	FDS_MANUFACTURER_TITUS:                       "Titus",
+	FDS_MANUFACTURER_VIRGIN_GAMES_61:             "Virgin Games",
+	FDS_MANUFACTURER_OCEAN:                       "Ocean",
+	FDS_MANUFACTURER_ELECTRONIC_ARTS_69:          "Electronic Arts",
+	FDS_MANUFACTURER_ELITE_SYSTEMS_6E:            "Elite Systems",
+	FDS_MANUFACTURER_ELECTRO_BRAIN:               "Electro Brain",
+	FDS_MANUFACTURER_INFOGRAMES_70:               "Infogrames",
+	FDS_MANUFACTURER_INTERPLAY:                   "Interplay",
+	FDS_MANUFACTURER_BRODERBUND:                  "Broderbund",
+	FDS_MANUFACTURER_SCULPTURED_SOFTWARE:         "Sculptured Software",
+	FDS_MANUFACTURER_THE_SALES_CURVE:             "The Sales Curve",
+	FDS_MANUFACTURER_THQ:                         "THQ",
+	FDS_MANUFACTURER_ACCOLADE:                    "Accolade",
+	FDS_MANUFACTURER_TRIFFIX_ENTERTAINMENT:       "Triffix Entertainment",
+	FDS_MANUFACTURER_MICROPROSE:                  "MicroProse",
+	FDS_MANUFACTURER_KEMCO:                       "Kemco",
+	FDS_MANUFACTURER_MISAWA_ENTERTAINMENT:        "Misawa Entertainment",
+	FDS_MANUFACTURER_LOZC:                        "LOZC",
+	FDS_MANUFACTURER_TOKUMA_SHOTEN:               "Tokuma Shoten",
+	FDS_MANUFACTURER_BULLET_PROOF_SOFTWARE:       "Bullet-Proof Software",
+	FDS_MANUFACTURER_VIC_TOKAI:                   "Vic Tokai",
+	FDS_MANUFACTURER_APE:                         "Ape",
+	FDS_MANUFACTURER_IMAX:                        "I'Max",
+	FDS_MANUFACTURER_CHUNSOFT:                    "Chunsoft",
+	FDS_MANUFACTURER_VIDEO_SYSTEM:                "Video System",
+	FDS_MANUFACTURER_TSUBURAYA_PRODUCTIONS:       "Tsuburaya Productions",
+	FDS_MANUFACTURER_VARIE:                       "Varie",
+	FDS_MANUFACTURER_YONEZAWA_SPAL:               "Yonezawa/S'Pal",
+	FDS_MANUFACTURER_KANEKO:                      "Kaneko",
+	FDS_MANUFACTURER_PACK_IN_VIDEO:               "Pack-In-Video",
+	FDS_MANUFACTURER_NICHIBUTSU:                  "Nichibutsu",
+	FDS_MANUFACTURER_TECMO:                       "Tecmo",
+	FDS_MANUFACTURER_IMAGINEER:                   "Imagineer",
+	FDS_MANUFACTURER_BANPRESTO_9D:                "Banpresto",
+	FDS_MANUFACTURER_NOVA:                        "Nova",
+	FDS_MANUFACTURER_HORI_ELECTRIC:               "Hori Electric",
+	FDS_MANUFACTURER_BANDAI_A2:                   "Bandai",
+	FDS_MANUFACTURER_KONAMI_A4:                   "Konami",
+	FDS_MANUFACTURER_KAWADA:                      "Kawada",
+	FDS_MANUFACTURER_TAKARA:                      "Takara",
+	FDS_MANUFACTURER_TECHNOS_JAPAN:               "Technos Japan",
+	FDS_MANUFACTURER_VICTOR_MUSICAL_INDUSTRIES:   "Victor Musical Industries",
+	FDS_MANUFACTURER_TOEI_ANIMATION:              "Toei Animation",
+	FDS_MANUFACTURER_TOHO:                        "Toho",
+	FDS_MANUFACTURER_NAMCO:                       "Namco",
+	FDS_MANUFACTURER_ACCLAIM_B0:                  "Acclaim",
+	FDS_MANUFACTURER_ASCII:                       "ASCII",
+	FDS_MANUFACTURER_BANDAI_B2:                   "Bandai",
+	FDS_MANUFACTURER_ENIX:                        "Enix",
+	FDS_MANUFACTURER_HAL_LABORATORY:              "HAL Laboratory",
+	FDS_MANUFACTURER_SNK:                         "SNK",
+	FDS_MANUFACTURER_PONY_CANYON:                 "Pony Canyon",
+	FDS_MANUFACTURER_CULTURE_BRAIN:               "Culture Brain",
+	FDS_MANUFACTURER_SUNSOFT:                     "Sunsoft",
+	FDS_MANUFACTURER_TOSHIBA_EMI:                 "Toshiba EMI",
+	FDS_MANUFACTURER_SONY_IMAGESOFT:              "Sony Imagesoft",
+	FDS_MANUFACTURER_SAMMY:                       "Sammy",
+	FDS_MANUFACTURER_TAITO:                       "Taito",
+	FDS_MANUFACTURER_KEMCO_C2:                    "Kemco",
+	FDS_MANUFACTURER_SQUARE:                      "Square",
+	FDS_MANUFACTURER_TOKUMA_SHOTEN_C4:            "Tokuma Shoten",
+	FDS_MANUFACTURER_DATA_EAST:                   "Data East",
+	FDS_MANUFACTURER_TONKIN_HOUSE:                "Tonkin House",
+	FDS_MANUFACTURER_KOEI:                        "Koei",
+	FDS_MANUFACTURER_UFL:                         "UFL",
+	FDS_MANUFACTURER_ULTRA_GAMES:                 "Konami (Ultra Games)",
+	FDS_MANUFACTURER_VAP:                         "VAP",
+	FDS_MANUFACTURER_USE_CORPORATION:             "Use Corporation",
+	FDS_MANUFACTURER_MELDAC:                      "Meldac",
+	FDS_MANUFACTURER_PONY_CANYON_CE:              "Pony Canyon",
+	FDS_MANUFACTURER_ANGEL_CF:                    "Angel",
+	FDS_MANUFACTURER_TAITO_D0:                    "Taito",
+	FDS_MANUFACTURER_SOFEL:                       "Sofel",
+	FDS_MANUFACTURER_QUEST:                       "Quest",
+	FDS_MANUFACTURER_SIGMA_ENTERPRISES:           "Sigma Enterprises",
+	FDS_MANUFACTURER_ASK_KODANSHA:                "ASK Kodansha",
+	FDS_MANUFACTURER_NAXAT_SOFT_D6:               "Naxat Soft",
+	FDS_MANUFACTURER_COPYA_SYSTEM:                "Copya System",
+	FDS_MANUFACTURER_BANPRESTO_D9:                "Banpresto",
+	FDS_MANUFACTURER_TOMY:                        "Tomy",
+	FDS_MANUFACTURER_LJN_DB:                      "LJN",
+	FDS_MANUFACTURER_NIPPON_COMPUTER_SYSTEMS:     "Nippon Computer Systems",
+	FDS_MANUFACTURER_HUMAN_ENTERTAINMENT:         "Human Entertainment",
+	FDS_MANUFACTURER_ALTRON:                      "Altron",
+	FDS_MANUFACTURER_JALECO_E0:                   "Jaleco",
+	FDS_MANUFACTURER_TOWA_CHIKI:                  "Towa Chiki",
+	FDS_MANUFACTURER_YUTAKA:                      "Yutaka",
+	FDS_MANUFACTURER_VARIE_E3:                    "Varie",
+	FDS_MANUFACTURER_EPOCH:                       "Epoch",
+	FDS_MANUFACTURER_ATHENA:                      "Athena",
+	FDS_MANUFACTURER_ASMIK:                       "Asmik",
+	FDS_MANUFACTURER_NATSUME:                     "Natsume",
+	FDS_MANUFACTURER_KING_RECORDS:                "King Records",
+	FDS_MANUFACTURER_ATLUS_EB:                    "Atlus",
+	FDS_MANUFACTURER_EPIC_SONY_RECORDS:           "Epic/Sony Records",
+	FDS_MANUFACTURER_IGS:                         "IGS",
+	FDS_MANUFACTURER_A_WAVE:                      "A Wave",
+	FDS_MANUFACTURER_EXTREME_ENTERTAINMENT:       "Extreme Entertainment",
+	FDS_MANUFACTURER_LJN_FF:                      "LJN",
+}
+
+func (manufacturerCode FDSManufacturerCode) String() string {
+	manufacturerName := GetFDSManufacturerName(uint8(manufacturerCode))
+	if manufacturerName == "" {
+		return "Unknown/Undefined"
+	}
+
+	return manufacturerName
+}
+
+// Get the name of the licensee for a manufacturer code in the disk
+// info block, or an empty string if the code isn't a known one.
+func GetFDSManufacturerName(manufacturerCode uint8) string {
+	return fdsManufacturerNames[FDSManufacturerCode(manufacturerCode)]
+}
@@ -41,9 +41,11 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"hash/crc32"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -61,15 +63,20 @@ var (
 )
 
 type FDSArchiveFile struct {
-	Name         string
-	Filename     string
-	RelativePath string
-	Size         uint64
-	CRC32        uint32
-	MD5          [16]byte
-	SHA1         [20]byte
-	SHA256       [32]byte
-	ArchiveDisks []*FDSDisk
+	Name             string
+	Filename         string
+	RelativePath     string
+	Size             uint64
+	CRC32            uint32
+	MD5              [16]byte
+	SHA1             [20]byte
+	SHA256           [32]byte
+	NormalizedCRC32  uint32
+	NormalizedMD5    [16]byte
+	NormalizedSHA1   [20]byte
+	NormalizedSHA256 [32]byte
+	ArchiveDisks     []*FDSDisk
+	HeaderSource     string
 }
 
 type FDSDisk struct {
@@ -153,8 +160,114 @@ func (r *FDSError) Error() string {
 	return r.Text
 }
 
+// Whether normalized hashes were generated for, or read in with, the archive
+func (archive *FDSArchiveFile) HasNormalizedHashes() bool {
+	return archive.NormalizedSHA256 != [32]byte{}
+}
+
+func (archive *FDSArchiveFile) String() string {
+	returnString := "Archive Type: FDS\n"
+
+	if archive.Name != "" {
+		returnString = returnString + "Archive Name: " + archive.Name + "\n"
+	} else if archive.Filename != "" {
+		returnString = returnString + "Archive Filename: " + archive.Filename + "\n"
+	} else if archive.RelativePath != "" {
+		returnString = returnString + "Archive Relative Path: " + archive.RelativePath + "\n"
+	}
+
+	returnString = returnString + "Archive Size: " + strconv.FormatUint(archive.Size, 10) + " bytes\n"
+
+	crc32Bytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32Bytes, archive.CRC32)
+	returnString = returnString + "Archive CRC32: " + strings.ToUpper(hex.EncodeToString(crc32Bytes)) + "\n"
+
+	returnString = returnString + "Archive MD5: " + strings.ToUpper(hex.EncodeToString(archive.MD5[:])) + "\n"
+	returnString = returnString + "Archive SHA1: " + strings.ToUpper(hex.EncodeToString(archive.SHA1[:])) + "\n"
+	returnString = returnString + "Archive SHA256: " + strings.ToUpper(hex.EncodeToString(archive.SHA256[:])) + "\n"
+
+	if archive.HasNormalizedHashes() {
+		normalizedCrc32Bytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(normalizedCrc32Bytes, archive.NormalizedCRC32)
+		returnString = returnString + "Normalized CRC32: " + strings.ToUpper(hex.EncodeToString(normalizedCrc32Bytes)) + "\n"
+
+		returnString = returnString + "Normalized MD5: " + strings.ToUpper(hex.EncodeToString(archive.NormalizedMD5[:])) + "\n"
+		returnString = returnString + "Normalized SHA1: " + strings.ToUpper(hex.EncodeToString(archive.NormalizedSHA1[:])) + "\n"
+		returnString = returnString + "Normalized SHA256: " + strings.ToUpper(hex.EncodeToString(archive.NormalizedSHA256[:])) + "\n"
+	}
+
+	returnString = returnString + "Number of Disks: " + strconv.Itoa(len(archive.ArchiveDisks)) + "\n"
+
+	for diskIndex := range archive.ArchiveDisks {
+		returnString = returnString + "\nDisk Number: " + strconv.Itoa(int(archive.ArchiveDisks[diskIndex].DiskNumber)) + "\n"
+
+		for sideIndex := range archive.ArchiveDisks[diskIndex].DiskSides {
+			returnString = returnString + archive.ArchiveDisks[diskIndex].DiskSides[sideIndex].String()
+		}
+	}
+
+	return returnString
+}
+
+func (side *FDSSide) String() string {
+	returnString := "  Side: " + getFDSSideString(side.SideNumber) + "\n"
+	returnString = returnString + "  Side Size: " + strconv.FormatUint(side.Size, 10) + " bytes\n"
+
+	crc32Bytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32Bytes, side.CRC32)
+	returnString = returnString + "  Side CRC32: " + strings.ToUpper(hex.EncodeToString(crc32Bytes)) + "\n"
+
+	returnString = returnString + "  Side MD5: " + strings.ToUpper(hex.EncodeToString(side.MD5[:])) + "\n"
+	returnString = returnString + "  Side SHA1: " + strings.ToUpper(hex.EncodeToString(side.SHA1[:])) + "\n"
+	returnString = returnString + "  Side SHA256: " + strings.ToUpper(hex.EncodeToString(side.SHA256[:])) + "\n"
+	returnString = returnString + "  Game Code: " + side.FDSGameName + "\n"
+	returnString = returnString + "  Game Type: " + getFDSGameTypeString(side.GameType) + "\n"
+	returnString = returnString + "  Revision: " + strconv.Itoa(int(side.RevisionNumber)) + "\n"
+	returnString = returnString + "  Manufacturer Code: 0x" + strings.ToUpper(hex.EncodeToString([]byte{side.ManufacturerCode})) + "\n"
+	returnString = returnString + "  Manufacturer: " + FDSManufacturerCode(side.ManufacturerCode).String() + "\n"
+	returnString = returnString + "  Disk Type: " + getFDSDiskTypeString(side.DiskType) + "\n"
+	returnString = returnString + "  Boot File ID: " + strconv.Itoa(int(side.BootFileID)) + "\n"
+	returnString = returnString + "  Manufacturing Date: " + getFDSDateString(side.ManufacturingDate) + "\n"
+	returnString = returnString + "  Country: " + getFDSCountryCodeString(side.CountryCode) + "\n"
+	returnString = returnString + "  Rewrite Date: " + getFDSDateString(side.RewriteDate) + "\n"
+	returnString = returnString + "  Rewrite Count: " + strconv.Itoa(int(side.RewriteCount)) + "\n"
+	returnString = returnString + "  Disk Writer Serial Number: " + strconv.Itoa(int(side.DiskWriterSerialNumber)) + "\n"
+	returnString = returnString + "  Price: " + strconv.Itoa(int(side.Price)) + "\n"
+	returnString = returnString + "  Number of Files: " + strconv.Itoa(len(side.SideFiles)) + "\n"
+
+	for fileIndex := range side.SideFiles {
+		returnString = returnString + side.SideFiles[fileIndex].String()
+	}
+
+	return returnString
+}
+
+func (file *FDSFile) String() string {
+	returnString := "    File " + strconv.Itoa(int(file.FileNumber)) + ": " + strings.TrimRight(file.FileName, "\x00 ") + "\n"
+	returnString = returnString + "      File ID: " + strconv.Itoa(int(file.FileIdentificationCode)) + "\n"
+
+	fileAddressBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(fileAddressBytes, file.FileAddress)
+	returnString = returnString + "      Load Address: $" + strings.ToUpper(hex.EncodeToString(fileAddressBytes)) + "\n"
+
+	returnString = returnString + "      Size: " + strconv.Itoa(int(file.FileSize)) + " bytes\n"
+	returnString = returnString + "      Type: " + getFDSFileTypeString(file.FileType) + "\n"
+
+	if file.FileData != nil {
+		crc32Bytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc32Bytes, file.FileData.CRC32)
+		returnString = returnString + "      CRC32: " + strings.ToUpper(hex.EncodeToString(crc32Bytes)) + "\n"
+
+		returnString = returnString + "      MD5: " + strings.ToUpper(hex.EncodeToString(file.FileData.MD5[:])) + "\n"
+		returnString = returnString + "      SHA1: " + strings.ToUpper(hex.EncodeToString(file.FileData.SHA1[:])) + "\n"
+		returnString = returnString + "      SHA256: " + strings.ToUpper(hex.EncodeToString(file.FileData.SHA256[:])) + "\n"
+	}
+
+	return returnString
+}
+
 // Read a byte slice and attempt to decode it into an FDSArchiveFile structure
-func DecodeFDSArchive(inputFile []byte, relativePath string, generateChecksums bool) (*FDSArchiveFile, error) {
+func DecodeFDSArchive(inputFile []byte, relativePath string, generateChecksums bool, generateNormalizedHashes bool) (*FDSArchiveFile, error) {
 	// Get all of the disk sides as byte slices
 	sideByteSlices, err := GetStrippedDiskSideByteSlices(inputFile)
 	if err != nil {
@@ -180,7 +293,7 @@ func DecodeFDSArchive(inputFile []byte, relativePath string, generateChecksums b
 	for sliceIndex := 0; sliceIndex < numberOfSides; sliceIndex++ {
 		tempSide, err := DecodeFDSSide(sideByteSlices[sliceIndex], generateChecksums)
 		if err != nil {
-			return nil, err
+			return nil, &FDSError{Text: "Unable to decode side " + strconv.Itoa(sliceIndex) + ": " + err.Error()}
 		}
 
 		tempSide.CRC32 = crc32.ChecksumIEEE(sideByteSlices[sliceIndex])
@@ -225,11 +338,32 @@ func DecodeFDSArchive(inputFile []byte, relativePath string, generateChecksums b
 		}
 	}
 
+	// Hash a headerless, checksumless copy of the archive with the fields
+	// disk writers change zeroed so rewritten copies of a game still match
+	if generateNormalizedHashes {
+		normalizedBytes, err := EncodeFDSArchive(tempArchive, false, false, false, false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		tempArchive.NormalizedCRC32 = crc32.ChecksumIEEE(normalizedBytes)
+		tempArchive.NormalizedMD5 = md5.Sum(normalizedBytes)
+		tempArchive.NormalizedSHA1 = sha1.Sum(normalizedBytes)
+		tempArchive.NormalizedSHA256 = sha256.Sum256(normalizedBytes)
+	}
+
 	return tempArchive, nil
 }
 
 func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
+	sideSize := len(inputSide)
+
 	// Validate the internal FDS header
+	err := checkFDSSideBounds(inputSide, 0x00, 0x38, "disk info block")
+	if err != nil {
+		return nil, err
+	}
+
 	if inputSide[0x00] != uint8(FDS_DISK_INFO_BLOCK) {
 		return nil, &FDSError{Text: "Unable to decode header for FDS side."}
 	}
@@ -237,7 +371,6 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 	readChecksums := false
 
 	tempSide := &FDSSide{}
-	var err error
 
 	// Read in filesystem metadata for the disk side
 	tempSide.ManufacturerCode = inputSide[0x0f]
@@ -284,6 +417,12 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		return nil, err
 	}
 
+	// The file layout block (or the disk info CRC) comes next
+	err = checkFDSSideBounds(inputSide, 0x38, 3, "file layout block")
+	if err != nil {
+		return nil, err
+	}
+
 	// Read and update checksums as applicable
 	if binary.LittleEndian.Uint16(inputSide[0x38:0x3a]) == testCrc && inputSide[0x3a] == '\x01' {
 		readChecksums = true
@@ -301,14 +440,19 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		tempSide.DiskInfoCRC = 0
 	}
 
-	var checksumOffset uint16 = 0
+	checksumOffset := 0
 	if readChecksums {
 		checksumOffset = 2
 	}
 
 	// Check for how many files are on the disk side
+	err = checkFDSSideBounds(inputSide, 0x38+checksumOffset, 2+checksumOffset, "file layout block")
+	if err != nil {
+		return nil, err
+	}
+
 	if inputSide[0x38+checksumOffset] != uint8(FDS_DISK_FILE_LAYOUT_BLOCK) {
-		return nil, &FDSError{Text: "Unable to determine number of files on FDS side."}
+		return nil, &FDSError{Text: "Unable to determine number of files on FDS side at offset 0x" + getFDSOffsetString(0x38+checksumOffset) + "."}
 	}
 
 	numberOfFiles := inputSide[0x39+checksumOffset]
@@ -330,13 +474,20 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		}
 	}
 
-	var currentIndex = 0x003a + (2 * checksumOffset)
+	currentIndex := 0x003a + (2 * checksumOffset)
 
 	// Read each file on the disk side into a struct
 	for fileIndex := 0; fileIndex < int(numberOfFiles); fileIndex++ {
+		fileContext := "file " + strconv.Itoa(fileIndex)
+
 		// Verify we're in the right block
+		err = checkFDSSideBounds(inputSide, currentIndex, 16+checksumOffset+1, fileContext+" header block")
+		if err != nil {
+			return nil, err
+		}
+
 		if inputSide[currentIndex] != uint8(FDS_FILE_HEADER_BLOCK) {
-			return nil, &FDSError{Text: "Unable to read file header."}
+			return nil, &FDSError{Text: "Unable to read file header for " + fileContext + " at offset 0x" + getFDSOffsetString(currentIndex) + "."}
 		}
 
 		tempFile := &FDSFile{}
@@ -349,6 +500,8 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		tempFile.FileSize = binary.LittleEndian.Uint16(inputSide[currentIndex+13 : currentIndex+15])
 		tempFile.FileType = inputSide[currentIndex+15]
 
+		fileSize := int(tempFile.FileSize)
+
 		// Checksums.  Again.
 		if readChecksums {
 			tempFile.FileMetadataCRC = binary.LittleEndian.Uint16(inputSide[currentIndex+16 : currentIndex+18])
@@ -368,11 +521,16 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 
 		// Read in the file contents
 		if inputSide[currentIndex+16+checksumOffset] != uint8(FDS_FILE_DATA_BLOCK) {
-			return nil, &FDSError{Text: "Unable to read file data."}
+			return nil, &FDSError{Text: "Unable to read file data for " + fileContext + " at offset 0x" + getFDSOffsetString(currentIndex+16+checksumOffset) + "."}
+		}
+
+		err = checkFDSSideBounds(inputSide, currentIndex+17+checksumOffset, fileSize+checksumOffset, fileContext+" data block")
+		if err != nil {
+			return nil, err
 		}
 
 		tempFileData := &FDSFileData{}
-		tempFileData.FileData = inputSide[currentIndex+17+checksumOffset : currentIndex+17+tempFile.FileSize+checksumOffset]
+		tempFileData.FileData = inputSide[currentIndex+17+checksumOffset : currentIndex+17+fileSize+checksumOffset]
 
 		tempFileData.CRC32 = crc32.ChecksumIEEE(tempFileData.FileData)
 		tempFileData.MD5 = md5.Sum(tempFileData.FileData)
@@ -381,7 +539,7 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		tempFileData.Size = uint64(len(tempFileData.FileData))
 
 		if readChecksums {
-			tempFileData.FileDataCRC = binary.LittleEndian.Uint16(inputSide[currentIndex+17+tempFile.FileSize+checksumOffset : currentIndex+17+tempFile.FileSize+checksumOffset+2])
+			tempFileData.FileDataCRC = binary.LittleEndian.Uint16(inputSide[currentIndex+17+fileSize+checksumOffset : currentIndex+17+fileSize+checksumOffset+2])
 		} else {
 			tempFileData.FileDataCRC = 0
 		}
@@ -400,19 +558,26 @@ func DecodeFDSSide(inputSide []byte, generateChecksums bool) (*FDSSide, error) {
 		tempFile.FileData = tempFileData
 		tempSide.SideFiles = append(tempSide.SideFiles, tempFile)
 
-		currentIndex = currentIndex + 15 + 2 + tempFile.FileSize + (2 * checksumOffset)
+		currentIndex = currentIndex + 15 + 2 + fileSize + (2 * checksumOffset)
 	}
 
-	if currentIndex < uint16(FDS_SIDE_SIZE) {
-		tempSide.UnallocatedSpace = inputSide[currentIndex:FDS_SIDE_SIZE]
-		tempSide.UnallocatedSpaceOffset = currentIndex
+	// Anything left over on a QD side past the size of an FDS side is dropped
+	unallocatedSpaceEnd := int(FDS_SIDE_SIZE)
+	if sideSize < unallocatedSpaceEnd {
+		unallocatedSpaceEnd = sideSize
+	}
+
+	if currentIndex < unallocatedSpaceEnd {
+		tempSide.UnallocatedSpace = inputSide[currentIndex:unallocatedSpaceEnd]
+		tempSide.UnallocatedSpaceOffset = uint16(currentIndex)
 	}
 
 	return tempSide, nil
 }
 
-// Turn an FDSArchiveFile struct into a byte slice that can be written to disk as a .fds file
-func EncodeFDSArchive(inputArchive *FDSArchiveFile, writeHeader bool, writeChecksums bool, generateChecksums bool, writeQd bool) ([]byte, error) {
+// Turn an FDSArchiveFile struct into a byte slice that can be written to disk as a .fds file.
+// If normalize is set, the disk writer metadata and unallocated space of each side are zeroed.
+func EncodeFDSArchive(inputArchive *FDSArchiveFile, writeHeader bool, writeChecksums bool, generateChecksums bool, writeQd bool, normalize bool) ([]byte, error) {
 	archiveBytes := make([]byte, 0)
 
 	// Write an FDS header if requested.  Don't do this unless you know you need to, though.
@@ -429,7 +594,12 @@ func EncodeFDSArchive(inputArchive *FDSArchiveFile, writeHeader bool, writeCheck
 	// Encode and append each disk side
 	for diskIndex := range inputArchive.ArchiveDisks {
 		for sideIndex := range inputArchive.ArchiveDisks[diskIndex].DiskSides {
-			sideBytes, err := EncodeFDSSide(inputArchive.ArchiveDisks[diskIndex].DiskSides[sideIndex], writeChecksums, generateChecksums, writeQd)
+			tempSide := inputArchive.ArchiveDisks[diskIndex].DiskSides[sideIndex]
+			if normalize {
+				tempSide = GetNormalizedFDSSide(tempSide)
+			}
+
+			sideBytes, err := EncodeFDSSide(tempSide, writeChecksums, generateChecksums, writeQd)
 			if err != nil {
 				return nil, err
 			}
@@ -650,6 +820,21 @@ func EncodeFDSSide(inputSide *FDSSide, writeChecksums bool, generateChecksums bo
 	return sideSlice, nil
 }
 
+// Get a copy of an FDSSide struct with the fields that disk writer
+// kiosks change on a rewrite zeroed, along with the unallocated space.
+// File data is shared with the original side.
+func GetNormalizedFDSSide(inputSide *FDSSide) *FDSSide {
+	normalizedSide := *inputSide
+
+	normalizedSide.RewriteDate = []byte{'\x00', '\x00', '\x00'}
+	normalizedSide.RewriteCount = 0
+	normalizedSide.DiskWriterSerialNumber = 0
+	normalizedSide.Price = 0
+	normalizedSide.UnallocatedSpace = make([]byte, len(inputSide.UnallocatedSpace))
+
+	return &normalizedSide
+}
+
 // Generate a CRC for given block of data.  Few, if any,
 // FDS implementations actually use these.
 func GenerateFDSBlockCRC(rawBlock []byte) (uint16, error) {
@@ -694,7 +879,7 @@ func GetStrippedDiskSideByteSlices(inputFile []byte) ([][]byte, error) {
 
 	fileSize := len(inputFile)
 
-	if fileSize < 2 {
+	if fileSize < 16 {
 		return nil, &FDSError{Text: "File too small to be a valid FDS archive."}
 	}
 
@@ -795,3 +980,87 @@ func encodeBcdByte(bcdInt uint8) (byte, error) {
 	mostSignificantNibble := bcdInt / 10
 	return (mostSignificantNibble << 4) | leastSignificantNibble, nil
 }
+
+// Dates on disks which were never written to a kiosk or
+// which have been blanked out can't be decoded meaningfully.
+func getFDSDateString(dateBytes []byte) string {
+	if len(dateBytes) < 3 {
+		return "Unknown"
+	}
+
+	if dateBytes[1] == 0 || dateBytes[2] == 0 {
+		return "Unknown (" + strings.ToUpper(hex.EncodeToString(dateBytes)) + ")"
+	}
+
+	return DecodeFDSDateFormat(dateBytes).Format("2006-01-02")
+}
+
+func getFDSSideString(sideNumber uint8) string {
+	switch sideNumber {
+	case 0:
+		return "A"
+	case 1:
+		return "B"
+	default:
+		return "Unknown/Undefined (" + strconv.Itoa(int(sideNumber)) + ")"
+	}
+}
+
+func getFDSGameTypeString(gameType uint8) string {
+	switch gameType {
+	case 0x20:
+		return "Normal disk"
+	case 0x45:
+		return "Event"
+	case 0x52:
+		return "Reduction in price via advertising"
+	default:
+		return "Unknown/Undefined (0x" + strings.ToUpper(hex.EncodeToString([]byte{gameType})) + ")"
+	}
+}
+
+func getFDSDiskTypeString(diskType uint8) string {
+	switch diskType {
+	case 0:
+		return "FMC (\"normal card\")"
+	case 1:
+		return "FSC (\"card with shutter\")"
+	default:
+		return "Unknown/Undefined (" + strconv.Itoa(int(diskType)) + ")"
+	}
+}
+
+func getFDSCountryCodeString(countryCode uint8) string {
+	switch countryCode {
+	case 0x49:
+		return "Japan"
+	default:
+		return "Unknown/Undefined (0x" + strings.ToUpper(hex.EncodeToString([]byte{countryCode})) + ")"
+	}
+}
+
+func getFDSFileTypeString(fileType uint8) string {
+	switch fileType {
+	case 0:
+		return "Program (PRAM)"
+	case 1:
+		return "Character (CRAM)"
+	case 2:
+		return "Nametable (VRAM)"
+	default:
+		return "Unknown/Undefined (" + strconv.Itoa(int(fileType)) + ")"
+	}
+}
+
+// Make sure a block of data can be read from an FDS side before reading it
+func checkFDSSideBounds(inputSide []byte, offset int, length int, blockName string) error {
+	if offset < 0 || length < 0 || offset+length > len(inputSide) {
+		return &FDSError{Text: "Unable to read " + blockName + " at offset 0x" + getFDSOffsetString(offset) + ".  " + strconv.Itoa(length) + " bytes are needed, but the side is only " + strconv.Itoa(len(inputSide)) + " bytes long."}
+	}
+
+	return nil
+}
+
+func getFDSOffsetString(offset int) string {
+	return strings.ToUpper(strconv.FormatInt(int64(offset), 16))
+}
@@ -0,0 +1,76 @@
+//go:build go1.18
+
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Fuzz targets for the FDS decoders.  Any input has to decode or
+// return an error, never panic.  Seeds in testdata/fuzz cover
+// truncated blocks and file sizes that run past the end of a side.
+
+package FDSTool
+
+import (
+	"testing"
+)
+
+func FuzzDecodeFDSSide(f *testing.F) {
+	f.Add(getTestFDSSide([]*testFDSFile{{declaredSize: 4, data: []byte{1, 2, 3, 4}}}, false))
+	f.Add(getTestFDSSide([]*testFDSFile{{declaredSize: 0xffff, data: []byte{1}}}, false))
+	f.Add(getTestFDSSideInfo()[0:0x20])
+
+	f.Fuzz(func(t *testing.T, sideBytes []byte) {
+		for _, generateChecksums := range []bool{false, true} {
+			side, err := DecodeFDSSide(sideBytes, generateChecksums)
+			if err != nil {
+				continue
+			}
+
+			_ = side.String()
+
+			_, err = EncodeFDSSide(side, true, generateChecksums, false)
+			if err != nil {
+				t.Fatalf("unable to encode a side that decoded: %v", err)
+			}
+		}
+	})
+}
+
+// Short inputs never get past the archive size checks, so each input
+// is also tried as the start of a full side
+func FuzzDecodeFDSArchive(f *testing.F) {
+	f.Add(getTestFDSSide([]*testFDSFile{{declaredSize: 4, data: []byte{1, 2, 3, 4}}}, false))
+	f.Add(getTestFDSSide([]*testFDSFile{{declaredSize: 0xffff, data: []byte{1}}}, false))
+	f.Add([]byte(FDS_HEADER_MAGIC))
+
+	f.Fuzz(func(t *testing.T, archiveBytes []byte) {
+		paddedArchive := make([]byte, FDS_SIDE_SIZE)
+		copy(paddedArchive, archiveBytes)
+		paddedArchive[0x00] = uint8(FDS_DISK_INFO_BLOCK)
+		copy(paddedArchive[0x01:0x0f], []byte(FDS_MAGIC))
+
+		for _, inputBytes := range [][]byte{archiveBytes, paddedArchive} {
+			archive, err := DecodeFDSArchive(inputBytes, "", true, true)
+			if err != nil {
+				continue
+			}
+
+			_ = archive.String()
+		}
+	})
+}
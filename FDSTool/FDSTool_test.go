@@ -0,0 +1,206 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FDSTool
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Build an FDS disk side without block checksums.  Each file is a
+// declared size followed by its data, so sizes that run past the end
+// of the side can be described.
+type testFDSFile struct {
+	declaredSize uint16
+	data         []byte
+}
+
+func getTestFDSSideInfo() []byte {
+	diskInfo := make([]byte, 0x38)
+	diskInfo[0x00] = uint8(FDS_DISK_INFO_BLOCK)
+	copy(diskInfo[0x01:0x0f], []byte(FDS_MAGIC))
+	diskInfo[0x0f] = 0x01
+	copy(diskInfo[0x10:0x13], []byte("TST"))
+	diskInfo[0x19] = 0x0f
+	copy(diskInfo[0x1f:0x22], []byte{0x61, 0x01, 0x01})
+	copy(diskInfo[0x2c:0x2f], []byte{0x61, 0x02, 0x02})
+
+	return diskInfo
+}
+
+func getTestFDSSide(files []*testFDSFile, padSide bool) []byte {
+	sideBytes := getTestFDSSideInfo()
+	sideBytes = append(sideBytes, uint8(FDS_DISK_FILE_LAYOUT_BLOCK), uint8(len(files)))
+
+	for index := range files {
+		fileHeader := make([]byte, 16)
+		fileHeader[0] = uint8(FDS_FILE_HEADER_BLOCK)
+		fileHeader[1] = uint8(index)
+		fileHeader[2] = uint8(index)
+		copy(fileHeader[3:11], []byte("FILE0000"))
+		fileHeader[11] = 0x00
+		fileHeader[12] = 0x60
+		fileHeader[13] = uint8(files[index].declaredSize)
+		fileHeader[14] = uint8(files[index].declaredSize >> 8)
+
+		sideBytes = append(sideBytes, fileHeader...)
+		sideBytes = append(sideBytes, uint8(FDS_FILE_DATA_BLOCK))
+		sideBytes = append(sideBytes, files[index].data...)
+	}
+
+	if padSide && uint64(len(sideBytes)) < FDS_SIDE_SIZE {
+		sideBytes = append(sideBytes, make([]byte, FDS_SIDE_SIZE-uint64(len(sideBytes)))...)
+	}
+
+	return sideBytes
+}
+
+func TestDecodeFDSSide(t *testing.T) {
+	validSide := getTestFDSSide([]*testFDSFile{{declaredSize: 4, data: []byte{1, 2, 3, 4}}, {declaredSize: 2, data: []byte{5, 6}}}, true)
+
+	wrongLayoutBlock := getTestFDSSide(nil, true)
+	wrongLayoutBlock[0x38] = 0x07
+
+	tooManyFiles := getTestFDSSide([]*testFDSFile{{declaredSize: 1, data: []byte{1}}}, false)
+	tooManyFiles[0x39] = 5
+
+	wrongDataBlock := getTestFDSSide([]*testFDSFile{{declaredSize: 1, data: []byte{1}}}, true)
+	wrongDataBlock[0x3a+16] = 0x07
+
+	tests := []struct {
+		name          string
+		side          []byte
+		expectError   bool
+		expectedFiles int
+	}{
+		{name: "valid side", side: validSide, expectedFiles: 2},
+		{name: "no files", side: getTestFDSSide(nil, true), expectedFiles: 0},
+		{name: "empty side", side: []byte{}, expectError: true},
+		{name: "truncated disk info block", side: getTestFDSSideInfo()[0:0x20], expectError: true},
+		{name: "wrong disk info block", side: append([]byte{0x07}, validSide[1:]...), expectError: true},
+		{name: "missing file layout block", side: getTestFDSSideInfo(), expectError: true},
+		{name: "wrong file layout block", side: wrongLayoutBlock, expectError: true},
+		{name: "truncated file layout block", side: getTestFDSSide(nil, false)[0:0x39], expectError: true},
+		{name: "more files than the side holds", side: tooManyFiles, expectError: true},
+		{name: "truncated file header", side: getTestFDSSide([]*testFDSFile{{declaredSize: 1, data: []byte{1}}}, false)[0 : 0x3a+8], expectError: true},
+		{name: "wrong file data block", side: wrongDataBlock, expectError: true},
+		{name: "file size past the end of the side", side: getTestFDSSide([]*testFDSFile{{declaredSize: 0xffff, data: []byte{1, 2, 3}}}, true), expectError: true},
+		{name: "file size past the end of a short side", side: getTestFDSSide([]*testFDSFile{{declaredSize: 16, data: []byte{1, 2, 3}}}, false), expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			side, err := DecodeFDSSide(test.side, false)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(side.SideFiles) != test.expectedFiles {
+				t.Fatalf("expected %d files, got %d", test.expectedFiles, len(side.SideFiles))
+			}
+		})
+	}
+}
+
+func TestDecodeFDSSideFileData(t *testing.T) {
+	side, err := DecodeFDSSide(getTestFDSSide([]*testFDSFile{{declaredSize: 4, data: []byte{1, 2, 3, 4}}, {declaredSize: 2, data: []byte{5, 6}}}, true), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if side.FDSGameName != "TST" || side.BootFileID != 0x0f || side.ManufacturerCode != 0x01 {
+		t.Fatalf("disk info decoded as game %q, boot file %d, manufacturer %d", side.FDSGameName, side.BootFileID, side.ManufacturerCode)
+	}
+
+	if !bytes.Equal(side.SideFiles[0].FileData.FileData, []byte{1, 2, 3, 4}) || !bytes.Equal(side.SideFiles[1].FileData.FileData, []byte{5, 6}) {
+		t.Fatalf("file data decoded as %v and %v", side.SideFiles[0].FileData.FileData, side.SideFiles[1].FileData.FileData)
+	}
+
+	if side.SideFiles[0].FileAddress != 0x6000 {
+		t.Fatalf("expected file address 0x6000, got 0x%x", side.SideFiles[0].FileAddress)
+	}
+}
+
+func TestDecodeFDSArchive(t *testing.T) {
+	validSide := getTestFDSSide([]*testFDSFile{{declaredSize: 4, data: []byte{1, 2, 3, 4}}}, true)
+
+	headeredArchive := append([]byte(FDS_HEADER_MAGIC), 1)
+	headeredArchive = append(headeredArchive, []byte(FDS_HEADER_PADDING)...)
+	headeredArchive = append(headeredArchive, validSide...)
+
+	qdSide := append(append([]byte{}, validSide...), make([]byte, QD_SIDE_SIZE-FDS_SIDE_SIZE)...)
+
+	badMagic := append([]byte{}, validSide...)
+	badMagic[0x01] = 'X'
+
+	badSide := getTestFDSSide([]*testFDSFile{{declaredSize: 0xffff, data: []byte{1}}}, true)
+
+	tests := []struct {
+		name          string
+		archive       []byte
+		expectError   bool
+		expectedSides int
+	}{
+		{name: "headerless archive", archive: validSide, expectedSides: 1},
+		{name: "archive with header", archive: headeredArchive, expectedSides: 1},
+		{name: "two sides", archive: append(append([]byte{}, validSide...), validSide...), expectedSides: 2},
+		{name: "QD side", archive: qdSide, expectedSides: 1},
+		{name: "empty file", archive: []byte{}, expectError: true},
+		{name: "truncated header", archive: []byte(FDS_HEADER_MAGIC), expectError: true},
+		{name: "header and no sides", archive: headeredArchive[0:16], expectedSides: 0},
+		{name: "not a multiple of the side size", archive: validSide[0:1000], expectError: true},
+		{name: "bad disk magic", archive: badMagic, expectError: true},
+		{name: "side that doesn't decode", archive: badSide, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			archive, err := DecodeFDSArchive(test.archive, "", false, true)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sides := 0
+			for diskIndex := range archive.ArchiveDisks {
+				sides = sides + len(archive.ArchiveDisks[diskIndex].DiskSides)
+			}
+
+			if sides != test.expectedSides {
+				t.Fatalf("expected %d sides, got %d", test.expectedSides, sides)
+			}
+		})
+	}
+}
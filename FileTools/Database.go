@@ -0,0 +1,197 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This loads ROM databases in any of the readable formats, given as
+// "format:path" sources, so operations which work on more than one
+// database can take them in a uniform way.
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"NES20Tool/NESTool"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+var (
+	DATABASE_FORMAT_DEFAULT = "default"
+	DATABASE_FORMAT_NES20DB = "nes20db"
+)
+
+type DatabaseSource struct {
+	Format string
+	Path   string
+}
+
+func (source *DatabaseSource) String() string {
+	return source.Format + ":" + source.Path
+}
+
+// Parse a database source in "format:path" form.  A path without a
+// known format in front of it is read as a default format XML file,
+// which keeps Windows drive letters working.
+func ParseDatabaseSource(sourceSpec string) (*DatabaseSource, error) {
+	if sourceSpec == "" {
+		return nil, errors.New("Empty database source")
+	}
+
+	separatorIndex := strings.Index(sourceSpec, ":")
+	if separatorIndex > 0 {
+		sourceFormat := sourceSpec[:separatorIndex]
+		if sourceFormat == DATABASE_FORMAT_DEFAULT || sourceFormat == DATABASE_FORMAT_NES20DB {
+			if separatorIndex == len(sourceSpec)-1 {
+				return nil, errors.New("Missing path in database source: " + sourceSpec)
+			}
+
+			return &DatabaseSource{Format: sourceFormat, Path: sourceSpec[separatorIndex+1:]}, nil
+		}
+	}
+
+	return &DatabaseSource{Format: DATABASE_FORMAT_DEFAULT, Path: sourceSpec}, nil
+}
+
+// Load a database into maps of NESROM and FDSArchiveFile structs.  The
+// nes20db format has no FDS archives, so its archive map is always empty.
+func LoadDatabase(source *DatabaseSource, enableInes bool, preserveTrainer bool, enableOrganization bool) (map[string]*NESTool.NESROM, map[string]*FDSTool.FDSArchiveFile, error) {
+	xmlPayload, err := ioutil.ReadFile(source.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if source.Format == DATABASE_FORMAT_DEFAULT {
+		return UnmarshalXMLFileToROMMap(string(xmlPayload), source.Path, enableInes, preserveTrainer, enableOrganization)
+	} else if source.Format == DATABASE_FORMAT_NES20DB {
+		romMap, err := UnmarshalNES20DBXMLToROMMap(string(xmlPayload), enableOrganization)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return romMap, make(map[string]*FDSTool.FDSArchiveFile), nil
+	}
+
+	return nil, nil, errors.New("Unsupported database format: " + source.Format)
+}
+
+// Combine ROM maps from several databases into one template map, in
+// order of precedence.  A ROM whose PRG and CHR ROM hashes, or whole
+// ROM hashes, are already in an earlier database is left out, since
+// matching tries whole ROM hashes before the PRG and CHR ROM ones and
+// would otherwise pick it over the earlier entry.  The rest keep their
+// original keys so they can still be matched directly.  Each ROM's
+// HeaderSource is set to the name of the database it came from.
+func CombineDatabases(romMaps []map[string]*NESTool.NESROM, sourceNames []string) map[string]*NESTool.NESROM {
+	combinedRoms := make(map[string]*NESTool.NESROM)
+	claimedEntries := make(map[string]bool)
+	claimedHashes := make(map[string]bool)
+
+	for sourceIndex := range romMaps {
+		sourceEntries := make(map[string]bool)
+		sourceHashes := make(map[string]bool)
+
+		for _, romKey := range GetSortedNESROMKeys(romMaps[sourceIndex]) {
+			entryKey := GetDatabaseEntryKey(romMaps[sourceIndex][romKey])
+			if claimedEntries[entryKey] || combinedRoms[romKey] != nil {
+				continue
+			}
+
+			romHashKeys := getDatabaseROMHashKeys(romMaps[sourceIndex][romKey])
+			isClaimed := false
+			for index := range romHashKeys {
+				if claimedHashes[romHashKeys[index]] {
+					isClaimed = true
+				}
+			}
+
+			if isClaimed {
+				continue
+			}
+
+			romMaps[sourceIndex][romKey].HeaderSource = sourceNames[sourceIndex]
+			combinedRoms[romKey] = romMaps[sourceIndex][romKey]
+			sourceEntries[entryKey] = true
+			for index := range romHashKeys {
+				sourceHashes[romHashKeys[index]] = true
+			}
+		}
+
+		for entryKey := range sourceEntries {
+			claimedEntries[entryKey] = true
+		}
+
+		for hashKey := range sourceHashes {
+			claimedHashes[hashKey] = true
+		}
+	}
+
+	return combinedRoms
+}
+
+// Get the whole ROM hashes a ROM can be matched by, in the same form as
+// the keys of the ROM maps.  Hashes a database doesn't have are zeroed,
+// so they're left out.
+func getDatabaseROMHashKeys(rom *NESTool.NESROM) []string {
+	romHashKeys := make([]string, 0, 2)
+
+	if rom.SHA256 != [32]byte{} {
+		romHashKeys = append(romHashKeys, "SHA256:"+strings.ToUpper(hex.EncodeToString(rom.SHA256[:])))
+	}
+
+	if rom.SHA1 != [20]byte{} {
+		romHashKeys = append(romHashKeys, "SHA1:"+strings.ToUpper(hex.EncodeToString(rom.SHA1[:])))
+	}
+
+	return romHashKeys
+}
+
+// Get a key for a ROM from its PRG and CHR ROM hashes.  Unlike the keys
+// of the ROM maps, this is the same whichever format a ROM was read from.
+func GetDatabaseEntryKey(rom *NESTool.NESROM) string {
+	prgRomSha1, chrRomSha1 := getDatabaseROMHashes(rom)
+	return "PRG:" + prgRomSha1 + ",CHR:" + chrRomSha1
+}
+
+// Get the PRG and CHR ROM SHA1 hashes of a ROM.  The default XML format
+// leaves the hashes of empty sections zeroed, where nes20db has the hash
+// of no data, so empty sections always get the latter.  A zeroed hash
+// is also taken as empty, since size fields can be missing.
+func getDatabaseROMHashes(rom *NESTool.NESROM) (string, string) {
+	var prgRomSha1 string
+	var chrRomSha1 string
+
+	if rom.Header20 != nil {
+		prgRomSha1 = getDatabaseSHA1String(rom.Header20.PRGROMSHA1, rom.Header20.PRGROMCalculatedSize)
+		chrRomSha1 = getDatabaseSHA1String(rom.Header20.CHRROMSHA1, rom.Header20.CHRROMCalculatedSize)
+	} else if rom.Header10 != nil {
+		prgRomSha1 = getDatabaseSHA1String(rom.Header10.PRGROMSHA1, rom.Header10.PRGROMCalculatedSize)
+		chrRomSha1 = getDatabaseSHA1String(rom.Header10.CHRROMSHA1, rom.Header10.CHRROMCalculatedSize)
+	}
+
+	return prgRomSha1, chrRomSha1
+}
+
+func getDatabaseSHA1String(sha1Sum [20]byte, size uint64) string {
+	if size == 0 || sha1Sum == [20]byte{} {
+		return SHA1_ZERO_SUM
+	}
+
+	return strings.ToUpper(hex.EncodeToString(sha1Sum[:]))
+}
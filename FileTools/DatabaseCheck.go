@@ -0,0 +1,563 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This checks that a ROM database is internally consistent.  Loading a
+// database quietly skips hashes it can't decode and lets entries with
+// the same key replace each other, so these checks work on the entries
+// as they're written in the file instead.  Findings use the same
+// severities and result type as the header lint rules.
+
+package FileTools
+
+import (
+	"NES20Tool/LintTool"
+	"NES20Tool/NESTool"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+type DatabaseCheckRule struct {
+	ID          string
+	Severity    uint8
+	Description string
+}
+
+// All database checks
+var DATABASE_CHECK_RULES = []*DatabaseCheckRule{
+	{ID: "DB001", Severity: LintTool.LINT_SEVERITY_ERROR, Description: "Database can't be parsed"},
+	{ID: "DB002", Severity: LintTool.LINT_SEVERITY_ERROR, Description: "Hash is missing or malformed, so it's ignored when loaded"},
+	{ID: "DB003", Severity: LintTool.LINT_SEVERITY_ERROR, Description: "Entries have the same key, so one replaces the other when loaded"},
+	{ID: "DB004", Severity: LintTool.LINT_SEVERITY_WARNING, Description: "ROM size doesn't match the section sizes"},
+	{ID: "DB005", Severity: LintTool.LINT_SEVERITY_WARNING, Description: "Enumerated value isn't a defined value (an error if it can't be read at all)"},
+	{ID: "DB006", Severity: LintTool.LINT_SEVERITY_ERROR, Description: "Mirroring isn't valid for the mapper, so it's ignored when loaded"},
+	{ID: "DB007", Severity: LintTool.LINT_SEVERITY_WARNING, Description: "Entries have the same PRG and CHR ROM hashes, so matching can pick either one"},
+	{ID: "DB008", Severity: LintTool.LINT_SEVERITY_ERROR, Description: "Size can't be read, so the database can't be loaded"},
+}
+
+// The state shared between the entries of a database while checking it
+type databaseCheck struct {
+	results          []*LintTool.LintResult
+	keyLabels        map[string]string
+	entryLabels      map[string]string
+	entryKeys        map[string]string
+	entries          int
+	unreadableValues int
+}
+
+// An enumerated header field type, as read from XML
+type databaseCheckEnum interface {
+	UnmarshalText(text []byte) error
+	Name() string
+}
+
+// Database entries as the checks read them, with only the fields which
+// are checked.  Enumerated values and sizes are kept as strings, since
+// one which can't be read would otherwise stop the whole database being
+// checked.
+type databaseCheckXML struct {
+	XMLName xml.Name               `xml:"nesroms"`
+	XMLROMs []*databaseCheckXMLROM `xml:"rom"`
+}
+
+type databaseCheckXMLROM struct {
+	Name         string `xml:"name,attr"`
+	Size         string `xml:"size,attr"`
+	RelativePath string `xml:"relativePath,attr"`
+	Crc32        string `xml:"crc32,attr"`
+	Md5          string `xml:"md5,attr"`
+	Sha1         string `xml:"sha1,attr"`
+	Sha256       string `xml:"sha256,attr"`
+	Header20     *struct {
+		Prgrom struct {
+			Size           string `xml:"size,attr"`
+			SizeExponent   string `xml:"sizeExponent,attr"`
+			SizeMultiplier string `xml:"sizeMultiplier,attr"`
+			Sum16          string `xml:"sum16,attr"`
+			Crc32          string `xml:"crc32,attr"`
+			Md5            string `xml:"md5,attr"`
+			Sha1           string `xml:"sha1,attr"`
+			Sha256         string `xml:"sha256,attr"`
+		} `xml:"prgrom"`
+		Chrrom struct {
+			Size           string `xml:"size,attr"`
+			SizeExponent   string `xml:"sizeExponent,attr"`
+			SizeMultiplier string `xml:"sizeMultiplier,attr"`
+			Sum16          string `xml:"sum16,attr"`
+			Crc32          string `xml:"crc32,attr"`
+			Md5            string `xml:"md5,attr"`
+			Sha1           string `xml:"sha1,attr"`
+			Sha256         string `xml:"sha256,attr"`
+		} `xml:"chrrom"`
+		Trainer struct {
+			Size   string `xml:"size,attr"`
+			Sum16  string `xml:"sum16,attr"`
+			Crc32  string `xml:"crc32,attr"`
+			Md5    string `xml:"md5,attr"`
+			Sha1   string `xml:"sha1,attr"`
+			Sha256 string `xml:"sha256,attr"`
+		} `xml:"trainer"`
+		MiscRoms struct {
+			Size   string `xml:"size,attr"`
+			Sum16  string `xml:"sum16,attr"`
+			Crc32  string `xml:"crc32,attr"`
+			Md5    string `xml:"md5,attr"`
+			Sha1   string `xml:"sha1,attr"`
+			Sha256 string `xml:"sha256,attr"`
+		} `xml:"miscRoms"`
+		ConsoleType struct {
+			Value string `xml:"value,attr"`
+		} `xml:"consoleType"`
+		CpuPpuTiming struct {
+			Value string `xml:"value,attr"`
+		} `xml:"cpuPpuTiming"`
+		VsHardwareType struct {
+			Value string `xml:"value,attr"`
+		} `xml:"vsHardwareType"`
+		VsPpuType struct {
+			Value string `xml:"value,attr"`
+		} `xml:"vsPpuType"`
+		ExtendedConsoleType struct {
+			Value string `xml:"value,attr"`
+		} `xml:"extendedConsoleType"`
+		DefaultExpansion struct {
+			Value string `xml:"value,attr"`
+		} `xml:"defaultExpansion"`
+	} `xml:"nes20"`
+	Header10 *struct {
+		Prgrom struct {
+			Size   string `xml:"size,attr"`
+			Sum16  string `xml:"sum16,attr"`
+			Crc32  string `xml:"crc32,attr"`
+			Md5    string `xml:"md5,attr"`
+			Sha1   string `xml:"sha1,attr"`
+			Sha256 string `xml:"sha256,attr"`
+		} `xml:"prgrom"`
+		Chrrom struct {
+			Size   string `xml:"size,attr"`
+			Sum16  string `xml:"sum16,attr"`
+			Crc32  string `xml:"crc32,attr"`
+			Md5    string `xml:"md5,attr"`
+			Sha1   string `xml:"sha1,attr"`
+			Sha256 string `xml:"sha256,attr"`
+		} `xml:"chrrom"`
+		Trainer struct {
+			Size   string `xml:"size,attr"`
+			Sum16  string `xml:"sum16,attr"`
+			Crc32  string `xml:"crc32,attr"`
+			Md5    string `xml:"md5,attr"`
+			Sha1   string `xml:"sha1,attr"`
+			Sha256 string `xml:"sha256,attr"`
+		} `xml:"trainer"`
+	} `xml:"ines"`
+	FDSArchive *struct{} `xml:"fds"`
+}
+
+type databaseCheckNES20DBXML struct {
+	XMLName xml.Name `xml:"nes20db"`
+	Games   []*struct {
+		Comment string `xml:",comment"`
+		Prgrom  struct {
+			Size  string `xml:"size,attr"`
+			Crc32 string `xml:"crc32,attr"`
+			Sha1  string `xml:"sha1,attr"`
+			Sum16 string `xml:"sum16,attr"`
+		} `xml:"prgrom"`
+		Chrrom struct {
+			Size  string `xml:"size,attr"`
+			Crc32 string `xml:"crc32,attr"`
+			Sha1  string `xml:"sha1,attr"`
+			Sum16 string `xml:"sum16,attr"`
+		} `xml:"chrrom"`
+		Rom struct {
+			Size  string `xml:"size,attr"`
+			Crc32 string `xml:"crc32,attr"`
+			Sha1  string `xml:"sha1,attr"`
+		} `xml:"rom"`
+		Pcb struct {
+			Mapper    uint16 `xml:"mapper,attr"`
+			Mirroring string `xml:"mirroring,attr"`
+		} `xml:"pcb"`
+		Console struct {
+			Type   string `xml:"type,attr"`
+			Region string `xml:"region,attr"`
+		} `xml:"console"`
+		Expansion struct {
+			Type string `xml:"type,attr"`
+		} `xml:"expansion"`
+		Miscrom struct {
+			Size  string `xml:"size,attr"`
+			Crc32 string `xml:"crc32,attr"`
+			Sha1  string `xml:"sha1,attr"`
+		} `xml:"miscrom"`
+		Vs struct {
+			Hardware string `xml:"hardware,attr"`
+			Ppu      string `xml:"ppu,attr"`
+		} `xml:"vs"`
+		Trainer struct {
+			Size  string `xml:"size,attr"`
+			Crc32 string `xml:"crc32,attr"`
+			Sha1  string `xml:"sha1,attr"`
+		} `xml:"trainer"`
+	} `xml:"game"`
+}
+
+// Check a database for internal consistency.  This returns the findings
+// and the number of entries checked.
+func CheckDatabase(source *DatabaseSource) ([]*LintTool.LintResult, int, error) {
+	xmlPayload, err := ioutil.ReadFile(source.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	check := &databaseCheck{results: make([]*LintTool.LintResult, 0), keyLabels: make(map[string]string), entryLabels: make(map[string]string), entryKeys: make(map[string]string)}
+
+	if source.Format == DATABASE_FORMAT_NES20DB {
+		check.checkNES20DBPayload(string(xmlPayload))
+	} else if IsXMLIndex(string(xmlPayload)) {
+		fragmentPaths, fragmentPayloads, err := readXMLFragments(string(xmlPayload), source.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for index := range fragmentPaths {
+			check.checkXMLPayload(fragmentPayloads[index], fragmentPaths[index]+": ")
+		}
+	} else {
+		check.checkXMLPayload(string(xmlPayload), "")
+	}
+
+	return check.results, check.entries, nil
+}
+
+// Find a database check by its ID, returning nil if there isn't one
+func GetDatabaseCheckRule(ruleId string) *DatabaseCheckRule {
+	for index := range DATABASE_CHECK_RULES {
+		if DATABASE_CHECK_RULES[index].ID == ruleId {
+			return DATABASE_CHECK_RULES[index]
+		}
+	}
+
+	return nil
+}
+
+func (check *databaseCheck) addResult(ruleId string, message string) {
+	check.results = append(check.results, &LintTool.LintResult{RuleID: ruleId, Severity: GetDatabaseCheckRule(ruleId).Severity, Message: message})
+}
+
+func (check *databaseCheck) checkHash(entryLabel string, fieldName string, hashString string, byteLength int, isRequired bool) {
+	if hashString == "" {
+		if isRequired {
+			check.addResult("DB002", entryLabel+": "+fieldName+" is missing")
+		}
+
+		return
+	}
+
+	hashBytes, err := hex.DecodeString(strings.ToLower(hashString))
+	if err != nil || len(hashBytes) != byteLength {
+		check.addResult("DB002", entryLabel+": "+fieldName+" should be "+strconv.Itoa(byteLength*2)+" hex digits, but is "+hashString)
+	}
+}
+
+// Enumerated values are read the same way as when loading, where a
+// value which can't be read at all stops the database loading, so it's
+// reported as an error rather than a warning
+func (check *databaseCheck) checkEnum(entryLabel string, fieldName string, enumValue string, enum databaseCheckEnum) {
+	err := enum.UnmarshalText([]byte(enumValue))
+	if err != nil {
+		check.unreadableValues++
+		check.results = append(check.results, &LintTool.LintResult{RuleID: "DB005", Severity: LintTool.LINT_SEVERITY_ERROR, Message: entryLabel + ": " + fieldName + " \"" + enumValue + "\" isn't a valid value, so the database can't be loaded"})
+		return
+	}
+
+	enumName := enum.Name()
+	_, err = strconv.Atoi(enumName)
+	if err == nil {
+		check.addResult("DB005", entryLabel+": "+fieldName+" "+enumName+" isn't defined")
+	}
+}
+
+// Sizes are read the same way as when loading, so one which can't be
+// read is reported like an enumerated value which can't be.  Sizes
+// which aren't given are zero.
+func (check *databaseCheck) checkSize(entryLabel string, fieldName string, sizeValue string, bitSize int) uint64 {
+	if sizeValue == "" {
+		return 0
+	}
+
+	size, err := strconv.ParseUint(strings.TrimSpace(sizeValue), 10, bitSize)
+	if err != nil {
+		check.unreadableValues++
+		check.addResult("DB008", entryLabel+": "+fieldName+" \""+sizeValue+"\" isn't a valid size, so the database can't be loaded")
+		return 0
+	}
+
+	return size
+}
+
+// Anything else loading would reject stops the whole database from
+// loading, so it's only reported when the enumerated values and sizes
+// haven't already explained why
+func (check *databaseCheck) checkLoading(xmlPayload string, xmlStruct interface{}, labelPrefix string, unreadableValues int) {
+	if check.unreadableValues > unreadableValues {
+		return
+	}
+
+	err := xml.Unmarshal([]byte(xmlPayload), xmlStruct)
+	if err != nil {
+		check.addResult("DB001", labelPrefix+err.Error())
+	}
+}
+
+// Keys are what entries are stored under when loaded, and entry keys
+// are their PRG and CHR ROM hashes, which matching also falls back to
+func (check *databaseCheck) checkKeys(entryLabel string, key string, prgRomSha1 [20]byte, prgRomSize uint64, chrRomSha1 [20]byte, chrRomSize uint64) {
+	if firstLabel, hasKey := check.keyLabels[key]; hasKey {
+		check.addResult("DB003", entryLabel+": "+key+" is already used by "+firstLabel)
+		return
+	}
+
+	check.keyLabels[key] = entryLabel
+
+	entryKey := "PRG:" + getDatabaseSHA1String(prgRomSha1, prgRomSize) + ",CHR:" + getDatabaseSHA1String(chrRomSha1, chrRomSize)
+	if firstLabel, hasEntry := check.entryLabels[entryKey]; hasEntry && check.entryKeys[entryKey] != key {
+		check.addResult("DB007", entryLabel+": "+entryKey+" is the same as "+firstLabel)
+		return
+	}
+
+	check.entryLabels[entryKey] = entryLabel
+	check.entryKeys[entryKey] = key
+}
+
+func (check *databaseCheck) checkXMLPayload(xmlPayload string, labelPrefix string) {
+	xmlStruct := &databaseCheckXML{}
+	err := xml.Unmarshal([]byte(xmlPayload), xmlStruct)
+	if err != nil {
+		check.addResult("DB001", labelPrefix+err.Error())
+		return
+	}
+
+	unreadableValues := check.unreadableValues
+
+	for index := range xmlStruct.XMLROMs {
+		xmlRom := xmlStruct.XMLROMs[index]
+		check.entries++
+
+		entryLabel := labelPrefix + "rom " + strconv.Itoa(index+1)
+		if xmlRom.RelativePath != "" {
+			entryLabel = labelPrefix + xmlRom.RelativePath
+		} else if xmlRom.Name != "" {
+			entryLabel = labelPrefix + xmlRom.Name
+		}
+
+		check.checkHash(entryLabel, "CRC32", xmlRom.Crc32, 4, true)
+		check.checkHash(entryLabel, "MD5", xmlRom.Md5, 16, true)
+		check.checkHash(entryLabel, "SHA1", xmlRom.Sha1, 20, true)
+		check.checkHash(entryLabel, "SHA256", xmlRom.Sha256, 32, true)
+		// Sizes which can't be read don't get compared
+		entryUnreadableValues := check.unreadableValues
+		romSize := check.checkSize(entryLabel, "ROM size", xmlRom.Size, 64)
+
+		// FDS archives are kept apart from ROMs when loaded
+		romKey := "SHA256:" + getXMLCheckHashString(xmlRom.Sha256, 32)
+		if xmlRom.FDSArchive != nil {
+			if firstLabel, hasKey := check.keyLabels["FDS:"+romKey]; hasKey {
+				check.addResult("DB003", entryLabel+": "+romKey+" is already used by "+firstLabel)
+			} else {
+				check.keyLabels["FDS:"+romKey] = entryLabel
+			}
+
+			continue
+		}
+
+		if xmlRom.Header20 != nil {
+			header20 := xmlRom.Header20
+			prgRomSize := getXMLCheckSectionSize(check.checkSize(entryLabel, "PRG ROM size", header20.Prgrom.Size, 16), check.checkSize(entryLabel, "PRG ROM size exponent", header20.Prgrom.SizeExponent, 8), check.checkSize(entryLabel, "PRG ROM size multiplier", header20.Prgrom.SizeMultiplier, 8), 16*1024)
+			chrRomSize := getXMLCheckSectionSize(check.checkSize(entryLabel, "CHR ROM size", header20.Chrrom.Size, 16), check.checkSize(entryLabel, "CHR ROM size exponent", header20.Chrrom.SizeExponent, 8), check.checkSize(entryLabel, "CHR ROM size multiplier", header20.Chrrom.SizeMultiplier, 8), 8*1024)
+			trainerSize := check.checkSize(entryLabel, "Trainer size", header20.Trainer.Size, 16)
+			miscRomSize := check.checkSize(entryLabel, "Misc ROM size", header20.MiscRoms.Size, 64)
+
+			check.checkHash(entryLabel, "PRG ROM Sum16", header20.Prgrom.Sum16, 2, true)
+			check.checkHash(entryLabel, "PRG ROM CRC32", header20.Prgrom.Crc32, 4, true)
+			check.checkHash(entryLabel, "PRG ROM MD5", header20.Prgrom.Md5, 16, true)
+			check.checkHash(entryLabel, "PRG ROM SHA1", header20.Prgrom.Sha1, 20, true)
+			check.checkHash(entryLabel, "PRG ROM SHA256", header20.Prgrom.Sha256, 32, true)
+			check.checkHash(entryLabel, "CHR ROM Sum16", header20.Chrrom.Sum16, 2, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM CRC32", header20.Chrrom.Crc32, 4, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM MD5", header20.Chrrom.Md5, 16, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM SHA1", header20.Chrrom.Sha1, 20, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM SHA256", header20.Chrrom.Sha256, 32, chrRomSize > 0)
+			check.checkHash(entryLabel, "Trainer Sum16", header20.Trainer.Sum16, 2, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer CRC32", header20.Trainer.Crc32, 4, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer MD5", header20.Trainer.Md5, 16, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer SHA1", header20.Trainer.Sha1, 20, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer SHA256", header20.Trainer.Sha256, 32, trainerSize > 0)
+			check.checkHash(entryLabel, "Misc ROM Sum16", header20.MiscRoms.Sum16, 2, miscRomSize > 0)
+			check.checkHash(entryLabel, "Misc ROM CRC32", header20.MiscRoms.Crc32, 4, miscRomSize > 0)
+			check.checkHash(entryLabel, "Misc ROM MD5", header20.MiscRoms.Md5, 16, miscRomSize > 0)
+			check.checkHash(entryLabel, "Misc ROM SHA1", header20.MiscRoms.Sha1, 20, miscRomSize > 0)
+			check.checkHash(entryLabel, "Misc ROM SHA256", header20.MiscRoms.Sha256, 32, miscRomSize > 0)
+
+			sectionSize := prgRomSize + chrRomSize + miscRomSize
+			if check.unreadableValues == entryUnreadableValues && romSize != sectionSize {
+				check.addResult("DB004", entryLabel+": ROM size is "+strconv.FormatUint(romSize, 10)+" bytes, but the PRG, CHR and misc ROMs add up to "+strconv.FormatUint(sectionSize, 10))
+			}
+
+			check.checkEnum(entryLabel, "Console Type", header20.ConsoleType.Value, new(NESTool.ConsoleType))
+			check.checkEnum(entryLabel, "Extended Console Type", header20.ExtendedConsoleType.Value, new(NESTool.ExtendedConsoleType))
+			check.checkEnum(entryLabel, "CPU/PPU Timing", header20.CpuPpuTiming.Value, new(NESTool.CPUPPUTiming))
+			check.checkEnum(entryLabel, "Vs. Hardware Type", header20.VsHardwareType.Value, new(NESTool.VsHardwareType))
+			check.checkEnum(entryLabel, "Vs. PPU Type", header20.VsPpuType.Value, new(NESTool.VsPPUType))
+			check.checkEnum(entryLabel, "Default Expansion", header20.DefaultExpansion.Value, new(NESTool.DefaultExpansion))
+
+			check.checkKeys(entryLabel, romKey, getXMLCheckSHA1(header20.Prgrom.Sha1), prgRomSize, getXMLCheckSHA1(header20.Chrrom.Sha1), chrRomSize)
+		} else if xmlRom.Header10 != nil {
+			header10 := xmlRom.Header10
+			prgRomSize := check.checkSize(entryLabel, "PRG ROM size", header10.Prgrom.Size, 8) * 16 * 1024
+			chrRomSize := check.checkSize(entryLabel, "CHR ROM size", header10.Chrrom.Size, 8) * 8 * 1024
+			trainerSize := check.checkSize(entryLabel, "Trainer size", header10.Trainer.Size, 16)
+
+			check.checkHash(entryLabel, "PRG ROM Sum16", header10.Prgrom.Sum16, 2, true)
+			check.checkHash(entryLabel, "PRG ROM CRC32", header10.Prgrom.Crc32, 4, true)
+			check.checkHash(entryLabel, "PRG ROM MD5", header10.Prgrom.Md5, 16, true)
+			check.checkHash(entryLabel, "PRG ROM SHA1", header10.Prgrom.Sha1, 20, true)
+			check.checkHash(entryLabel, "PRG ROM SHA256", header10.Prgrom.Sha256, 32, true)
+			check.checkHash(entryLabel, "CHR ROM Sum16", header10.Chrrom.Sum16, 2, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM CRC32", header10.Chrrom.Crc32, 4, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM MD5", header10.Chrrom.Md5, 16, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM SHA1", header10.Chrrom.Sha1, 20, chrRomSize > 0)
+			check.checkHash(entryLabel, "CHR ROM SHA256", header10.Chrrom.Sha256, 32, chrRomSize > 0)
+			check.checkHash(entryLabel, "Trainer Sum16", header10.Trainer.Sum16, 2, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer CRC32", header10.Trainer.Crc32, 4, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer MD5", header10.Trainer.Md5, 16, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer SHA1", header10.Trainer.Sha1, 20, trainerSize > 0)
+			check.checkHash(entryLabel, "Trainer SHA256", header10.Trainer.Sha256, 32, trainerSize > 0)
+
+			// iNES ROMs can have data after the CHR ROM, so only a short
+			// ROM is inconsistent
+			if check.unreadableValues == entryUnreadableValues && romSize < prgRomSize+chrRomSize {
+				check.addResult("DB004", entryLabel+": ROM size is "+strconv.FormatUint(romSize, 10)+" bytes, but the PRG and CHR ROMs add up to "+strconv.FormatUint(prgRomSize+chrRomSize, 10))
+			}
+
+			check.checkKeys(entryLabel, romKey, getXMLCheckSHA1(header10.Prgrom.Sha1), prgRomSize, getXMLCheckSHA1(header10.Chrrom.Sha1), chrRomSize)
+		}
+	}
+
+	check.checkLoading(xmlPayload, &NESXML{}, labelPrefix, unreadableValues)
+}
+
+func (check *databaseCheck) checkNES20DBPayload(xmlPayload string) {
+	xmlStruct := &databaseCheckNES20DBXML{}
+	err := xml.Unmarshal([]byte(xmlPayload), xmlStruct)
+	if err != nil {
+		check.addResult("DB001", err.Error())
+		return
+	}
+
+	unreadableValues := check.unreadableValues
+
+	for index := range xmlStruct.Games {
+		game := xmlStruct.Games[index]
+		check.entries++
+
+		entryLabel := strings.TrimSpace(game.Comment)
+		if entryLabel == "" {
+			entryLabel = "game " + strconv.Itoa(index+1)
+		}
+
+		// Sizes which can't be read don't get compared
+		entryUnreadableValues := check.unreadableValues
+		romSize := check.checkSize(entryLabel, "ROM size", game.Rom.Size, 64)
+		prgRomSize := check.checkSize(entryLabel, "PRG ROM size", game.Prgrom.Size, 64)
+		chrRomSize := check.checkSize(entryLabel, "CHR ROM size", game.Chrrom.Size, 64)
+		miscRomSize := check.checkSize(entryLabel, "Misc ROM size", game.Miscrom.Size, 64)
+		trainerSize := check.checkSize(entryLabel, "Trainer size", game.Trainer.Size, 16)
+
+		check.checkHash(entryLabel, "CRC32", game.Rom.Crc32, 4, true)
+		check.checkHash(entryLabel, "SHA1", game.Rom.Sha1, 20, true)
+		check.checkHash(entryLabel, "PRG ROM Sum16", game.Prgrom.Sum16, 2, true)
+		check.checkHash(entryLabel, "PRG ROM CRC32", game.Prgrom.Crc32, 4, true)
+		check.checkHash(entryLabel, "PRG ROM SHA1", game.Prgrom.Sha1, 20, true)
+		check.checkHash(entryLabel, "CHR ROM Sum16", game.Chrrom.Sum16, 2, chrRomSize > 0)
+		check.checkHash(entryLabel, "CHR ROM CRC32", game.Chrrom.Crc32, 4, chrRomSize > 0)
+		check.checkHash(entryLabel, "CHR ROM SHA1", game.Chrrom.Sha1, 20, chrRomSize > 0)
+		check.checkHash(entryLabel, "Misc ROM CRC32", game.Miscrom.Crc32, 4, miscRomSize > 0)
+		check.checkHash(entryLabel, "Misc ROM SHA1", game.Miscrom.Sha1, 20, miscRomSize > 0)
+		check.checkHash(entryLabel, "Trainer CRC32", game.Trainer.Crc32, 4, trainerSize > 0)
+		check.checkHash(entryLabel, "Trainer SHA1", game.Trainer.Sha1, 20, trainerSize > 0)
+
+		sectionSize := prgRomSize + chrRomSize + miscRomSize
+		if check.unreadableValues == entryUnreadableValues && romSize != sectionSize {
+			check.addResult("DB004", entryLabel+": ROM size is "+strconv.FormatUint(romSize, 10)+" bytes, but the PRG, CHR and misc ROMs add up to "+strconv.FormatUint(sectionSize, 10))
+		}
+
+		// nes20db console types include the extended ones
+		check.checkEnum(entryLabel, "Console Type", game.Console.Type, new(NESTool.ExtendedConsoleType))
+		check.checkEnum(entryLabel, "Region", game.Console.Region, new(NESTool.CPUPPUTiming))
+		check.checkEnum(entryLabel, "Vs. Hardware Type", game.Vs.Hardware, new(NESTool.VsHardwareType))
+		check.checkEnum(entryLabel, "Vs. PPU Type", game.Vs.Ppu, new(NESTool.VsPPUType))
+		check.checkEnum(entryLabel, "Expansion", game.Expansion.Type, new(NESTool.DefaultExpansion))
+
+		_, _, isValidMirroring := NESTool.GetMirroringFromNES20DB(game.Pcb.Mapper, game.Pcb.Mirroring)
+		if !isValidMirroring {
+			check.addResult("DB006", entryLabel+": Mirroring \""+game.Pcb.Mirroring+"\" isn't valid for mapper "+strconv.Itoa(int(game.Pcb.Mapper)))
+		}
+
+		check.checkKeys(entryLabel, "SHA1:"+strings.ToUpper(game.Rom.Sha1), getXMLCheckSHA1(game.Prgrom.Sha1), prgRomSize, getXMLCheckSHA1(game.Chrrom.Sha1), chrRomSize)
+	}
+
+	check.checkLoading(xmlPayload, &NES20DBXML{}, "", unreadableValues)
+}
+
+// Get a section size from the default XML format, which is in units
+// unless the exponent form is used
+func getXMLCheckSectionSize(size uint64, sizeExponent uint64, sizeMultiplier uint64, unitSize uint64) uint64 {
+	if size > 0 {
+		return size * unitSize
+	} else if sizeExponent > 0 || sizeMultiplier > 0 {
+		return (1 << sizeExponent) * uint64((uint8(sizeMultiplier)*2)+1)
+	}
+
+	return 0
+}
+
+// Get a hash the way loading would, with anything that can't be
+// decoded left zeroed
+func getXMLCheckHashString(hashString string, byteLength int) string {
+	hashBytes := make([]byte, byteLength)
+	decodedBytes, err := hex.DecodeString(strings.ToLower(hashString))
+	if err == nil {
+		copy(hashBytes, decodedBytes)
+	}
+
+	return strings.ToUpper(hex.EncodeToString(hashBytes))
+}
+
+func getXMLCheckSHA1(hashString string) [20]byte {
+	var sha1Sum [20]byte
+	decodedBytes, err := hex.DecodeString(strings.ToLower(hashString))
+	if err == nil {
+		copy(sha1Sum[:], decodedBytes)
+	}
+
+	return sha1Sum
+}
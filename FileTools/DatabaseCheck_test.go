@@ -0,0 +1,247 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/LintTool"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testNES20DBGame = `	<game>
+		<!-- {name} -->
+		<prgrom size="{prgSize}" crc32="3B2B30A9" sha1="{prgSha1}" sum16="C000"></prgrom>
+		<chrrom size="0" crc32="00000000" sha1="0000000000000000000000000000000000000000" sum16="0000"></chrrom>
+		<rom size="{romSize}" crc32="3B2B30A9" sha1="{romSha1}"></rom>
+		<pcb mapper="{mapper}" submapper="0" mirroring="{mirroring}" battery="0"></pcb>
+		<console type="{consoleType}" region="0"></console>
+		<expansion type="0"></expansion>
+		<miscrom size="0" crc32="00000000" sha1="0000000000000000000000000000000000000000" number="0"></miscrom>
+		<vs hardware="{vsHardware}" ppu="0"></vs>
+		<trainer size="0" crc32="" sha1=""></trainer>
+	</game>
+`
+
+var testXMLROM = `	<rom name="{name}" size="32768" relativePath="{name}.nes" crc32="3B2B30A9" md5="923B6ADDD49C773E2E52F1EBBF537554" sha1="F2A6F57EF05479E832A9093DF131D8DE5028B754" sha256="{romSha256}">
+		<nes20>
+			<prgrom size="{prgSize}" sum16="C000" crc32="3B2B30A9" md5="923B6ADDD49C773E2E52F1EBBF537554" sha1="F2A6F57EF05479E832A9093DF131D8DE5028B754" sha256="7509DA7A121EA51CE0C0D2E7F7D12FFE885F124DD99E2633D55D84AB6DF0D471"></prgrom>
+			<chrrom size="0"></chrrom>
+			<mirroringType value="true"></mirroringType>
+			<consoleType value="{consoleType}"></consoleType>
+			<mapper value="{mapper}"></mapper>
+			<vsPpuType value="{vsPpuType}"></vsPpuType>
+			<extendedConsoleType value=""></extendedConsoleType>
+		</nes20>
+	</rom>
+`
+
+// Build a test nes20db game, with defaults for any fields not given
+func getTestNES20DBGame(fields map[string]string) string {
+	return getTestDatabaseEntry(testNES20DBGame, fields, map[string]string{
+		"name":        "game.nes",
+		"prgSha1":     "F2A6F57EF05479E832A9093DF131D8DE5028B754",
+		"prgSize":     "32768",
+		"romSha1":     "F2A6F57EF05479E832A9093DF131D8DE5028B754",
+		"romSize":     "32768",
+		"mapper":      "218",
+		"mirroring":   "1",
+		"consoleType": "0",
+		"vsHardware":  "0",
+	})
+}
+
+// Build a test default format ROM, with defaults for any fields not given
+func getTestXMLROM(fields map[string]string) string {
+	return getTestDatabaseEntry(testXMLROM, fields, map[string]string{
+		"name":        "game",
+		"romSha256":   "7509DA7A121EA51CE0C0D2E7F7D12FFE885F124DD99E2633D55D84AB6DF0D471",
+		"prgSize":     "2",
+		"mapper":      "218",
+		"consoleType": "0",
+		"vsPpuType":   "0",
+	})
+}
+
+func getTestDatabaseEntry(entryTemplate string, fields map[string]string, defaultFields map[string]string) string {
+	for fieldName := range defaultFields {
+		fieldValue, hasField := fields[fieldName]
+		if !hasField {
+			fieldValue = defaultFields[fieldName]
+		}
+
+		entryTemplate = strings.Replace(entryTemplate, "{"+fieldName+"}", fieldValue, -1)
+	}
+
+	return entryTemplate
+}
+
+func TestCheckDatabase(t *testing.T) {
+	otherSha1 := "1EDF14B9F91477ED8071B1F66E2D4C2849501B91"
+
+	tests := []struct {
+		name             string
+		format           string
+		payload          string
+		expectedEntries  int
+		expectedResults  []string
+		expectedSeverity int
+		expectedMessage  string
+	}{
+		{
+			name:             "valid nes20db",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(nil) + getTestNES20DBGame(map[string]string{"name": "other.nes", "prgSha1": otherSha1, "romSha1": otherSha1}) + "</nes20db>\n",
+			expectedEntries:  2,
+			expectedSeverity: -1,
+		},
+		{
+			name:             "unparseable nes20db",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(nil),
+			expectedResults:  []string{"DB001"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+		},
+		{
+			name:             "nes20db number that can't be read",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"mapper": "x218"}) + "</nes20db>\n",
+			expectedResults:  []string{"DB001"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+		},
+		{
+			name:             "nes20db enums that can't be read are each reported",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"consoleType": "99"}) + getTestNES20DBGame(map[string]string{"name": "other.nes", "prgSha1": otherSha1, "romSha1": otherSha1, "vsHardware": "famicom", "romSize": "40000"}) + "</nes20db>\n",
+			expectedEntries:  2,
+			expectedResults:  []string{"DB005", "DB004", "DB005"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+			expectedMessage:  "game.nes: Console Type \"99\" isn't a valid value, so the database can't be loaded",
+		},
+		{
+			name:             "nes20db sizes that can't be read are each reported",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"romSize": "32K"}) + getTestNES20DBGame(map[string]string{"name": "other.nes", "prgSha1": otherSha1, "romSha1": otherSha1, "prgSize": "-1"}) + "</nes20db>\n",
+			expectedEntries:  2,
+			expectedResults:  []string{"DB008", "DB008"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+			expectedMessage:  "game.nes: ROM size \"32K\" isn't a valid size, so the database can't be loaded",
+		},
+		{
+			name:             "nes20db undefined enum",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"consoleType": "15"}) + "</nes20db>\n",
+			expectedEntries:  1,
+			expectedResults:  []string{"DB005"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_WARNING),
+			expectedMessage:  "game.nes: Console Type 15 isn't defined",
+		},
+		{
+			name:             "nes20db empty enum",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"consoleType": ""}) + "</nes20db>\n",
+			expectedEntries:  1,
+			expectedSeverity: -1,
+		},
+		{
+			name:             "nes20db hashes, keys and mirroring",
+			format:           DATABASE_FORMAT_NES20DB,
+			payload:          "<nes20db>\n" + getTestNES20DBGame(map[string]string{"prgSha1": "F2A6"}) + getTestNES20DBGame(nil) + getTestNES20DBGame(map[string]string{"name": "other.nes", "romSha1": otherSha1, "mirroring": "Q"}) + getTestNES20DBGame(map[string]string{"name": "third.nes", "romSha1": "0631457264FF7F8D5FB1EDC2C0211992A67C73E6"}) + "</nes20db>\n",
+			expectedEntries:  4,
+			expectedResults:  []string{"DB002", "DB003", "DB006", "DB007"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+		},
+		{
+			name:             "valid default format",
+			format:           DATABASE_FORMAT_DEFAULT,
+			payload:          "<nesroms>\n" + getTestXMLROM(nil) + "</nesroms>\n",
+			expectedEntries:  1,
+			expectedSeverity: -1,
+		},
+		{
+			name:             "default format enums that can't be read are each reported",
+			format:           DATABASE_FORMAT_DEFAULT,
+			payload:          "<nesroms>\n" + getTestXMLROM(map[string]string{"consoleType": "famicom"}) + getTestXMLROM(map[string]string{"name": "other", "romSha256": "E11360251D1173650CDCD20F111D8F1CA2E412F572E8B36A4DC067121C1799B8", "vsPpuType": "20"}) + "</nesroms>\n",
+			expectedEntries:  2,
+			expectedResults:  []string{"DB005", "DB005", "DB007"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+			expectedMessage:  "other.nes: Vs. PPU Type \"20\" isn't a valid value, so the database can't be loaded",
+		},
+		{
+			name:             "default format sizes that can't be read are each reported",
+			format:           DATABASE_FORMAT_DEFAULT,
+			payload:          "<nesroms>\n" + getTestXMLROM(map[string]string{"prgSize": "70000"}) + getTestXMLROM(map[string]string{"name": "other", "romSha256": "E11360251D1173650CDCD20F111D8F1CA2E412F572E8B36A4DC067121C1799B8", "prgSize": "two"}) + "</nesroms>\n",
+			expectedEntries:  2,
+			expectedResults:  []string{"DB008", "DB008", "DB007"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+			expectedMessage:  "game.nes: PRG ROM size \"70000\" isn't a valid size, so the database can't be loaded",
+		},
+		{
+			name:             "default format number that can't be read",
+			format:           DATABASE_FORMAT_DEFAULT,
+			payload:          "<nesroms>\n" + getTestXMLROM(map[string]string{"mapper": "x218"}) + "</nesroms>\n",
+			expectedEntries:  1,
+			expectedResults:  []string{"DB001"},
+			expectedSeverity: int(LintTool.LINT_SEVERITY_ERROR),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			databasePath := filepath.Join(t.TempDir(), "database.xml")
+			err := ioutil.WriteFile(databasePath, []byte(test.payload), 0644)
+			if err != nil {
+				t.Fatalf("unable to write database: %v", err)
+			}
+
+			checkResults, checkedEntries, err := CheckDatabase(&DatabaseSource{Format: test.format, Path: databasePath})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if checkedEntries != test.expectedEntries {
+				t.Fatalf("expected %d entries, got %d", test.expectedEntries, checkedEntries)
+			}
+
+			ruleIds := make([]string, 0, len(checkResults))
+			hasMessage := test.expectedMessage == ""
+			for index := range checkResults {
+				ruleIds = append(ruleIds, checkResults[index].RuleID)
+				if checkResults[index].Message == test.expectedMessage {
+					hasMessage = true
+				}
+			}
+
+			if len(ruleIds) != len(test.expectedResults) || (len(ruleIds) > 0 && !reflect.DeepEqual(ruleIds, test.expectedResults)) {
+				t.Fatalf("expected results %v, got %v", test.expectedResults, ruleIds)
+			}
+
+			if LintTool.GetHighestLintSeverity(checkResults) != test.expectedSeverity {
+				t.Fatalf("expected highest severity %d, got %d", test.expectedSeverity, LintTool.GetHighestLintSeverity(checkResults))
+			}
+
+			if !hasMessage {
+				t.Fatalf("expected a result with message %q", test.expectedMessage)
+			}
+		})
+	}
+}
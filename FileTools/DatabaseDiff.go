@@ -0,0 +1,272 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This compares two ROM databases, which can be in different formats,
+// and reports which entries were added, removed, or changed.  Entries
+// are matched by their PRG and CHR ROM hashes, and changed entries list
+// each header field that differs.  Entries sharing their hashes with
+// another entry in the same database are listed separately.
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+var (
+	DATABASE_DIFF_DATABASE_NEW = "new"
+	DATABASE_DIFF_DATABASE_OLD = "old"
+
+	DATABASE_DIFF_FIELD_HEADER_FORMAT = "header-format"
+	DATABASE_DIFF_FIELD_NAME          = "name"
+	DATABASE_DIFF_FIELD_RELATIVE_PATH = "relative-path"
+)
+
+type DatabaseDiff struct {
+	Added      []*DatabaseDiffEntry `json:"added"`
+	Removed    []*DatabaseDiffEntry `json:"removed"`
+	Changed    []*DatabaseDiffEntry `json:"changed"`
+	Duplicates []*DatabaseDiffEntry `json:"duplicates"`
+}
+
+type DatabaseDiffEntry struct {
+	Key          string                 `json:"key"`
+	Database     string                 `json:"database,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	RelativePath string                 `json:"relativePath,omitempty"`
+	PrgRomSha1   string                 `json:"prgRomSha1"`
+	ChrRomSha1   string                 `json:"chrRomSha1"`
+	Changes      []*DatabaseFieldChange `json:"changes,omitempty"`
+}
+
+type DatabaseFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Compare two ROM maps.  Fields are only compared when both entries
+// have them.  When a database has more than one entry with the same
+// hashes, the first in GetSortedNESROMKeys order is compared, and the
+// rest are listed as duplicates.
+func DiffDatabases(oldRoms map[string]*NESTool.NESROM, newRoms map[string]*NESTool.NESROM) (*DatabaseDiff, error) {
+	oldEntries, oldDuplicates := getDatabaseEntryMap(oldRoms)
+	newEntries, newDuplicates := getDatabaseEntryMap(newRoms)
+
+	diff := &DatabaseDiff{Added: make([]*DatabaseDiffEntry, 0), Removed: make([]*DatabaseDiffEntry, 0), Changed: make([]*DatabaseDiffEntry, 0), Duplicates: make([]*DatabaseDiffEntry, 0)}
+
+	for index := range oldDuplicates {
+		diffEntry := getDatabaseDiffEntry(GetDatabaseEntryKey(oldDuplicates[index]), oldDuplicates[index])
+		diffEntry.Database = DATABASE_DIFF_DATABASE_OLD
+		diff.Duplicates = append(diff.Duplicates, diffEntry)
+	}
+
+	for index := range newDuplicates {
+		diffEntry := getDatabaseDiffEntry(GetDatabaseEntryKey(newDuplicates[index]), newDuplicates[index])
+		diffEntry.Database = DATABASE_DIFF_DATABASE_NEW
+		diff.Duplicates = append(diff.Duplicates, diffEntry)
+	}
+
+	for key := range newEntries {
+		if oldEntries[key] == nil {
+			diff.Added = append(diff.Added, getDatabaseDiffEntry(key, newEntries[key]))
+		}
+	}
+
+	for key := range oldEntries {
+		if newEntries[key] == nil {
+			diff.Removed = append(diff.Removed, getDatabaseDiffEntry(key, oldEntries[key]))
+			continue
+		}
+
+		changes, err := GetDatabaseFieldChanges(oldEntries[key], newEntries[key])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(changes) > 0 {
+			diffEntry := getDatabaseDiffEntry(key, newEntries[key])
+			diffEntry.Changes = changes
+			diff.Changed = append(diff.Changed, diffEntry)
+		}
+	}
+
+	sortDatabaseDiffEntries(diff.Added)
+	sortDatabaseDiffEntries(diff.Removed)
+	sortDatabaseDiffEntries(diff.Changed)
+	sortDatabaseDiffEntries(diff.Duplicates)
+
+	return diff, nil
+}
+
+// Get the fields which differ between two entries for the same ROM
+func GetDatabaseFieldChanges(oldRom *NESTool.NESROM, newRom *NESTool.NESROM) ([]*DatabaseFieldChange, error) {
+	changes := make([]*DatabaseFieldChange, 0)
+
+	for _, fieldName := range GetDatabaseFieldNames() {
+		oldValue, oldHasField, err := GetDatabaseFieldValue(oldRom, fieldName)
+		if err != nil {
+			return nil, err
+		}
+
+		newValue, newHasField, err := GetDatabaseFieldValue(newRom, fieldName)
+		if err != nil {
+			return nil, err
+		}
+
+		if oldHasField && newHasField && oldValue != newValue {
+			changes = append(changes, &DatabaseFieldChange{Field: fieldName, Old: oldValue, New: newValue})
+		}
+	}
+
+	return changes, nil
+}
+
+// Get the names of the fields compared between database entries, which
+// are the header fields along with a few describing the entry itself
+func GetDatabaseFieldNames() []string {
+	fieldNames := []string{DATABASE_DIFF_FIELD_NAME, DATABASE_DIFF_FIELD_RELATIVE_PATH, DATABASE_DIFF_FIELD_HEADER_FORMAT}
+	return append(fieldNames, NESTool.NES_HEADER_FIELD_NAMES...)
+}
+
+// Get the value of a compared field for a database entry.  Names and
+// relative paths count as missing when they're empty, since not every
+// format records them, and header fields are missing when the entry's
+// header type doesn't have them.
+func GetDatabaseFieldValue(rom *NESTool.NESROM, fieldName string) (string, bool, error) {
+	switch fieldName {
+	case DATABASE_DIFF_FIELD_NAME:
+		return rom.Name, rom.Name != "", nil
+	case DATABASE_DIFF_FIELD_RELATIVE_PATH:
+		relativePath := getXMLSortRelativePath(rom.RelativePath)
+		return relativePath, relativePath != "", nil
+	case DATABASE_DIFF_FIELD_HEADER_FORMAT:
+		return getDatabaseHeaderFormat(rom), true, nil
+	}
+
+	return NESTool.GetNESROMHeaderField(rom, fieldName)
+}
+
+func (diff *DatabaseDiff) String() string {
+	returnString := "Added: " + strconv.Itoa(len(diff.Added)) + "\n"
+	returnString = returnString + "Removed: " + strconv.Itoa(len(diff.Removed)) + "\n"
+	returnString = returnString + "Changed: " + strconv.Itoa(len(diff.Changed)) + "\n"
+	returnString = returnString + "Duplicates: " + strconv.Itoa(len(diff.Duplicates)) + "\n"
+
+	for index := range diff.Added {
+		returnString = returnString + "\n+ " + diff.Added[index].String() + "\n"
+	}
+
+	for index := range diff.Removed {
+		returnString = returnString + "\n- " + diff.Removed[index].String() + "\n"
+	}
+
+	for index := range diff.Changed {
+		returnString = returnString + "\n~ " + diff.Changed[index].String() + "\n"
+		for changeIndex := range diff.Changed[index].Changes {
+			change := diff.Changed[index].Changes[changeIndex]
+			returnString = returnString + "    " + change.Field + ": " + change.Old + " -> " + change.New + "\n"
+		}
+	}
+
+	for index := range diff.Duplicates {
+		returnString = returnString + "\n! " + diff.Duplicates[index].Database + ": " + diff.Duplicates[index].String() + "\n"
+	}
+
+	return returnString
+}
+
+func (entry *DatabaseDiffEntry) String() string {
+	entryName := entry.Name
+	if entry.RelativePath != "" {
+		entryName = entry.RelativePath
+	}
+
+	if entryName == "" {
+		return entry.Key
+	}
+
+	return entryName + " (" + entry.Key + ")"
+}
+
+// Marshal a database diff to indented JSON
+func MarshalDatabaseDiff(diff *DatabaseDiff) (string, error) {
+	jsonBytes, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// Key a ROM map by PRG and CHR ROM hashes.  Two entries with the same
+// hashes can't be told apart, so the first in GetSortedNESROMKeys order
+// (relative path, then name, then hash) is kept, and the rest are
+// returned in that order.
+func getDatabaseEntryMap(nesRoms map[string]*NESTool.NESROM) (map[string]*NESTool.NESROM, []*NESTool.NESROM) {
+	entryMap := make(map[string]*NESTool.NESROM)
+	duplicateRoms := make([]*NESTool.NESROM, 0)
+
+	for _, romKey := range GetSortedNESROMKeys(nesRoms) {
+		entryKey := GetDatabaseEntryKey(nesRoms[romKey])
+		if entryMap[entryKey] != nil {
+			duplicateRoms = append(duplicateRoms, nesRoms[romKey])
+			continue
+		}
+
+		entryMap[entryKey] = nesRoms[romKey]
+	}
+
+	return entryMap, duplicateRoms
+}
+
+func getDatabaseDiffEntry(key string, rom *NESTool.NESROM) *DatabaseDiffEntry {
+	prgRomSha1, chrRomSha1 := getDatabaseROMHashes(rom)
+
+	return &DatabaseDiffEntry{Key: key, Name: rom.Name, RelativePath: getXMLSortRelativePath(rom.RelativePath), PrgRomSha1: prgRomSha1, ChrRomSha1: chrRomSha1}
+}
+
+func getDatabaseHeaderFormat(rom *NESTool.NESROM) string {
+	if rom.Header20 != nil {
+		return "nes20"
+	}
+
+	return "ines"
+}
+
+func sortDatabaseDiffEntries(entries []*DatabaseDiffEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RelativePath != entries[j].RelativePath {
+			return entries[i].RelativePath < entries[j].RelativePath
+		}
+
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+
+		return entries[i].Database < entries[j].Database
+	})
+}
@@ -0,0 +1,150 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffDatabases(t *testing.T) {
+	tests := []struct {
+		name               string
+		oldRoms            map[string]*NESTool.NESROM
+		newRoms            map[string]*NESTool.NESROM
+		expectedAdded      []string
+		expectedRemoved    []string
+		expectedChanged    []string
+		expectedDuplicates []string
+	}{
+		{
+			name:    "identical",
+			oldRoms: map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4)},
+			newRoms: map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4)},
+		},
+		{
+			name:            "added and removed",
+			oldRoms:         map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4)},
+			newRoms:         map[string]*NESTool.NESROM{"b": getTestDatabaseROM("B", 2, 4)},
+			expectedAdded:   []string{"B"},
+			expectedRemoved: []string{"A"},
+		},
+		{
+			name:            "changed",
+			oldRoms:         map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4)},
+			newRoms:         map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 1)},
+			expectedChanged: []string{"A"},
+		},
+		{
+			name:            "matched by hashes rather than keys",
+			oldRoms:         map[string]*NESTool.NESROM{"SHA256:00": getTestDatabaseROM("A", 1, 4)},
+			newRoms:         map[string]*NESTool.NESROM{"SHA1:00": getTestDatabaseROM("A (Rev 1)", 1, 4)},
+			expectedChanged: []string{"A (Rev 1)"},
+		},
+		{
+			name:               "duplicates in both databases",
+			oldRoms:            map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4), "b": getTestDatabaseROM("A (Alt)", 1, 1)},
+			newRoms:            map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4), "b": getTestDatabaseROM("A (Alt)", 1, 1), "c": getTestDatabaseROM("A (Alt 2)", 1, 1)},
+			expectedDuplicates: []string{"A (Alt 2)", "A (Alt)", "A (Alt)"},
+		},
+		{
+			name:               "first duplicate in sorted order is compared",
+			oldRoms:            map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4)},
+			newRoms:            map[string]*NESTool.NESROM{"b": getTestDatabaseROM("A", 1, 4), "c": getTestDatabaseROM("A (Alt)", 1, 1)},
+			expectedDuplicates: []string{"A (Alt)"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff, err := DiffDatabases(test.oldRoms, test.newRoms)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			checkDatabaseDiffEntryNames(t, "added", diff.Added, test.expectedAdded)
+			checkDatabaseDiffEntryNames(t, "removed", diff.Removed, test.expectedRemoved)
+			checkDatabaseDiffEntryNames(t, "changed", diff.Changed, test.expectedChanged)
+			checkDatabaseDiffEntryNames(t, "duplicates", diff.Duplicates, test.expectedDuplicates)
+		})
+	}
+}
+
+func TestDiffDatabasesChanges(t *testing.T) {
+	oldRom := getTestDatabaseROM("A", 1, 4)
+	newRom := getTestDatabaseROM("A", 1, 1)
+	newRom.Header20.SubMapper = 1
+
+	diff, err := DiffDatabases(map[string]*NESTool.NESROM{"a": oldRom}, map[string]*NESTool.NESROM{"a": newRom})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(diff.Changed) != 1 {
+		t.Fatalf("expected 1 changed entry, got %d", len(diff.Changed))
+	}
+
+	expected := []*DatabaseFieldChange{
+		{Field: "mapper-number", Old: "4", New: "1"},
+		{Field: "submapper-number", Old: "0", New: "1"},
+	}
+
+	if !reflect.DeepEqual(diff.Changed[0].Changes, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, diff.Changed[0].Changes)
+	}
+}
+
+func TestDatabaseDiffDuplicatesString(t *testing.T) {
+	oldRoms := map[string]*NESTool.NESROM{"a": getTestDatabaseROM("A", 1, 4), "b": getTestDatabaseROM("A (Alt)", 1, 1)}
+
+	diff, err := DiffDatabases(oldRoms, map[string]*NESTool.NESROM{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	diffString := diff.String()
+	if !strings.Contains(diffString, "Duplicates: 1\n") || !strings.Contains(diffString, "\n! old: A (Alt) (PRG:") {
+		t.Fatalf("duplicate missing from diff:\n%s", diffString)
+	}
+
+	diffPayload, err := MarshalDatabaseDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(diffPayload, "\"database\": \"old\"") {
+		t.Fatalf("duplicate database missing from JSON:\n%s", diffPayload)
+	}
+}
+
+func checkDatabaseDiffEntryNames(t *testing.T, section string, entries []*DatabaseDiffEntry, expectedNames []string) {
+	t.Helper()
+
+	entryNames := make([]string, 0, len(entries))
+	for index := range entries {
+		entryNames = append(entryNames, entries[index].Name)
+	}
+
+	if len(entryNames) != len(expectedNames) || (len(expectedNames) > 0 && !reflect.DeepEqual(entryNames, expectedNames)) {
+		t.Fatalf("expected %s entries %v, got %v", section, expectedNames, entryNames)
+	}
+}
@@ -0,0 +1,360 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This merges several ROM databases into one.  Sources are given in
+// order of precedence, so an entry from an earlier source wins over
+// the same entry from a later one.  Merge rules can take individual
+// fields from a different source, and fields the sources disagree on
+// are collected into a conflict report, along with any rules which
+// couldn't be applied.
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"NES20Tool/NESTool"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type DatabaseMergeRule struct {
+	Field       string
+	SourceIndex int
+}
+
+type DatabaseMergeReport struct {
+	Sources   []string                 `json:"sources"`
+	Entries   int                      `json:"entries"`
+	Conflicts []*DatabaseMergeConflict `json:"conflicts"`
+}
+
+type DatabaseMergeConflict struct {
+	Key          string                        `json:"key"`
+	Name         string                        `json:"name,omitempty"`
+	RelativePath string                        `json:"relativePath,omitempty"`
+	Fields       []*DatabaseMergeFieldConflict `json:"fields"`
+	SkippedRules []*DatabaseMergeSkippedRule   `json:"skippedRules,omitempty"`
+}
+
+type DatabaseMergeFieldConflict struct {
+	Field  string                     `json:"field"`
+	Chosen string                     `json:"chosen"`
+	Values []*DatabaseMergeFieldValue `json:"values"`
+}
+
+type DatabaseMergeFieldValue struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+type DatabaseMergeSkippedRule struct {
+	Field  string `json:"field"`
+	Source string `json:"source"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Parse merge rules in "field=source" form, separated by commas, where
+// source is the position of a database in the precedence order,
+// starting from 1
+func ParseDatabaseMergeRules(rulesSpec string, sourceCount int) ([]*DatabaseMergeRule, error) {
+	mergeRules := make([]*DatabaseMergeRule, 0)
+	if strings.TrimSpace(rulesSpec) == "" {
+		return mergeRules, nil
+	}
+
+	for _, ruleSpec := range strings.Split(rulesSpec, ",") {
+		ruleParts := strings.Split(strings.TrimSpace(ruleSpec), "=")
+		if len(ruleParts) != 2 {
+			return nil, errors.New("Merge rules must be in field=source form: " + ruleSpec)
+		}
+
+		fieldName := strings.TrimSpace(ruleParts[0])
+		if fieldName != DATABASE_DIFF_FIELD_NAME && fieldName != DATABASE_DIFF_FIELD_RELATIVE_PATH && !NESTool.IsNESHeaderFieldName(fieldName) {
+			return nil, errors.New("Unknown field in merge rule: " + fieldName)
+		}
+
+		sourceNumber, err := strconv.Atoi(strings.TrimSpace(ruleParts[1]))
+		if err != nil || sourceNumber < 1 || sourceNumber > sourceCount {
+			return nil, errors.New("Merge rule source must be a number from 1-" + strconv.Itoa(sourceCount) + ": " + ruleSpec)
+		}
+
+		mergeRules = append(mergeRules, &DatabaseMergeRule{Field: fieldName, SourceIndex: sourceNumber - 1})
+	}
+
+	return mergeRules, nil
+}
+
+// Merge ROM maps in order of precedence.  Entries are matched by their
+// PRG and CHR ROM hashes, and the result is keyed the same way.  Each
+// merged entry is the one from the first source that has it, with any
+// merge rules applied where the rule's source also has the entry and
+// the field.  Rules for fields which don't apply to the merged entry,
+// such as Vs. System fields for another console type, are skipped and
+// reported.  Entries are changed in place.
+func MergeDatabases(romMaps []map[string]*NESTool.NESROM, sourceNames []string, mergeRules []*DatabaseMergeRule) (map[string]*NESTool.NESROM, *DatabaseMergeReport, error) {
+	entryMaps := make([]map[string]*NESTool.NESROM, 0, len(romMaps))
+	for index := range romMaps {
+		entryMap, duplicateRoms := getDatabaseEntryMap(romMaps[index])
+		if len(duplicateRoms) > 0 {
+			return nil, nil, errors.New(sourceNames[index] + ": Duplicate PRG and CHR ROM hashes in database: " + GetDatabaseEntryKey(duplicateRoms[0]))
+		}
+
+		entryMaps = append(entryMaps, entryMap)
+	}
+
+	mergedRoms := make(map[string]*NESTool.NESROM)
+	report := &DatabaseMergeReport{Sources: sourceNames, Conflicts: make([]*DatabaseMergeConflict, 0)}
+
+	for sourceIndex := range entryMaps {
+		for _, entryKey := range GetSortedNESROMKeys(entryMaps[sourceIndex]) {
+			if mergedRoms[entryKey] != nil {
+				continue
+			}
+
+			fieldConflicts, skippedRules, err := mergeDatabaseEntry(entryKey, sourceIndex, entryMaps, sourceNames, mergeRules)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			mergedRom := entryMaps[sourceIndex][entryKey]
+			mergedRoms[entryKey] = mergedRom
+
+			if len(fieldConflicts) > 0 || len(skippedRules) > 0 {
+				report.Conflicts = append(report.Conflicts, &DatabaseMergeConflict{Key: entryKey, Name: mergedRom.Name, RelativePath: getXMLSortRelativePath(mergedRom.RelativePath), Fields: fieldConflicts, SkippedRules: skippedRules})
+			}
+		}
+	}
+
+	report.Entries = len(mergedRoms)
+
+	return mergedRoms, report, nil
+}
+
+// Merge FDS archive maps in order of precedence.  Archives don't have
+// headers to disagree on, so the first source with an archive wins.
+// Each archive's HeaderSource is set to the name of the database it
+// came from.
+func MergeFDSArchives(archiveMaps []map[string]*FDSTool.FDSArchiveFile, sourceNames []string) map[string]*FDSTool.FDSArchiveFile {
+	mergedArchives := make(map[string]*FDSTool.FDSArchiveFile)
+
+	for index := range archiveMaps {
+		for key := range archiveMaps[index] {
+			if mergedArchives[key] == nil {
+				archiveMaps[index][key].HeaderSource = sourceNames[index]
+				mergedArchives[key] = archiveMaps[index][key]
+			}
+		}
+	}
+
+	return mergedArchives
+}
+
+func (report *DatabaseMergeReport) String() string {
+	returnString := "Sources: " + strings.Join(report.Sources, ", ") + "\n"
+	returnString = returnString + "Entries: " + strconv.Itoa(report.Entries) + "\n"
+	returnString = returnString + "Conflicts: " + strconv.Itoa(len(report.Conflicts)) + "\n"
+
+	for index := range report.Conflicts {
+		conflict := report.Conflicts[index]
+		entryName := conflict.RelativePath
+		if entryName == "" {
+			entryName = conflict.Name
+		}
+
+		if entryName == "" {
+			returnString = returnString + "\n" + conflict.Key + "\n"
+		} else {
+			returnString = returnString + "\n" + entryName + " (" + conflict.Key + ")\n"
+		}
+
+		for fieldIndex := range conflict.Fields {
+			returnString = returnString + "    " + conflict.Fields[fieldIndex].Field + "\n"
+			for valueIndex := range conflict.Fields[fieldIndex].Values {
+				fieldValue := conflict.Fields[fieldIndex].Values[valueIndex]
+				if fieldValue.Source == conflict.Fields[fieldIndex].Chosen {
+					returnString = returnString + "      * "
+				} else {
+					returnString = returnString + "        "
+				}
+
+				returnString = returnString + fieldValue.Source + ": " + fieldValue.Value + "\n"
+			}
+		}
+
+		for ruleIndex := range conflict.SkippedRules {
+			skippedRule := conflict.SkippedRules[ruleIndex]
+			returnString = returnString + "    " + skippedRule.Field + " rule skipped for " + skippedRule.Source + ": " + skippedRule.Value + " (" + skippedRule.Reason + ")\n"
+		}
+	}
+
+	return returnString
+}
+
+// Marshal a database merge report to indented JSON
+func MarshalDatabaseMergeReport(report *DatabaseMergeReport) (string, error) {
+	jsonBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// Find the fields the sources disagree on for one entry, then apply the
+// merge rules to the winning entry.  Header fields are copied as they
+// are from the rule's source, in the order of the field names, so a
+// console type rule is applied before the fields which depend on it.
+func mergeDatabaseEntry(entryKey string, winningIndex int, entryMaps []map[string]*NESTool.NESROM, sourceNames []string, mergeRules []*DatabaseMergeRule) ([]*DatabaseMergeFieldConflict, []*DatabaseMergeSkippedRule, error) {
+	winningRom := entryMaps[winningIndex][entryKey]
+	fieldConflicts := make([]*DatabaseMergeFieldConflict, 0)
+
+	for _, fieldName := range GetDatabaseFieldNames() {
+		fieldValues := make([]*DatabaseMergeFieldValue, 0)
+		isConflict := false
+
+		for sourceIndex := range entryMaps {
+			sourceRom := entryMaps[sourceIndex][entryKey]
+			if sourceRom == nil {
+				continue
+			}
+
+			sourceValue, sourceHasField, err := GetDatabaseFieldValue(sourceRom, fieldName)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !sourceHasField {
+				continue
+			}
+
+			if len(fieldValues) > 0 && fieldValues[0].Value != sourceValue {
+				isConflict = true
+			}
+
+			fieldValues = append(fieldValues, &DatabaseMergeFieldValue{Source: sourceNames[sourceIndex], Value: sourceValue})
+		}
+
+		if isConflict {
+			fieldConflicts = append(fieldConflicts, &DatabaseMergeFieldConflict{Field: fieldName, Chosen: sourceNames[winningIndex], Values: fieldValues})
+		}
+	}
+
+	chosenSources := make(map[string]string)
+	skippedRules := make([]*DatabaseMergeSkippedRule, 0)
+
+	for _, fieldName := range GetDatabaseFieldNames() {
+		mergeRule := getDatabaseMergeRule(mergeRules, fieldName)
+		if mergeRule == nil || mergeRule.SourceIndex == winningIndex {
+			continue
+		}
+
+		ruleRom := entryMaps[mergeRule.SourceIndex][entryKey]
+		if ruleRom == nil {
+			continue
+		}
+
+		ruleValue, ruleHasField, err := GetDatabaseFieldValue(ruleRom, fieldName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		winningValue, winningHasField, err := GetDatabaseFieldValue(winningRom, fieldName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ruleHasField || (winningHasField && ruleValue == winningValue) {
+			continue
+		}
+
+		skipReason := ""
+		if !winningHasField {
+			skipReason = getDatabaseMergeCopyReason(ruleRom, winningRom)
+		} else if fieldName == DATABASE_DIFF_FIELD_NAME {
+			winningRom.Name = ruleRom.Name
+		} else if fieldName == DATABASE_DIFF_FIELD_RELATIVE_PATH {
+			winningRom.RelativePath = strings.Replace(ruleValue, "/", string(os.PathSeparator), -1)
+		} else {
+			skipReason = getDatabaseMergeSkipReason(winningRom, fieldName)
+			if skipReason == "" {
+				isCopied, err := NESTool.CopyNESROMHeaderField(winningRom, ruleRom, fieldName)
+				if err != nil {
+					skipReason = err.Error()
+				} else if !isCopied {
+					skipReason = getDatabaseMergeCopyReason(ruleRom, winningRom)
+				}
+			}
+		}
+
+		if skipReason != "" {
+			skippedRules = append(skippedRules, &DatabaseMergeSkippedRule{Field: fieldName, Source: sourceNames[mergeRule.SourceIndex], Value: ruleValue, Reason: skipReason})
+			continue
+		}
+
+		chosenSources[fieldName] = sourceNames[mergeRule.SourceIndex]
+	}
+
+	for index := range fieldConflicts {
+		if chosenSource, hasRule := chosenSources[fieldConflicts[index].Field]; hasRule {
+			fieldConflicts[index].Chosen = chosenSource
+		}
+	}
+
+	return fieldConflicts, skippedRules, nil
+}
+
+// Get the merge rule for a field.  When there's more than one, the last
+// one given wins.
+func getDatabaseMergeRule(mergeRules []*DatabaseMergeRule, fieldName string) *DatabaseMergeRule {
+	var mergeRule *DatabaseMergeRule
+	for index := range mergeRules {
+		if mergeRules[index].Field == fieldName {
+			mergeRule = mergeRules[index]
+		}
+	}
+
+	return mergeRule
+}
+
+func getDatabaseMergeCopyReason(ruleRom *NESTool.NESROM, winningRom *NESTool.NESROM) string {
+	return "the field can't be copied between " + getDatabaseHeaderFormat(ruleRom) + " and " + getDatabaseHeaderFormat(winningRom) + " headers"
+}
+
+// Get the reason a header field doesn't apply to an entry with its
+// current console type, or an empty string if it does
+func getDatabaseMergeSkipReason(rom *NESTool.NESROM, fieldName string) string {
+	if rom.Header20 == nil {
+		return ""
+	}
+
+	consoleType := NESTool.ConsoleType(rom.Header20.ConsoleType)
+	if (fieldName == "vs-hardware-type" || fieldName == "vs-ppu-type") && consoleType != NESTool.CONSOLE_TYPE_VS_SYSTEM {
+		return "only valid for console type " + NESTool.CONSOLE_TYPE_VS_SYSTEM.Name() + ", not " + consoleType.Name()
+	}
+
+	if fieldName == "extended-console-type" && consoleType != NESTool.CONSOLE_TYPE_EXTENDED {
+		return "only valid for console type " + NESTool.CONSOLE_TYPE_EXTENDED.Name() + ", not " + consoleType.Name()
+	}
+
+	return ""
+}
@@ -0,0 +1,275 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"NES20Tool/NESTool"
+	"reflect"
+	"testing"
+)
+
+func TestParseDatabaseMergeRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rulesSpec   string
+		sourceCount int
+		expected    []*DatabaseMergeRule
+		expectError bool
+	}{
+		{name: "empty", rulesSpec: "", sourceCount: 2, expected: []*DatabaseMergeRule{}},
+		{name: "whitespace", rulesSpec: "  ", sourceCount: 2, expected: []*DatabaseMergeRule{}},
+		{name: "one rule", rulesSpec: "mapper-number=2", sourceCount: 2, expected: []*DatabaseMergeRule{{Field: "mapper-number", SourceIndex: 1}}},
+		{name: "several rules with spaces", rulesSpec: " name = 1 , vs-ppu-type=3", sourceCount: 3, expected: []*DatabaseMergeRule{{Field: "name", SourceIndex: 0}, {Field: "vs-ppu-type", SourceIndex: 2}}},
+		{name: "relative path", rulesSpec: "relative-path=2", sourceCount: 2, expected: []*DatabaseMergeRule{{Field: "relative-path", SourceIndex: 1}}},
+		{name: "unknown field", rulesSpec: "mapper=1", sourceCount: 2, expectError: true},
+		{name: "header format", rulesSpec: "header-format=1", sourceCount: 2, expectError: true},
+		{name: "missing source", rulesSpec: "mapper-number", sourceCount: 2, expectError: true},
+		{name: "too many parts", rulesSpec: "mapper-number=1=2", sourceCount: 2, expectError: true},
+		{name: "source zero", rulesSpec: "mapper-number=0", sourceCount: 2, expectError: true},
+		{name: "source past the last database", rulesSpec: "mapper-number=3", sourceCount: 2, expectError: true},
+		{name: "source not a number", rulesSpec: "mapper-number=second", sourceCount: 2, expectError: true},
+		{name: "empty rule", rulesSpec: "mapper-number=1,", sourceCount: 2, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mergeRules, err := ParseDatabaseMergeRules(test.rulesSpec, test.sourceCount)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %d rules", len(mergeRules))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(mergeRules, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, mergeRules)
+			}
+		})
+	}
+}
+
+// Build an entry for merging with the given console type fields.  The
+// Vs. System PPU type is set to the same value as the hardware type.
+func getTestMergeROM(name string, consoleType NESTool.ConsoleType, vsHardwareType uint8, extendedConsoleType uint8) *NESTool.NESROM {
+	rom := getTestDatabaseROM(name, 1, 99)
+	rom.Header20.ConsoleType = uint8(consoleType)
+	rom.Header20.VsHardwareType = vsHardwareType
+	rom.Header20.VsPPUType = vsHardwareType
+	rom.Header20.ExtendedConsoleType = extendedConsoleType
+
+	return rom
+}
+
+// Build an iNES entry with the same PRG ROM as getTestMergeROM
+func getTestMergeINESROM(name string) *NESTool.NESROM {
+	return &NESTool.NESROM{
+		Name: name,
+		Header10: &NESTool.NES10Header{
+			PRGROMCalculatedSize: 16 * 1024,
+			PRGROMSHA1:           [20]byte{1},
+			Mapper:               99,
+		},
+	}
+}
+
+func TestMergeDatabases(t *testing.T) {
+	tests := []struct {
+		name                        string
+		roms                        []*NESTool.NESROM
+		rulesSpec                   string
+		expectedName                string
+		expectedConsoleType         NESTool.ConsoleType
+		expectedVsHardwareType      uint8
+		expectedExtendedConsoleType uint8
+		expectedConflicts           []string
+		expectedChosen              []string
+		expectedSkipped             []string
+	}{
+		{
+			name:                   "first source wins",
+			roms:                   []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 1, 0), getTestMergeROM("B", NESTool.CONSOLE_TYPE_VS_SYSTEM, 2, 0)},
+			expectedName:           "A",
+			expectedConsoleType:    NESTool.CONSOLE_TYPE_VS_SYSTEM,
+			expectedVsHardwareType: 1,
+			expectedConflicts:      []string{"name", "vs-hardware-type", "vs-ppu-type"},
+			expectedChosen:         []string{"1", "1", "1"},
+		},
+		{
+			name:                   "rule takes a field from a later source",
+			roms:                   []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 1, 0), getTestMergeROM("B", NESTool.CONSOLE_TYPE_VS_SYSTEM, 2, 0)},
+			rulesSpec:              "vs-hardware-type=2,name=2",
+			expectedName:           "B",
+			expectedConsoleType:    NESTool.CONSOLE_TYPE_VS_SYSTEM,
+			expectedVsHardwareType: 2,
+			expectedConflicts:      []string{"name", "vs-hardware-type", "vs-ppu-type"},
+			expectedChosen:         []string{"2", "2", "1"},
+		},
+		{
+			name:                "Vs. System rule for another console type is skipped",
+			roms:                []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_NES, 0, 0), getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 2, 0)},
+			rulesSpec:           "vs-hardware-type=2",
+			expectedName:        "A",
+			expectedConsoleType: NESTool.CONSOLE_TYPE_NES,
+			expectedConflicts:   []string{"console-type", "vs-hardware-type", "vs-ppu-type"},
+			expectedChosen:      []string{"1", "1", "1"},
+			expectedSkipped:     []string{"vs-hardware-type"},
+		},
+		{
+			name:                        "console type rule keeps the other fields",
+			roms:                        []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_EXTENDED, 2, 5), getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 2, 0)},
+			rulesSpec:                   "console-type=2",
+			expectedName:                "A",
+			expectedConsoleType:         NESTool.CONSOLE_TYPE_VS_SYSTEM,
+			expectedVsHardwareType:      2,
+			expectedExtendedConsoleType: 5,
+			expectedConflicts:           []string{"console-type", "extended-console-type"},
+			expectedChosen:              []string{"2", "1"},
+		},
+		{
+			name:                   "console type rule is applied before the fields depending on it",
+			roms:                   []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_NES, 0, 0), getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 3, 0)},
+			rulesSpec:              "vs-ppu-type=2,vs-hardware-type=2,console-type=2",
+			expectedName:           "A",
+			expectedConsoleType:    NESTool.CONSOLE_TYPE_VS_SYSTEM,
+			expectedVsHardwareType: 3,
+			expectedConflicts:      []string{"console-type", "vs-hardware-type", "vs-ppu-type"},
+			expectedChosen:         []string{"2", "2", "2"},
+		},
+		{
+			name:                   "extended console type rule for another console type is skipped",
+			roms:                   []*NESTool.NESROM{getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 1, 0), getTestMergeROM("A", NESTool.CONSOLE_TYPE_EXTENDED, 0, 5), getTestMergeROM("A", NESTool.CONSOLE_TYPE_VS_SYSTEM, 2, 0)},
+			rulesSpec:              "extended-console-type=2,vs-hardware-type=3",
+			expectedName:           "A",
+			expectedConsoleType:    NESTool.CONSOLE_TYPE_VS_SYSTEM,
+			expectedVsHardwareType: 2,
+			expectedConflicts:      []string{"console-type", "extended-console-type", "vs-hardware-type", "vs-ppu-type"},
+			expectedChosen:         []string{"1", "1", "3", "1"},
+			expectedSkipped:        []string{"extended-console-type"},
+		},
+		{
+			name:              "NES 2.0 field rule for an iNES entry is skipped",
+			roms:              []*NESTool.NESROM{getTestMergeINESROM("A"), getTestMergeROM("A", NESTool.CONSOLE_TYPE_NES, 0, 0)},
+			rulesSpec:         "submapper-number=2",
+			expectedName:      "A",
+			expectedConflicts: []string{"header-format"},
+			expectedChosen:    []string{"1"},
+			expectedSkipped:   []string{"submapper-number"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			romMaps := make([]map[string]*NESTool.NESROM, 0)
+			sourceNames := make([]string, 0)
+			for index := range test.roms {
+				romMaps = append(romMaps, map[string]*NESTool.NESROM{"SHA1:" + string(rune('A'+index)): test.roms[index]})
+				sourceNames = append(sourceNames, string(rune('1'+index)))
+			}
+
+			mergeRules, err := ParseDatabaseMergeRules(test.rulesSpec, len(test.roms))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mergedRoms, report, err := MergeDatabases(romMaps, sourceNames, mergeRules)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(mergedRoms) != 1 || report.Entries != 1 {
+				t.Fatalf("expected 1 merged entry, got %d", len(mergedRoms))
+			}
+
+			mergedRom := mergedRoms[GetDatabaseEntryKey(test.roms[0])]
+			if mergedRom != test.roms[0] {
+				t.Fatalf("expected the first source's entry to be merged")
+			}
+
+			if mergedRom.Name != test.expectedName {
+				t.Fatalf("expected name %s, got %s", test.expectedName, mergedRom.Name)
+			}
+
+			if mergedRom.Header20 != nil && (mergedRom.Header20.ConsoleType != uint8(test.expectedConsoleType) || mergedRom.Header20.VsHardwareType != test.expectedVsHardwareType || mergedRom.Header20.ExtendedConsoleType != test.expectedExtendedConsoleType) {
+				t.Fatalf("expected console type %d, Vs. hardware type %d and extended console type %d, got %d, %d and %d", test.expectedConsoleType, test.expectedVsHardwareType, test.expectedExtendedConsoleType, mergedRom.Header20.ConsoleType, mergedRom.Header20.VsHardwareType, mergedRom.Header20.ExtendedConsoleType)
+			}
+
+			conflictFields := make([]string, 0)
+			chosenSources := make([]string, 0)
+			skippedFields := make([]string, 0)
+			for index := range report.Conflicts {
+				for fieldIndex := range report.Conflicts[index].Fields {
+					conflictFields = append(conflictFields, report.Conflicts[index].Fields[fieldIndex].Field)
+					chosenSources = append(chosenSources, report.Conflicts[index].Fields[fieldIndex].Chosen)
+				}
+
+				for ruleIndex := range report.Conflicts[index].SkippedRules {
+					skippedFields = append(skippedFields, report.Conflicts[index].SkippedRules[ruleIndex].Field)
+				}
+			}
+
+			if len(conflictFields) != len(test.expectedConflicts) || (len(conflictFields) > 0 && !reflect.DeepEqual(conflictFields, test.expectedConflicts)) {
+				t.Fatalf("expected conflicts %v, got %v", test.expectedConflicts, conflictFields)
+			}
+
+			if len(chosenSources) != len(test.expectedChosen) || (len(chosenSources) > 0 && !reflect.DeepEqual(chosenSources, test.expectedChosen)) {
+				t.Fatalf("expected chosen sources %v, got %v", test.expectedChosen, chosenSources)
+			}
+
+			if len(skippedFields) != len(test.expectedSkipped) || (len(skippedFields) > 0 && !reflect.DeepEqual(skippedFields, test.expectedSkipped)) {
+				t.Fatalf("expected skipped rules %v, got %v", test.expectedSkipped, skippedFields)
+			}
+		})
+	}
+}
+
+func TestMergeDatabasesDuplicates(t *testing.T) {
+	romMaps := []map[string]*NESTool.NESROM{{"a": getTestDatabaseROM("A", 1, 4), "b": getTestDatabaseROM("A (Alt)", 1, 4)}}
+
+	_, _, err := MergeDatabases(romMaps, []string{"1"}, []*DatabaseMergeRule{})
+	if err == nil {
+		t.Fatalf("expected an error for duplicate entries")
+	}
+}
+
+func TestMergeFDSArchives(t *testing.T) {
+	archiveMaps := []map[string]*FDSTool.FDSArchiveFile{
+		{"SHA256:A": {Name: "A"}},
+		{"SHA256:A": {Name: "A (Alt)"}, "SHA256:B": {Name: "B"}},
+	}
+
+	mergedArchives := MergeFDSArchives(archiveMaps, []string{"first", "second"})
+	if len(mergedArchives) != 2 {
+		t.Fatalf("expected 2 archives, got %d", len(mergedArchives))
+	}
+
+	if mergedArchives["SHA256:A"].Name != "A" || mergedArchives["SHA256:A"].HeaderSource != "first" {
+		t.Fatalf("expected A from first, got %s from %s", mergedArchives["SHA256:A"].Name, mergedArchives["SHA256:A"].HeaderSource)
+	}
+
+	if mergedArchives["SHA256:B"].Name != "B" || mergedArchives["SHA256:B"].HeaderSource != "second" {
+		t.Fatalf("expected B from second, got %s from %s", mergedArchives["SHA256:B"].Name, mergedArchives["SHA256:B"].HeaderSource)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"reflect"
+	"testing"
+)
+
+// Build a database entry with a 16 KiB PRG ROM whose hash starts with
+// the given byte, and no CHR ROM
+func getTestDatabaseROM(name string, prgRomHashByte byte, mapper uint16) *NESTool.NESROM {
+	return &NESTool.NESROM{
+		Name: name,
+		Header20: &NESTool.NES20Header{
+			PRGROMCalculatedSize: 16 * 1024,
+			PRGROMSHA1:           [20]byte{prgRomHashByte},
+			Mapper:               mapper,
+		},
+	}
+}
+
+func TestParseDatabaseSource(t *testing.T) {
+	tests := []struct {
+		name           string
+		sourceSpec     string
+		expectedFormat string
+		expectedPath   string
+		expectError    bool
+	}{
+		{name: "default format", sourceSpec: "default:roms.xml", expectedFormat: DATABASE_FORMAT_DEFAULT, expectedPath: "roms.xml"},
+		{name: "nes20db format", sourceSpec: "nes20db:nes20db.xml", expectedFormat: DATABASE_FORMAT_NES20DB, expectedPath: "nes20db.xml"},
+		{name: "path without a format", sourceSpec: "roms.xml", expectedFormat: DATABASE_FORMAT_DEFAULT, expectedPath: "roms.xml"},
+		{name: "Windows drive letter", sourceSpec: "C:\\roms.xml", expectedFormat: DATABASE_FORMAT_DEFAULT, expectedPath: "C:\\roms.xml"},
+		{name: "format and Windows drive letter", sourceSpec: "nes20db:C:\\nes20db.xml", expectedFormat: DATABASE_FORMAT_NES20DB, expectedPath: "C:\\nes20db.xml"},
+		{name: "unknown format", sourceSpec: "json:roms.json", expectedFormat: DATABASE_FORMAT_DEFAULT, expectedPath: "json:roms.json"},
+		{name: "missing path", sourceSpec: "nes20db:", expectError: true},
+		{name: "empty", sourceSpec: "", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := ParseDatabaseSource(test.sourceSpec)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", source.String())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if source.Format != test.expectedFormat || source.Path != test.expectedPath {
+				t.Fatalf("expected %s:%s, got %s", test.expectedFormat, test.expectedPath, source.String())
+			}
+		})
+	}
+}
+
+func TestGetDatabaseEntryKey(t *testing.T) {
+	nonZeroSha1 := "AB00000000000000000000000000000000000000"
+
+	tests := []struct {
+		name     string
+		rom      *NESTool.NESROM
+		expected string
+	}{
+		{name: "NES 2.0 header", rom: getTestDatabaseROM("", 0xAB, 0), expected: "PRG:" + nonZeroSha1 + ",CHR:" + SHA1_ZERO_SUM},
+		{name: "iNES header", rom: &NESTool.NESROM{Header10: &NESTool.NES10Header{PRGROMCalculatedSize: 16 * 1024, PRGROMSHA1: [20]byte{0xAB}}}, expected: "PRG:" + nonZeroSha1 + ",CHR:" + SHA1_ZERO_SUM},
+		{name: "zeroed hash with a size", rom: &NESTool.NESROM{Header20: &NESTool.NES20Header{PRGROMCalculatedSize: 16 * 1024}}, expected: "PRG:" + SHA1_ZERO_SUM + ",CHR:" + SHA1_ZERO_SUM},
+		{name: "hash without a size", rom: &NESTool.NESROM{Header20: &NESTool.NES20Header{PRGROMSHA1: [20]byte{0xAB}, CHRROMSHA1: [20]byte{0xAB}}}, expected: "PRG:" + SHA1_ZERO_SUM + ",CHR:" + SHA1_ZERO_SUM},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entryKey := GetDatabaseEntryKey(test.rom)
+			if entryKey != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, entryKey)
+			}
+		})
+	}
+}
+
+func TestCombineDatabases(t *testing.T) {
+	wholeRomRom := getTestDatabaseROM("B (default)", 2, 4)
+	wholeRomRom.SHA1 = [20]byte{0xCD}
+	wholeRomRom.SHA256 = [32]byte{0xCD}
+
+	// The same ROM described with a different PRG ROM hash, such as
+	// from a different split between PRG and CHR ROM
+	wholeRomNes20dbRom := getTestDatabaseROM("B (nes20db)", 3, 4)
+	wholeRomNes20dbRom.SHA1 = [20]byte{0xCD}
+
+	tests := []struct {
+		name            string
+		romMaps         []map[string]*NESTool.NESROM
+		expectedSources map[string]string
+	}{
+		{
+			name: "separate entries",
+			romMaps: []map[string]*NESTool.NESROM{
+				{"SHA256:A": getTestDatabaseROM("A", 1, 4)},
+				{"SHA1:B": getTestDatabaseROM("B", 2, 4)},
+			},
+			expectedSources: map[string]string{"SHA256:A": "first", "SHA1:B": "second"},
+		},
+		{
+			name: "same PRG and CHR ROM hashes",
+			romMaps: []map[string]*NESTool.NESROM{
+				{"SHA1:A": getTestDatabaseROM("A (nes20db)", 1, 4)},
+				{"SHA256:A": getTestDatabaseROM("A (default)", 1, 1)},
+			},
+			expectedSources: map[string]string{"SHA1:A": "first"},
+		},
+		{
+			name: "same key",
+			romMaps: []map[string]*NESTool.NESROM{
+				{"SHA256:A": getTestDatabaseROM("A", 1, 4)},
+				{"SHA256:A": getTestDatabaseROM("A (Alt)", 2, 4)},
+			},
+			expectedSources: map[string]string{"SHA256:A": "first"},
+		},
+		{
+			name: "same whole ROM hash with other PRG and CHR ROM hashes",
+			romMaps: []map[string]*NESTool.NESROM{
+				{"SHA1:CD": wholeRomNes20dbRom},
+				{"SHA256:CD": wholeRomRom},
+			},
+			expectedSources: map[string]string{"SHA1:CD": "first"},
+		},
+		{
+			name: "duplicates within one database are kept",
+			romMaps: []map[string]*NESTool.NESROM{
+				{"SHA256:A": getTestDatabaseROM("A", 1, 4), "SHA256:B": getTestDatabaseROM("A (Alt)", 1, 4)},
+			},
+			expectedSources: map[string]string{"SHA256:A": "first", "SHA256:B": "first"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sourceNames := []string{"first", "second"}[:len(test.romMaps)]
+			combinedRoms := CombineDatabases(test.romMaps, sourceNames)
+
+			romSources := make(map[string]string)
+			for romKey := range combinedRoms {
+				romSources[romKey] = combinedRoms[romKey].HeaderSource
+			}
+
+			if !reflect.DeepEqual(romSources, test.expectedSources) {
+				t.Fatalf("expected %v, got %v", test.expectedSources, romSources)
+			}
+		})
+	}
+}
@@ -247,13 +247,24 @@ func LoadUNIF(fileName string, basePath string, printChecksums bool) (*NESTool.N
 }
 
 // Read in an FDS file and decode it into an FDSArchiveFile struct
-func LoadFDSArchive(fileName string, basePath string, generateChecksums bool, printChecksums bool) (*FDSTool.FDSArchiveFile, error) {
+func LoadFDSArchive(fileName string, basePath string, generateChecksums bool, generateNormalizedHashes bool, printChecksums bool) (*FDSTool.FDSArchiveFile, error) {
 	byteSlice, relativePath, err := LoadFile(fileName, basePath)
 	if err != nil {
 		return nil, err
 	}
 
-	decodedArchive, err := FDSTool.DecodeFDSArchive(byteSlice, relativePath, generateChecksums)
+	println("Loading FDS archive: " + fileName)
+
+	decodedArchive, err := FDSTool.DecodeFDSArchive(byteSlice, relativePath, generateChecksums, generateNormalizedHashes)
+	if err != nil {
+		switch err.(type) {
+		case *FDSTool.FDSError:
+			return nil, &FDSTool.FDSError{Text: fileName + ": " + err.Error()}
+		default:
+			return nil, err
+		}
+	}
+
 	if decodedArchive != nil {
 		decodedArchive.Filename = fileName
 		tempName := filepath.Base(fileName)
@@ -265,8 +276,6 @@ func LoadFDSArchive(fileName string, basePath string, generateChecksums bool, pr
 		decodedArchive.Name = tempName
 	}
 
-	println("Loading FDS archive: " + fileName)
-
 	if printChecksums && decodedArchive != nil {
 		crc32Bytes := make([]byte, 4)
 		binary.BigEndian.PutUint32(crc32Bytes, decodedArchive.CRC32)
@@ -274,6 +283,15 @@ func LoadFDSArchive(fileName string, basePath string, generateChecksums bool, pr
 		println("MD5   : " + strings.ToUpper(hex.EncodeToString(decodedArchive.MD5[:])))
 		println("SHA1  : " + strings.ToUpper(hex.EncodeToString(decodedArchive.SHA1[:])))
 		println("SHA256: " + strings.ToUpper(hex.EncodeToString(decodedArchive.SHA256[:])))
+
+		if generateNormalizedHashes {
+			normalizedCrc32Bytes := make([]byte, 4)
+			binary.BigEndian.PutUint32(normalizedCrc32Bytes, decodedArchive.NormalizedCRC32)
+			println("Normalized CRC32 : " + strings.ToUpper(hex.EncodeToString(normalizedCrc32Bytes)))
+			println("Normalized MD5   : " + strings.ToUpper(hex.EncodeToString(decodedArchive.NormalizedMD5[:])))
+			println("Normalized SHA1  : " + strings.ToUpper(hex.EncodeToString(decodedArchive.NormalizedSHA1[:])))
+			println("Normalized SHA256: " + strings.ToUpper(hex.EncodeToString(decodedArchive.NormalizedSHA256[:])))
+		}
 	}
 
 	return decodedArchive, nil
@@ -323,6 +341,39 @@ func LoadROMRecursive(basePath string, enableInes bool, preserveTrainers bool, p
 	return romSlice, nil
 }
 
+// Find NES ROM files recursively from a given base path, without
+// decoding them
+func FindROMFilesRecursive(basePath string) ([]string, error) {
+	romFiles := make([]string, 0)
+	nesRegEx, err := regexp.Compile("^.+\\.nes$")
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && nesRegEx.MatchString(info.Name()) {
+			romFiles = append(romFiles, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return romFiles, nil
+}
+
 // Read in UNIF files recursively from a given base path
 func LoadUNIFRecursive(basePath string, printChecksums bool) ([]*NESTool.NESROM, error) {
 	romSlice := make([]*NESTool.NESROM, 0)
@@ -373,7 +424,7 @@ func LoadUNIFRecursive(basePath string, printChecksums bool) ([]*NESTool.NESROM,
 }
 
 // Read in FDS files recursively from a given path
-func LoadFDSArchiveRecursive(basePath string, generateChecksums bool, printChecksums bool) ([]*FDSTool.FDSArchiveFile, error) {
+func LoadFDSArchiveRecursive(basePath string, generateChecksums bool, generateNormalizedHashes bool, printChecksums bool) ([]*FDSTool.FDSArchiveFile, error) {
 	archiveSlice := make([]*FDSTool.FDSArchiveFile, 0)
 	fdsRegEx, err := regexp.Compile("^.+\\.fds$")
 	if err != nil {
@@ -391,10 +442,11 @@ func LoadFDSArchiveRecursive(basePath string, generateChecksums bool, printCheck
 		}
 
 		if !info.IsDir() && fdsRegEx.MatchString(info.Name()) {
-			tempArchive, err := LoadFDSArchive(path, basePath, generateChecksums, printChecksums)
+			tempArchive, err := LoadFDSArchive(path, basePath, generateChecksums, generateNormalizedHashes, printChecksums)
 			if err != nil {
 				switch err.(type) {
 				case *FDSTool.FDSError:
+					println(err.Error())
 					break
 				default:
 					return err
@@ -510,8 +562,8 @@ func LoadUNIFRecursiveMap(basePath string, hashTypes uint64, printChecksums bool
 
 // Read in FDS files and add them to a map, with checksums as keys
 //TODO: Determine a better way to identify duplicates based on archive/filesystem contents
-func LoadFDSArchiveRecursiveMap(basePath string, generateChecksums bool, hashTypes uint64, printChecksums bool) (map[string]*FDSTool.FDSArchiveFile, error) {
-	archiveSlice, err := LoadFDSArchiveRecursive(basePath, generateChecksums, printChecksums)
+func LoadFDSArchiveRecursiveMap(basePath string, generateChecksums bool, generateNormalizedHashes bool, hashTypes uint64, printChecksums bool) (map[string]*FDSTool.FDSArchiveFile, error) {
+	archiveSlice, err := LoadFDSArchiveRecursive(basePath, generateChecksums, generateNormalizedHashes, printChecksums)
 	if err != nil {
 		switch err.(type) {
 		case *FDSTool.FDSError:
@@ -561,6 +613,23 @@ func WriteROM(romModel *NESTool.NESROM, enableInes bool, truncateRom bool, prese
 		return err
 	}
 
+	return writeROMBytes(romModel, nesRomBytes, destinationBasePath)
+}
+
+// Encode and write an NES ROM to disk without its header, as used by
+// No-Intro style sets.  The trainer is only kept if preserveTrainer is set.
+func WriteHeaderlessROM(romModel *NESTool.NESROM, enableInes bool, truncateRom bool, preserveTrainer bool, destinationBasePath string) error {
+	nesRomBytes, err := NESTool.EncodeNESROM(romModel, enableInes, truncateRom, preserveTrainer)
+	if err != nil {
+		return err
+	}
+
+	return writeROMBytes(romModel, nesRomBytes[16:], destinationBasePath)
+}
+
+// Write encoded ROM bytes to the ROM's filename, or to its relative path
+// under a destination base path
+func writeROMBytes(romModel *NESTool.NESROM, nesRomBytes []byte, destinationBasePath string) error {
 	if destinationBasePath == "" {
 		tempFilename := romModel.Filename
 		if tempFilename == "" {
@@ -581,12 +650,11 @@ func WriteROM(romModel *NESTool.NESROM, enableInes bool, truncateRom bool, prese
 		tempRomPath = tempRomPath + tempRelativePath
 		directoryPath := tempRomPath[0:strings.LastIndex(tempRomPath, string(os.PathSeparator))]
 
-		defer func() {
-			err := os.MkdirAll(directoryPath, os.ModeDir|0770)
-			if err != nil {
-				panic(errors.New("Unable to create directory: " + directoryPath))
-			}
-		}()
+		// The directory has to exist before the ROM can be written into it
+		err := os.MkdirAll(directoryPath, os.ModeDir|0770)
+		if err != nil {
+			return errors.New("Unable to create directory: " + directoryPath)
+		}
 
 		return ioutil.WriteFile(tempRomPath, nesRomBytes, 0644)
 	}
@@ -594,7 +662,7 @@ func WriteROM(romModel *NESTool.NESROM, enableInes bool, truncateRom bool, prese
 
 // Encode and write an FDS archive to disk
 func WriteFDSArchive(archiveModel *FDSTool.FDSArchiveFile, writeFDSHeader bool, destinationBasePath string) error {
-	fdsArchiveBytes, err := FDSTool.EncodeFDSArchive(archiveModel, writeFDSHeader, false, false, false)
+	fdsArchiveBytes, err := FDSTool.EncodeFDSArchive(archiveModel, writeFDSHeader, false, false, false, false)
 	if err != nil {
 		return err
 	}
@@ -619,12 +687,11 @@ func WriteFDSArchive(archiveModel *FDSTool.FDSArchiveFile, writeFDSHeader bool,
 		tempRomPath = tempRomPath + tempRelativePath
 		directoryPath := tempRomPath[0:strings.LastIndex(tempRomPath, string(os.PathSeparator))]
 
-		defer func() {
-			err := os.MkdirAll(directoryPath, os.ModeDir|0770)
-			if err != nil {
-				panic(errors.New("Unable to create directory: " + directoryPath))
-			}
-		}()
+		// The directory has to exist before the archive can be written into it
+		err := os.MkdirAll(directoryPath, os.ModeDir|0770)
+		if err != nil {
+			return errors.New("Unable to create directory: " + directoryPath)
+		}
 
 		return ioutil.WriteFile(tempRomPath, fdsArchiveBytes, 0644)
 	}
@@ -0,0 +1,109 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Header spec files list header fields to edit, using the same field
+// names as the editheaderfield operation.  They can either be a flat
+// JSON object, or plain "field: value" or "field = value" lines with
+// "#" comments.
+
+package FileTools
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Read a header spec file into a map of field names to values
+func LoadHeaderSpec(specPath string) (map[string]string, error) {
+	specBytes, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseHeaderSpec(string(specBytes))
+}
+
+// Parse a header spec in either JSON or key/value form
+func ParseHeaderSpec(specPayload string) (map[string]string, error) {
+	if strings.HasPrefix(strings.TrimSpace(specPayload), "{") {
+		return parseJSONHeaderSpec(specPayload)
+	}
+
+	fieldValues := make(map[string]string)
+
+	for lineIndex, specLine := range strings.Split(specPayload, "\n") {
+		commentIndex := strings.Index(specLine, "#")
+		if commentIndex >= 0 {
+			specLine = specLine[:commentIndex]
+		}
+
+		specLine = strings.TrimSpace(specLine)
+		if specLine == "" {
+			continue
+		}
+
+		separatorIndex := strings.IndexAny(specLine, ":=")
+		if separatorIndex <= 0 {
+			return nil, errors.New("Invalid header spec line " + strconv.Itoa(lineIndex+1) + ": " + specLine)
+		}
+
+		fieldName := strings.TrimSpace(specLine[:separatorIndex])
+		fieldValue := strings.Trim(strings.TrimSpace(specLine[separatorIndex+1:]), "\"'")
+
+		if _, hasField := fieldValues[fieldName]; hasField {
+			return nil, errors.New("Header field " + fieldName + " is set more than once.")
+		}
+
+		fieldValues[fieldName] = fieldValue
+	}
+
+	return fieldValues, nil
+}
+
+func parseJSONHeaderSpec(specPayload string) (map[string]string, error) {
+	rawFieldValues := make(map[string]interface{})
+	err := json.Unmarshal([]byte(specPayload), &rawFieldValues)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldValues := make(map[string]string)
+
+	for fieldName, rawValue := range rawFieldValues {
+		switch fieldValue := rawValue.(type) {
+		case string:
+			fieldValues[fieldName] = fieldValue
+		case bool:
+			fieldValues[fieldName] = strconv.FormatBool(fieldValue)
+		case float64:
+			if fieldValue < 0 || fieldValue != float64(uint64(fieldValue)) {
+				return nil, errors.New("Header field " + fieldName + " must be a whole number.")
+			}
+
+			fieldValues[fieldName] = strconv.FormatUint(uint64(fieldValue), 10)
+		default:
+			return nil, errors.New("Header field " + fieldName + " must be a string, number, or boolean.")
+		}
+	}
+
+	return fieldValues, nil
+}
@@ -0,0 +1,97 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaderSpec(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		expected    map[string]string
+		expectError bool
+	}{
+		{name: "key/value lines", payload: "mapper: 4\nsubmapper = 1\n", expected: map[string]string{"mapper": "4", "submapper": "1"}},
+		{name: "comments and blank lines", payload: "# MMC3\n\nmapper: 4 # TxROM\n  \n", expected: map[string]string{"mapper": "4"}},
+		{name: "quoted values", payload: "mirroring: \"h\"\nbattery = 'true'\n", expected: map[string]string{"mirroring": "h", "battery": "true"}},
+		{name: "windows line endings", payload: "mapper: 4\r\nbattery: false\r\n", expected: map[string]string{"mapper": "4", "battery": "false"}},
+		{name: "empty value", payload: "mapper:\n", expected: map[string]string{"mapper": ""}},
+		{name: "empty spec", payload: "\n# nothing here\n", expected: map[string]string{}},
+		{name: "line without a separator", payload: "mapper 4\n", expectError: true},
+		{name: "line without a field name", payload: ": 4\n", expectError: true},
+		{name: "field set twice", payload: "mapper: 4\nmapper = 5\n", expectError: true},
+		{name: "JSON", payload: `{"mapper": 4, "battery": true, "mirroring": "v"}`, expected: map[string]string{"mapper": "4", "battery": "true", "mirroring": "v"}},
+		{name: "JSON with leading whitespace", payload: "\n  {\"submapper\": 0}", expected: map[string]string{"submapper": "0"}},
+		{name: "JSON fraction", payload: `{"mapper": 4.5}`, expectError: true},
+		{name: "JSON negative number", payload: `{"mapper": -1}`, expectError: true},
+		{name: "JSON null", payload: `{"mapper": null}`, expectError: true},
+		{name: "JSON nested object", payload: `{"mapper": {"number": 4}}`, expectError: true},
+		{name: "invalid JSON", payload: `{"mapper": 4`, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fieldValues, err := ParseHeaderSpec(test.payload)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", fieldValues)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(fieldValues, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, fieldValues)
+			}
+		})
+	}
+}
+
+func TestLoadHeaderSpec(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "header.spec")
+	err := ioutil.WriteFile(specPath, []byte("mapper: 4\nbattery: true\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write spec file: %v", err)
+	}
+
+	fieldValues, err := LoadHeaderSpec(specPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"mapper": "4", "battery": "true"}
+	if !reflect.DeepEqual(fieldValues, expected) {
+		t.Fatalf("expected %v, got %v", expected, fieldValues)
+	}
+
+	_, err = LoadHeaderSpec(filepath.Join(t.TempDir(), "missing.spec"))
+	if err == nil {
+		t.Fatalf("expected an error for a missing spec file")
+	}
+}
@@ -32,7 +32,6 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 var (
@@ -42,7 +41,7 @@ var (
 type NES20DBXML struct {
 	XMLName xml.Name       `xml:"nes20db"`
 	Text    string         `xml:",chardata"`
-	Date    string         `xml:"date,attr"`
+	Date    string         `xml:"date,attr,omitempty"`
 	Games   []*NES20DBGame `xml:"game"`
 }
 
@@ -77,13 +76,13 @@ type NES20DBGame struct {
 		Battery   uint8  `xml:"battery,attr"`
 	} `xml:"pcb"`
 	Console struct {
-		Text   string `xml:",chardata"`
-		Type   uint8  `xml:"type,attr"`
-		Region uint8  `xml:"region,attr"`
+		Text   string                      `xml:",chardata"`
+		Type   NESTool.ExtendedConsoleType `xml:"type,attr"`
+		Region NESTool.CPUPPUTiming        `xml:"region,attr"`
 	} `xml:"console"`
 	Expansion struct {
-		Text string `xml:",chardata"`
-		Type uint8  `xml:"type,attr"`
+		Text string                   `xml:",chardata"`
+		Type NESTool.DefaultExpansion `xml:"type,attr"`
 	} `xml:"expansion"`
 	Chrram struct {
 		Text string `xml:",chardata"`
@@ -105,9 +104,9 @@ type NES20DBGame struct {
 		Number uint8  `xml:"number,attr"`
 	} `xml:"miscrom"`
 	Vs struct {
-		Text     string `xml:",chardata"`
-		Hardware uint8  `xml:"hardware,attr"`
-		Ppu      uint8  `xml:"ppu,attr"`
+		Text     string                 `xml:",chardata"`
+		Hardware NESTool.VsHardwareType `xml:"hardware,attr"`
+		Ppu      NESTool.VsPPUType      `xml:"ppu,attr"`
 	} `xml:"vs"`
 	Chrnvram struct {
 		Text string `xml:",chardata"`
@@ -121,19 +120,26 @@ type NES20DBGame struct {
 	} `xml:"trainer"`
 }
 
-// Take a map of NES 2.0 ROMs and marshal an XML file in nes20db format from them
-func MarshalNES20DBXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, enableOrganization bool) (string, error) {
+// Take a map of NES 2.0 ROMs and marshal an XML file in nes20db format from them.
+// Games are sorted by relative path, then name, then SHA256, and dbDate is
+// passed to GetNES20DBDate to pick the date attribute.
+func MarshalNES20DBXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, enableOrganization bool, dbDate string, canonicalXml bool) (string, error) {
 	romXml := &NES20DBXML{}
 
-	romXml.Date = time.Now().Format("2006-01-02")
+	romDate, err := GetNES20DBDate(dbDate, canonicalXml)
+	if err != nil {
+		return "", err
+	}
+
+	romXml.Date = romDate
 
-	for index := range nesRoms {
+	for _, index := range GetSortedNESROMKeys(nesRoms) {
 		if nesRoms[index].Header20 != nil {
 			tempGame := &NES20DBGame{}
 
 			if enableOrganization {
 				tempRelativePath := nesRoms[index].RelativePath
-				if tempRelativePath[0] == os.PathSeparator {
+				if len(tempRelativePath) > 0 && tempRelativePath[0] == os.PathSeparator {
 					tempRelativePath = tempRelativePath[1:]
 				}
 				tempRelativePath = strings.Replace(tempRelativePath, string(os.PathSeparator), "\\", -1)
@@ -180,60 +186,23 @@ func MarshalNES20DBXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, enableOrgan
 			tempGame.Pcb.Mapper = nesRoms[index].Header20.Mapper
 			tempGame.Pcb.Submapper = nesRoms[index].Header20.SubMapper
 
-			// This has weird special cases for mirroring
-			if tempGame.Pcb.Mapper == 30 {
-				if !nesRoms[index].Header20.FourScreen {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "H"
-					} else {
-						tempGame.Pcb.Mirroring = "V"
-					}
-				} else {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "1"
-					} else {
-						tempGame.Pcb.Mirroring = "4"
-					}
-				}
-			} else if tempGame.Pcb.Mapper == 218 {
-				if !nesRoms[index].Header20.FourScreen {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "H"
-					} else {
-						tempGame.Pcb.Mirroring = "V"
-					}
-				} else {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "0"
-					} else {
-						tempGame.Pcb.Mirroring = "1"
-					}
-				}
-			} else {
-				if !nesRoms[index].Header20.FourScreen {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "H"
-					} else {
-						tempGame.Pcb.Mirroring = "V"
-					}
-				} else {
-					if !nesRoms[index].Header20.MirroringType {
-						tempGame.Pcb.Mirroring = "4"
-					} else {
-						return "", errors.New("Invalid mirroring type and four screen setting for mapper " + strconv.FormatUint(uint64(tempGame.Pcb.Mapper), 10) + " in ROM: " + nesRoms[index].Name)
-					}
-				}
+			// Some mappers give the mirroring bits their own meanings
+			mirroringCode, isValidMirroring := NESTool.GetNES20DBMirroring(tempGame.Pcb.Mapper, nesRoms[index].Header20.MirroringType, nesRoms[index].Header20.FourScreen)
+			if !isValidMirroring {
+				return "", errors.New("Invalid mirroring type and four screen setting for mapper " + strconv.FormatUint(uint64(tempGame.Pcb.Mapper), 10) + " in ROM: " + nesRoms[index].Name)
 			}
 
-			tempGame.Console.Region = nesRoms[index].Header20.CPUPPUTiming
+			tempGame.Pcb.Mirroring = mirroringCode
+
+			tempGame.Console.Region = NESTool.CPUPPUTiming(nesRoms[index].Header20.CPUPPUTiming)
 
 			if nesRoms[index].Header20.ConsoleType < 3 {
-				tempGame.Console.Type = nesRoms[index].Header20.ConsoleType
+				tempGame.Console.Type = NESTool.ExtendedConsoleType(nesRoms[index].Header20.ConsoleType)
 			} else {
-				tempGame.Console.Type = nesRoms[index].Header20.ExtendedConsoleType
+				tempGame.Console.Type = NESTool.ExtendedConsoleType(nesRoms[index].Header20.ExtendedConsoleType)
 			}
 
-			tempGame.Expansion.Type = nesRoms[index].Header20.DefaultExpansion
+			tempGame.Expansion.Type = NESTool.DefaultExpansion(nesRoms[index].Header20.DefaultExpansion)
 
 			if nesRoms[index].Header20.CHRRAMSize > 0 {
 				tempGame.Chrram.Size = 64 << nesRoms[index].Header20.CHRRAMSize
@@ -257,8 +226,8 @@ func MarshalNES20DBXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, enableOrgan
 
 			tempGame.Miscrom.Number = nesRoms[index].Header20.MiscROMs
 
-			tempGame.Vs.Hardware = nesRoms[index].Header20.VsHardwareType
-			tempGame.Vs.Ppu = nesRoms[index].Header20.VsPPUType
+			tempGame.Vs.Hardware = NESTool.VsHardwareType(nesRoms[index].Header20.VsHardwareType)
+			tempGame.Vs.Ppu = NESTool.VsPPUType(nesRoms[index].Header20.VsPPUType)
 
 			if nesRoms[index].Header20.CHRNVRAMSize > 0 {
 				tempGame.Chrnvram.Size = 64 << nesRoms[index].Header20.CHRNVRAMSize
@@ -283,8 +252,7 @@ func MarshalNES20DBXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, enableOrgan
 		return "", err
 	}
 
-	returnString := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>" + "\n" + string(xmlBytes)
-	return returnString, nil
+	return finishXMLPayload(xmlBytes, true, canonicalXml), nil
 }
 
 // Unmarshal an nes20db XML file to a map of NESROM structs, with their SHA1 checksum as the key
@@ -367,45 +335,10 @@ func UnmarshalNES20DBXMLToROMMap(xmlPayload string, enableOrganization bool) (ma
 		tempRom.Header20.Mapper = xmlStruct.Games[index].Pcb.Mapper
 		tempRom.Header20.SubMapper = xmlStruct.Games[index].Pcb.Submapper
 
-		if tempRom.Header20.Mapper == 30 {
-			if xmlStruct.Games[index].Pcb.Mirroring == "H" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "V" {
-				tempRom.Header20.MirroringType = true
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "1" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = true
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "4" {
-				tempRom.Header20.MirroringType = true
-				tempRom.Header20.FourScreen = true
-			}
-		} else if tempRom.Header20.Mapper == 218 {
-			if xmlStruct.Games[index].Pcb.Mirroring == "H" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "V" {
-				tempRom.Header20.MirroringType = true
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "0" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = true
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "1" {
-				tempRom.Header20.MirroringType = true
-				tempRom.Header20.FourScreen = true
-			}
-		} else {
-			if xmlStruct.Games[index].Pcb.Mirroring == "H" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "V" {
-				tempRom.Header20.MirroringType = true
-				tempRom.Header20.FourScreen = false
-			} else if xmlStruct.Games[index].Pcb.Mirroring == "4" {
-				tempRom.Header20.MirroringType = false
-				tempRom.Header20.FourScreen = true
-			}
+		mirroringType, fourScreen, isValidMirroring := NESTool.GetMirroringFromNES20DB(tempRom.Header20.Mapper, xmlStruct.Games[index].Pcb.Mirroring)
+		if isValidMirroring {
+			tempRom.Header20.MirroringType = mirroringType
+			tempRom.Header20.FourScreen = fourScreen
 		}
 
 		if xmlStruct.Games[index].Pcb.Battery == 1 {
@@ -414,16 +347,16 @@ func UnmarshalNES20DBXMLToROMMap(xmlPayload string, enableOrganization bool) (ma
 			tempRom.Header20.Battery = false
 		}
 
-		tempRom.Header20.CPUPPUTiming = xmlStruct.Games[index].Console.Region
+		tempRom.Header20.CPUPPUTiming = uint8(xmlStruct.Games[index].Console.Region)
 
-		if xmlStruct.Games[index].Console.Type < 4 {
-			tempRom.Header20.ConsoleType = xmlStruct.Games[index].Console.Type
+		if xmlStruct.Games[index].Console.Type < NESTool.EXTENDED_CONSOLE_TYPE_DECIMAL_FAMICLONE {
+			tempRom.Header20.ConsoleType = uint8(xmlStruct.Games[index].Console.Type)
 		} else {
 			tempRom.Header20.ConsoleType = 3
-			tempRom.Header20.ExtendedConsoleType = xmlStruct.Games[index].Console.Type
+			tempRom.Header20.ExtendedConsoleType = uint8(xmlStruct.Games[index].Console.Type)
 		}
 
-		tempRom.Header20.DefaultExpansion = xmlStruct.Games[index].Expansion.Type
+		tempRom.Header20.DefaultExpansion = uint8(xmlStruct.Games[index].Expansion.Type)
 
 		if xmlStruct.Games[index].Chrram.Size > 0 {
 			chrRamShifts := uint8(0)
@@ -485,8 +418,8 @@ func UnmarshalNES20DBXMLToROMMap(xmlPayload string, enableOrganization bool) (ma
 			}
 		}
 
-		tempRom.Header20.VsPPUType = xmlStruct.Games[index].Vs.Ppu
-		tempRom.Header20.VsHardwareType = xmlStruct.Games[index].Vs.Hardware
+		tempRom.Header20.VsPPUType = uint8(xmlStruct.Games[index].Vs.Ppu)
+		tempRom.Header20.VsHardwareType = uint8(xmlStruct.Games[index].Vs.Hardware)
 
 		if xmlStruct.Games[index].Chrnvram.Size > 0 {
 			chrNvramShifts := uint8(0)
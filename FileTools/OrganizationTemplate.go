@@ -0,0 +1,119 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Naming templates for organizing FDS archives.  A template is a
+// relative path with {placeholder} fields, such as
+// "{manufacturer}/{filename}", which groups disks by publisher.
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ORGANIZATION_TEMPLATE_NAME              = "name"
+	ORGANIZATION_TEMPLATE_RELATIVE_PATH     = "relative-path"
+	ORGANIZATION_TEMPLATE_DIRECTORY         = "directory"
+	ORGANIZATION_TEMPLATE_FILENAME          = "filename"
+	ORGANIZATION_TEMPLATE_MANUFACTURER      = "manufacturer"
+	ORGANIZATION_TEMPLATE_MANUFACTURER_CODE = "manufacturer-code"
+)
+
+// Get the relative path an FDS archive is written to with a naming
+// template.  The manufacturer is taken from the first disk side, and
+// the other fields from the archive's relative path, which defaults to
+// its file name, or its name with a .fds extension.  Characters that can't be used in
+// file names are dropped from or replaced in field values.
+func GetFDSOrganizedRelativePath(archive *FDSTool.FDSArchiveFile, organizationTemplate string) (string, error) {
+	relativePath := getXMLSortRelativePath(archive.RelativePath)
+	if relativePath == "" && archive.Filename != "" {
+		relativePath = filepath.Base(archive.Filename)
+	} else if relativePath == "" {
+		relativePath = archive.Name + ".fds"
+	}
+
+	directory := path.Dir(relativePath)
+	if directory == "." {
+		directory = ""
+	}
+
+	manufacturer := "Unknown"
+	manufacturerCode := "None"
+	if len(archive.ArchiveDisks) > 0 && len(archive.ArchiveDisks[0].DiskSides) > 0 {
+		sideManufacturerCode := archive.ArchiveDisks[0].DiskSides[0].ManufacturerCode
+		manufacturerCode = strings.ToUpper(hex.EncodeToString([]byte{sideManufacturerCode}))
+		if FDSTool.GetFDSManufacturerName(sideManufacturerCode) != "" {
+			manufacturer = getOrganizationTemplateValue(FDSTool.GetFDSManufacturerName(sideManufacturerCode))
+		}
+	}
+
+	fieldValues := map[string]string{
+		ORGANIZATION_TEMPLATE_NAME:              getOrganizationTemplateValue(archive.Name),
+		ORGANIZATION_TEMPLATE_RELATIVE_PATH:     relativePath,
+		ORGANIZATION_TEMPLATE_DIRECTORY:         directory,
+		ORGANIZATION_TEMPLATE_FILENAME:          path.Base(relativePath),
+		ORGANIZATION_TEMPLATE_MANUFACTURER:      manufacturer,
+		ORGANIZATION_TEMPLATE_MANUFACTURER_CODE: manufacturerCode,
+	}
+
+	returnString := ""
+	remainingTemplate := organizationTemplate
+	for {
+		fieldStart := strings.Index(remainingTemplate, "{")
+		if fieldStart < 0 {
+			returnString = returnString + remainingTemplate
+			break
+		}
+
+		fieldEnd := strings.Index(remainingTemplate[fieldStart:], "}")
+		if fieldEnd < 0 {
+			return "", errors.New("Unclosed field in organization template: " + organizationTemplate)
+		}
+
+		fieldName := remainingTemplate[fieldStart+1 : fieldStart+fieldEnd]
+		fieldValue, isField := fieldValues[fieldName]
+		if !isField {
+			return "", errors.New("Unknown field in organization template: {" + fieldName + "}")
+		}
+
+		returnString = returnString + remainingTemplate[0:fieldStart] + fieldValue
+		remainingTemplate = remainingTemplate[fieldStart+fieldEnd+1:]
+	}
+
+	// Empty fields can leave doubled or leading separators behind
+	returnString = path.Clean("/" + returnString)[1:]
+	if returnString == "" {
+		return "", errors.New("Organization template gives an empty path for: " + archive.Name)
+	}
+
+	return strings.Replace(returnString, "/", string(os.PathSeparator), -1), nil
+}
+
+// Make a value safe to use as a single path element
+func getOrganizationTemplateValue(fieldValue string) string {
+	fieldValue = strings.NewReplacer("<", "", ">", "", "\"", "", "/", "-", "\\", "-", ":", "-", "*", "-", "?", "-", "|", "-").Replace(fieldValue)
+	return strings.TrimSpace(fieldValue)
+}
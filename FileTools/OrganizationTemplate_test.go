@@ -0,0 +1,78 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"path/filepath"
+	"testing"
+)
+
+func getTestOrganizationArchive(name string, relativePath string, manufacturerCode uint8) *FDSTool.FDSArchiveFile {
+	return &FDSTool.FDSArchiveFile{
+		Name:         name,
+		RelativePath: filepath.FromSlash(relativePath),
+		ArchiveDisks: []*FDSTool.FDSDisk{{DiskSides: []*FDSTool.FDSSide{{ManufacturerCode: manufacturerCode}}}},
+	}
+}
+
+func TestGetFDSOrganizedRelativePath(t *testing.T) {
+	tests := []struct {
+		name                 string
+		archive              *FDSTool.FDSArchiveFile
+		organizationTemplate string
+		expected             string
+		expectError          bool
+	}{
+		{name: "grouped by manufacturer", archive: getTestOrganizationArchive("Zelda", "Adventure/Zelda.fds", 0x01), organizationTemplate: "{manufacturer}/{filename}", expected: "Nintendo/Zelda.fds"},
+		{name: "manufacturer code and directory", archive: getTestOrganizationArchive("Zelda", "Adventure/Zelda.fds", 0x01), organizationTemplate: "{directory}/{manufacturer-code} {name}.fds", expected: "Adventure/01 Zelda.fds"},
+		{name: "whole relative path", archive: getTestOrganizationArchive("Zelda", "Adventure/Zelda.fds", 0xA4), organizationTemplate: "{manufacturer}/{relative-path}", expected: "Konami/Adventure/Zelda.fds"},
+		{name: "manufacturer name with a separator", archive: getTestOrganizationArchive("Game", "Game.fds", 0x96), organizationTemplate: "{manufacturer}/{filename}", expected: "Yonezawa-S'Pal/Game.fds"},
+		{name: "unlicensed", archive: getTestOrganizationArchive("Game", "Game.fds", 0x00), organizationTemplate: "{manufacturer}/{filename}", expected: "unlicensed/Game.fds"},
+		{name: "unknown manufacturer", archive: getTestOrganizationArchive("Game", "Game.fds", 0xFE), organizationTemplate: "{manufacturer}/{filename}", expected: "Unknown/Game.fds"},
+		{name: "no disk sides", archive: &FDSTool.FDSArchiveFile{Name: "Game"}, organizationTemplate: "{manufacturer-code}/{filename}", expected: "None/Game.fds"},
+		{name: "empty directory", archive: getTestOrganizationArchive("Game", "Game.fds", 0x01), organizationTemplate: "{directory}/{filename}", expected: "Game.fds"},
+		{name: "path leaving the output directory", archive: getTestOrganizationArchive("Game", "Game.fds", 0x01), organizationTemplate: "../{filename}", expected: "Game.fds"},
+		{name: "unknown field", archive: getTestOrganizationArchive("Game", "Game.fds", 0x01), organizationTemplate: "{publisher}/{filename}", expectError: true},
+		{name: "unclosed field", archive: getTestOrganizationArchive("Game", "Game.fds", 0x01), organizationTemplate: "{manufacturer/{filename}", expectError: true},
+		{name: "empty path", archive: getTestOrganizationArchive("Game", "Game.fds", 0x01), organizationTemplate: "{directory}", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			relativePath, err := GetFDSOrganizedRelativePath(test.archive, test.organizationTemplate)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", relativePath)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if relativePath != filepath.FromSlash(test.expected) {
+				t.Fatalf("expected %s, got %s", filepath.FromSlash(test.expected), relativePath)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Structured versions of the rominfo report, for scripts that would
+// otherwise have to parse the text output.  NESROMInfo has everything
+// that's decoded from a ROM, and NESROMInfoRow is a flat summary for
+// tables of ROMs.
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"NES20Tool/UNIFTool"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ROM_INFO_CSV_COLUMNS = []string{
+	"path",
+	"headerVersion",
+	"mapper",
+	"subMapper",
+	"prgRomSize",
+	"chrRomSize",
+	"miscRomSize",
+	"prgRamSize",
+	"prgNvramSize",
+	"chrRamSize",
+	"chrNvramSize",
+	"mirroring",
+	"battery",
+	"cpuPpuTiming",
+	"consoleType",
+	"crc32",
+	"sha1",
+	"sha256",
+	"prgRomCrc32",
+	"prgRomSha1",
+	"chrRomCrc32",
+	"chrRomSha1",
+}
+
+type NESROMInfo struct {
+	Name                string                    `json:"name,omitempty"`
+	Filename            string                    `json:"filename,omitempty"`
+	RelativePath        string                    `json:"relativePath,omitempty"`
+	HeaderVersion       string                    `json:"headerVersion"`
+	HeaderType          string                    `json:"headerType,omitempty"`
+	DiskDude            bool                      `json:"diskDude,omitempty"`
+	Size                uint64                    `json:"size"`
+	Crc32               string                    `json:"crc32"`
+	Md5                 string                    `json:"md5"`
+	Sha1                string                    `json:"sha1"`
+	Sha256              string                    `json:"sha256"`
+	PrgRom              *ROMSectionInfo           `json:"prgRom"`
+	ChrRom              *ROMSectionInfo           `json:"chrRom,omitempty"`
+	MiscRom             *ROMSectionInfo           `json:"miscRom,omitempty"`
+	Trainer             *ROMSectionInfo           `json:"trainer,omitempty"`
+	MiscRoms            uint8                     `json:"miscRoms"`
+	PrgRamSize          uint64                    `json:"prgRamSize"`
+	PrgNvramSize        uint64                    `json:"prgNvramSize"`
+	ChrRamSize          uint64                    `json:"chrRamSize"`
+	ChrNvramSize        uint64                    `json:"chrNvramSize"`
+	MirroringType       bool                      `json:"mirroringType"`
+	FourScreen          bool                      `json:"fourScreen"`
+	Mirroring           string                    `json:"mirroring"`
+	Battery             bool                      `json:"battery"`
+	Mapper              uint16                    `json:"mapper"`
+	MapperName          string                    `json:"mapperName,omitempty"`
+	SubMapper           uint8                     `json:"subMapper"`
+	SubMapperName       string                    `json:"subMapperName,omitempty"`
+	ConsoleType         string                    `json:"consoleType"`
+	ExtendedConsoleType string                    `json:"extendedConsoleType,omitempty"`
+	VsPpuType           string                    `json:"vsPpuType,omitempty"`
+	VsHardwareType      string                    `json:"vsHardwareType,omitempty"`
+	CpuPpuTiming        string                    `json:"cpuPpuTiming"`
+	DefaultExpansion    string                    `json:"defaultExpansion,omitempty"`
+	ExistingHeader      string                    `json:"existingHeader,omitempty"`
+	CalculatedHeader    string                    `json:"calculatedHeader,omitempty"`
+	UNIF                *UNIFInfo                 `json:"unif,omitempty"`
+	UNIFDumper          *UNIFDumperInfoJSONFields `json:"unifDumper,omitempty"`
+}
+
+type ROMSectionInfo struct {
+	Size   uint64 `json:"size"`
+	Sum16  string `json:"sum16"`
+	Crc32  string `json:"crc32"`
+	Md5    string `json:"md5"`
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
+}
+
+type UNIFInfo struct {
+	Version uint32           `json:"version"`
+	Chunks  []*UNIFChunkInfo `json:"chunks"`
+}
+
+type UNIFChunkInfo struct {
+	ID    string `json:"id"`
+	Size  uint64 `json:"size"`
+	Crc32 string `json:"crc32"`
+	Value string `json:"value,omitempty"`
+}
+
+type UNIFDumperInfoJSONFields struct {
+	DumperName   string `json:"dumperName"`
+	DumpDate     string `json:"dumpDate,omitempty"`
+	DumpingAgent string `json:"dumpingAgent"`
+}
+
+type NESROMInfoRow struct {
+	Path          string `json:"path"`
+	HeaderVersion string `json:"headerVersion"`
+	Mapper        uint16 `json:"mapper"`
+	SubMapper     uint8  `json:"subMapper"`
+	PrgRomSize    uint64 `json:"prgRomSize"`
+	ChrRomSize    uint64 `json:"chrRomSize"`
+	MiscRomSize   uint64 `json:"miscRomSize"`
+	PrgRamSize    uint64 `json:"prgRamSize"`
+	PrgNvramSize  uint64 `json:"prgNvramSize"`
+	ChrRamSize    uint64 `json:"chrRamSize"`
+	ChrNvramSize  uint64 `json:"chrNvramSize"`
+	Mirroring     string `json:"mirroring"`
+	Battery       bool   `json:"battery"`
+	CpuPpuTiming  string `json:"cpuPpuTiming"`
+	ConsoleType   string `json:"consoleType"`
+	Crc32         string `json:"crc32"`
+	Sha1          string `json:"sha1"`
+	Sha256        string `json:"sha256"`
+	PrgRomCrc32   string `json:"prgRomCrc32"`
+	PrgRomSha1    string `json:"prgRomSha1"`
+	ChrRomCrc32   string `json:"chrRomCrc32"`
+	ChrRomSha1    string `json:"chrRomSha1"`
+}
+
+// Get everything decoded from a ROM.  Sizes are in bytes, and
+// enumerated values use the same names editheaderfield accepts.
+func GetNESROMInfo(rom *NESTool.NESROM) (*NESROMInfo, error) {
+	if rom == nil || (rom.Header20 == nil && rom.Header10 == nil) {
+		return nil, errors.New("ROM has no decoded header.")
+	}
+
+	info := &NESROMInfo{}
+	info.Name = rom.Name
+	info.Filename = rom.Filename
+	info.RelativePath = rom.RelativePath
+	info.Size = rom.Size
+	info.Crc32 = getROMInfoCRC32String(rom.CRC32)
+	info.Md5 = strings.ToUpper(hex.EncodeToString(rom.MD5[:]))
+	info.Sha1 = strings.ToUpper(hex.EncodeToString(rom.SHA1[:]))
+	info.Sha256 = strings.ToUpper(hex.EncodeToString(rom.SHA256[:]))
+
+	if rom.HeaderType != NESTool.NES_HEADER_TYPE_UNKNOWN {
+		info.HeaderType = NESTool.GetNESHeaderTypeString(rom.HeaderType)
+		info.DiskDude = NESTool.IsDiskDudeHeader(rom.HeaderData)
+	}
+
+	if rom.HeaderData != nil {
+		info.ExistingHeader = strings.ToUpper(hex.EncodeToString(rom.HeaderData))
+	}
+
+	calculatedHeaderBytes, err := NESTool.EncodeNESROMHeader(rom, true, true)
+	if err == nil {
+		info.CalculatedHeader = strings.ToUpper(hex.EncodeToString(calculatedHeaderBytes))
+	}
+
+	if rom.Header20 != nil {
+		header20 := rom.Header20
+		info.HeaderVersion = "NES 2.0"
+		info.PrgRom = &ROMSectionInfo{Size: header20.PRGROMCalculatedSize, Sum16: getROMInfoSum16String(header20.PRGROMSum16), Crc32: getROMInfoCRC32String(header20.PRGROMCRC32), Md5: strings.ToUpper(hex.EncodeToString(header20.PRGROMMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header20.PRGROMSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header20.PRGROMSHA256[:]))}
+
+		if header20.CHRROMCalculatedSize > 0 {
+			info.ChrRom = &ROMSectionInfo{Size: header20.CHRROMCalculatedSize, Sum16: getROMInfoSum16String(header20.CHRROMSum16), Crc32: getROMInfoCRC32String(header20.CHRROMCRC32), Md5: strings.ToUpper(hex.EncodeToString(header20.CHRROMMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header20.CHRROMSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header20.CHRROMSHA256[:]))}
+		}
+
+		if header20.MiscROMCalculatedSize > 0 {
+			info.MiscRom = &ROMSectionInfo{Size: header20.MiscROMCalculatedSize, Sum16: getROMInfoSum16String(header20.MiscROMSum16), Crc32: getROMInfoCRC32String(header20.MiscROMCRC32), Md5: strings.ToUpper(hex.EncodeToString(header20.MiscROMMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header20.MiscROMSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header20.MiscROMSHA256[:]))}
+		}
+
+		if header20.Trainer && header20.TrainerCalculatedSize > 0 {
+			info.Trainer = &ROMSectionInfo{Size: uint64(header20.TrainerCalculatedSize), Sum16: getROMInfoSum16String(header20.TrainerSum16), Crc32: getROMInfoCRC32String(header20.TrainerCRC32), Md5: strings.ToUpper(hex.EncodeToString(header20.TrainerMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header20.TrainerSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header20.TrainerSHA256[:]))}
+		}
+
+		info.MiscRoms = header20.MiscROMs
+		info.PrgRamSize = getROMInfoRAMSize(header20.PRGRAMSize)
+		info.PrgNvramSize = getROMInfoRAMSize(header20.PRGNVRAMSize)
+		info.ChrRamSize = getROMInfoRAMSize(header20.CHRRAMSize)
+		info.ChrNvramSize = getROMInfoRAMSize(header20.CHRNVRAMSize)
+		info.MirroringType = header20.MirroringType
+		info.FourScreen = header20.FourScreen
+		info.Battery = header20.Battery
+		info.Mapper = header20.Mapper
+		info.SubMapper = header20.SubMapper
+		info.ConsoleType = NESTool.ConsoleType(header20.ConsoleType).Name()
+		info.CpuPpuTiming = NESTool.CPUPPUTiming(header20.CPUPPUTiming).Name()
+		info.DefaultExpansion = NESTool.DefaultExpansion(header20.DefaultExpansion).Name()
+
+		if header20.ConsoleType == uint8(NESTool.CONSOLE_TYPE_VS_SYSTEM) {
+			info.VsPpuType = NESTool.VsPPUType(header20.VsPPUType).Name()
+			info.VsHardwareType = NESTool.VsHardwareType(header20.VsHardwareType).Name()
+		} else if header20.ConsoleType == uint8(NESTool.CONSOLE_TYPE_EXTENDED) {
+			info.ExtendedConsoleType = NESTool.ExtendedConsoleType(header20.ExtendedConsoleType).Name()
+		}
+	} else {
+		header10 := rom.Header10
+		info.HeaderVersion = "iNES"
+		info.PrgRom = &ROMSectionInfo{Size: header10.PRGROMCalculatedSize, Sum16: getROMInfoSum16String(header10.PRGROMSum16), Crc32: getROMInfoCRC32String(header10.PRGROMCRC32), Md5: strings.ToUpper(hex.EncodeToString(header10.PRGROMMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header10.PRGROMSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header10.PRGROMSHA256[:]))}
+
+		if header10.CHRROMCalculatedSize > 0 {
+			info.ChrRom = &ROMSectionInfo{Size: header10.CHRROMCalculatedSize, Sum16: getROMInfoSum16String(header10.CHRROMSum16), Crc32: getROMInfoCRC32String(header10.CHRROMCRC32), Md5: strings.ToUpper(hex.EncodeToString(header10.CHRROMMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header10.CHRROMSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header10.CHRROMSHA256[:]))}
+		} else {
+			// iNES always means 8 KiB of CHR RAM when there's no CHR ROM
+			info.ChrRamSize = 8192
+		}
+
+		if header10.Trainer && header10.TrainerCalculatedSize > 0 {
+			info.Trainer = &ROMSectionInfo{Size: uint64(header10.TrainerCalculatedSize), Sum16: getROMInfoSum16String(header10.TrainerSum16), Crc32: getROMInfoCRC32String(header10.TrainerCRC32), Md5: strings.ToUpper(hex.EncodeToString(header10.TrainerMD5[:])), Sha1: strings.ToUpper(hex.EncodeToString(header10.TrainerSHA1[:])), Sha256: strings.ToUpper(hex.EncodeToString(header10.TrainerSHA256[:]))}
+		}
+
+		// A PRG RAM size of 0 means 8 KiB, for compatibility with older iNES headers
+		prgRamSize := 8192 * uint64(header10.PRGRAMSize)
+		if prgRamSize == 0 {
+			prgRamSize = 8192
+		}
+
+		if header10.Battery {
+			info.PrgNvramSize = prgRamSize
+		} else {
+			info.PrgRamSize = prgRamSize
+		}
+
+		info.MirroringType = header10.MirroringType
+		info.FourScreen = header10.FourScreen
+		info.Battery = header10.Battery
+		info.Mapper = uint16(header10.Mapper)
+
+		if header10.VsUnisystem {
+			info.ConsoleType = NESTool.CONSOLE_TYPE_VS_SYSTEM.Name()
+		} else if header10.PlayChoice10 {
+			info.ConsoleType = NESTool.CONSOLE_TYPE_PLAYCHOICE10.Name()
+		} else {
+			info.ConsoleType = NESTool.CONSOLE_TYPE_NES.Name()
+		}
+
+		if header10.TVSystem {
+			info.CpuPpuTiming = NESTool.CPU_PPU_TIMING_PAL.Name()
+		} else {
+			info.CpuPpuTiming = NESTool.CPU_PPU_TIMING_NTSC.Name()
+		}
+	}
+
+	info.Mirroring = getROMInfoMirroring(info.Mapper, info.MirroringType, info.FourScreen)
+	if NESTool.GetMapperInfo(info.Mapper) != nil {
+		info.MapperName = NESTool.GetMapperName(info.Mapper)
+	}
+	info.SubMapperName = NESTool.GetSubMapperName(info.Mapper, info.SubMapper)
+
+	if rom.UNIFChunks != nil {
+		info.UNIF = &UNIFInfo{Version: rom.UNIFVersion, Chunks: make([]*UNIFChunkInfo, 0)}
+		for index := range rom.UNIFChunks {
+			chunkInfo := &UNIFChunkInfo{ID: rom.UNIFChunks[index].ID, Size: rom.UNIFChunks[index].Size, Crc32: getROMInfoCRC32String(rom.UNIFChunks[index].CRC32)}
+			if !UNIFTool.IsUNIFROMChunk(rom.UNIFChunks[index].ID) {
+				chunkInfo.Value = UNIFTool.GetUNIFChunkValueString(rom.UNIFChunks[index])
+			}
+
+			info.UNIF.Chunks = append(info.UNIF.Chunks, chunkInfo)
+		}
+	}
+
+	if rom.UNIFDumper != nil {
+		info.UNIFDumper = &UNIFDumperInfoJSONFields{DumperName: rom.UNIFDumper.DumperName, DumpDate: rom.UNIFDumper.GetDumpDateString(), DumpingAgent: rom.UNIFDumper.DumpingAgent}
+	}
+
+	return info, nil
+}
+
+// Get the flat summary of a ROM used for JSON lines and CSV output
+func GetNESROMInfoRow(info *NESROMInfo) *NESROMInfoRow {
+	row := &NESROMInfoRow{}
+
+	row.Path = info.RelativePath
+	if row.Path == "" {
+		row.Path = info.Filename
+	}
+
+	row.HeaderVersion = info.HeaderVersion
+	row.Mapper = info.Mapper
+	row.SubMapper = info.SubMapper
+	row.PrgRamSize = info.PrgRamSize
+	row.PrgNvramSize = info.PrgNvramSize
+	row.ChrRamSize = info.ChrRamSize
+	row.ChrNvramSize = info.ChrNvramSize
+	row.Mirroring = info.Mirroring
+	row.Battery = info.Battery
+	row.CpuPpuTiming = info.CpuPpuTiming
+	row.ConsoleType = info.ConsoleType
+	row.Crc32 = info.Crc32
+	row.Sha1 = info.Sha1
+	row.Sha256 = info.Sha256
+
+	if info.PrgRom != nil {
+		row.PrgRomSize = info.PrgRom.Size
+		row.PrgRomCrc32 = info.PrgRom.Crc32
+		row.PrgRomSha1 = info.PrgRom.Sha1
+	}
+
+	if info.ChrRom != nil {
+		row.ChrRomSize = info.ChrRom.Size
+		row.ChrRomCrc32 = info.ChrRom.Crc32
+		row.ChrRomSha1 = info.ChrRom.Sha1
+	}
+
+	if info.MiscRom != nil {
+		row.MiscRomSize = info.MiscRom.Size
+	}
+
+	return row
+}
+
+// Get a row's values in the order of ROM_INFO_CSV_COLUMNS
+func (row *NESROMInfoRow) CSVRecord() []string {
+	return []string{
+		row.Path,
+		row.HeaderVersion,
+		strconv.FormatUint(uint64(row.Mapper), 10),
+		strconv.FormatUint(uint64(row.SubMapper), 10),
+		strconv.FormatUint(row.PrgRomSize, 10),
+		strconv.FormatUint(row.ChrRomSize, 10),
+		strconv.FormatUint(row.MiscRomSize, 10),
+		strconv.FormatUint(row.PrgRamSize, 10),
+		strconv.FormatUint(row.PrgNvramSize, 10),
+		strconv.FormatUint(row.ChrRamSize, 10),
+		strconv.FormatUint(row.ChrNvramSize, 10),
+		row.Mirroring,
+		strconv.FormatBool(row.Battery),
+		row.CpuPpuTiming,
+		row.ConsoleType,
+		row.Crc32,
+		row.Sha1,
+		row.Sha256,
+		row.PrgRomCrc32,
+		row.PrgRomSha1,
+		row.ChrRomCrc32,
+		row.ChrRomSha1,
+	}
+}
+
+func MarshalNESROMInfo(info *NESROMInfo) (string, error) {
+	jsonBytes, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// Rows are written one per line, for JSON lines output
+func MarshalNESROMInfoRow(row *NESROMInfoRow) (string, error) {
+	jsonBytes, err := json.Marshal(row)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+func getROMInfoMirroring(mapper uint16, mirroringType bool, fourScreen bool) string {
+	mirroringCode, isValid := NESTool.GetNES20DBMirroring(mapper, mirroringType, fourScreen)
+	if !isValid {
+		return "invalid"
+	}
+
+	switch mirroringCode {
+	case NESTool.MAPPER_MIRRORING_HORIZONTAL:
+		return "horizontal"
+	case NESTool.MAPPER_MIRRORING_VERTICAL:
+		return "vertical"
+	case NESTool.MAPPER_MIRRORING_FOUR_SCREEN:
+		return "four-screen"
+	default:
+		return "one-screen"
+	}
+}
+
+func getROMInfoRAMSize(shiftCount uint8) uint64 {
+	if shiftCount == 0 {
+		return 0
+	}
+
+	return 64 << shiftCount
+}
+
+func getROMInfoCRC32String(crc32Value uint32) string {
+	crc32Bytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32Bytes, crc32Value)
+	return strings.ToUpper(hex.EncodeToString(crc32Bytes))
+}
+
+func getROMInfoSum16String(sum16Value uint16) string {
+	sum16Bytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(sum16Bytes, sum16Value)
+	return strings.ToUpper(hex.EncodeToString(sum16Bytes))
+}
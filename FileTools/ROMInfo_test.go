@@ -0,0 +1,62 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"testing"
+)
+
+func TestGetNESROMInfoINESPRGRAM(t *testing.T) {
+	tests := []struct {
+		name                 string
+		headerByte6          byte
+		headerByte8          byte
+		expectedPrgRamSize   uint64
+		expectedPrgNvramSize uint64
+	}{
+		{name: "unspecified PRG RAM", expectedPrgRamSize: 8192},
+		{name: "unspecified PRG NVRAM", headerByte6: 0b00000010, expectedPrgNvramSize: 8192},
+		{name: "8 KiB of PRG RAM", headerByte8: 1, expectedPrgRamSize: 8192},
+		{name: "16 KiB of PRG RAM", headerByte8: 2, expectedPrgRamSize: 16384},
+		{name: "32 KiB of PRG NVRAM", headerByte6: 0b00000010, headerByte8: 4, expectedPrgNvramSize: 32768},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileData := []byte{'N', 'E', 'S', 0x1a, 1, 1, test.headerByte6, 0, test.headerByte8, 0, 0, 0, 0, 0, 0, 0}
+			fileData = append(fileData, make([]byte, 16384+8192)...)
+
+			rom, err := NESTool.DecodeNESROM(fileData, true, false, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			info, err := GetNESROMInfo(rom)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if info.PrgRamSize != test.expectedPrgRamSize || info.PrgNvramSize != test.expectedPrgNvramSize {
+				t.Fatalf("expected PRG RAM %d and PRG NVRAM %d, got %d and %d", test.expectedPrgRamSize, test.expectedPrgNvramSize, info.PrgRamSize, info.PrgNvramSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This is a file for splitting NES 2.0 ROMs into their sections, with
+// a JSON manifest describing the header, and for assembling ROMs from
+// those sections again.
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var (
+	SPLIT_PRG_ROM_FILE  = "prgrom.bin"
+	SPLIT_CHR_ROM_FILE  = "chrrom.bin"
+	SPLIT_MISC_ROM_FILE = "miscrom.bin"
+	SPLIT_TRAINER_FILE  = "trainer.bin"
+)
+
+type NESROMManifest struct {
+	PrgRomFile          string `json:"prgRomFile"`
+	ChrRomFile          string `json:"chrRomFile,omitempty"`
+	MiscRomFile         string `json:"miscRomFile,omitempty"`
+	TrainerFile         string `json:"trainerFile,omitempty"`
+	PrgRomSize          uint64 `json:"prgRomSize"`
+	ChrRomSize          uint64 `json:"chrRomSize"`
+	MiscRomSize         uint64 `json:"miscRomSize"`
+	PrgRam              uint8  `json:"prgRam"`
+	PrgNvram            uint8  `json:"prgNvram"`
+	ChrRam              uint8  `json:"chrRam"`
+	ChrNvram            uint8  `json:"chrNvram"`
+	MirroringType       bool   `json:"mirroringType"`
+	Battery             bool   `json:"battery"`
+	Trainer             bool   `json:"trainer"`
+	FourScreen          bool   `json:"fourScreen"`
+	ConsoleType         uint8  `json:"consoleType"`
+	Mapper              uint16 `json:"mapper"`
+	SubMapper           uint8  `json:"subMapper"`
+	CpuPpuTiming        uint8  `json:"cpuPpuTiming"`
+	VsHardwareType      uint8  `json:"vsHardwareType"`
+	VsPpuType           uint8  `json:"vsPpuType"`
+	ExtendedConsoleType uint8  `json:"extendedConsoleType"`
+	MiscRoms            uint8  `json:"miscRoms"`
+	DefaultExpansion    uint8  `json:"defaultExpansion"`
+}
+
+// Write the PRG, CHR, misc and trainer sections of an NES 2.0 ROM to
+// separate files next to a JSON manifest of its header
+func SplitROM(romModel *NESTool.NESROM, manifestPath string) error {
+	if romModel.Header20 == nil {
+		return errors.New("Only ROMs with NES 2.0 headers can be split.")
+	}
+
+	manifest := &NESROMManifest{}
+	manifest.PrgRomSize = uint64(len(romModel.PRGROMData))
+	manifest.ChrRomSize = uint64(len(romModel.CHRROMData))
+	manifest.MiscRomSize = uint64(len(romModel.MiscROMData))
+	manifest.PrgRam = romModel.Header20.PRGRAMSize
+	manifest.PrgNvram = romModel.Header20.PRGNVRAMSize
+	manifest.ChrRam = romModel.Header20.CHRRAMSize
+	manifest.ChrNvram = romModel.Header20.CHRNVRAMSize
+	manifest.MirroringType = romModel.Header20.MirroringType
+	manifest.Battery = romModel.Header20.Battery
+	manifest.FourScreen = romModel.Header20.FourScreen
+	manifest.ConsoleType = romModel.Header20.ConsoleType
+	manifest.Mapper = romModel.Header20.Mapper
+	manifest.SubMapper = romModel.Header20.SubMapper
+	manifest.CpuPpuTiming = romModel.Header20.CPUPPUTiming
+	manifest.VsHardwareType = romModel.Header20.VsHardwareType
+	manifest.VsPpuType = romModel.Header20.VsPPUType
+	manifest.ExtendedConsoleType = romModel.Header20.ExtendedConsoleType
+	manifest.MiscRoms = romModel.Header20.MiscROMs
+	manifest.DefaultExpansion = romModel.Header20.DefaultExpansion
+
+	splitDirectory := filepath.Dir(manifestPath)
+
+	manifest.PrgRomFile = SPLIT_PRG_ROM_FILE
+	err := WriteBytesToFile(romModel.PRGROMData, filepath.Join(splitDirectory, manifest.PrgRomFile))
+	if err != nil {
+		return err
+	}
+
+	if len(romModel.CHRROMData) > 0 {
+		manifest.ChrRomFile = SPLIT_CHR_ROM_FILE
+		err = WriteBytesToFile(romModel.CHRROMData, filepath.Join(splitDirectory, manifest.ChrRomFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(romModel.MiscROMData) > 0 {
+		manifest.MiscRomFile = SPLIT_MISC_ROM_FILE
+		err = WriteBytesToFile(romModel.MiscROMData, filepath.Join(splitDirectory, manifest.MiscRomFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	if romModel.Header20.Trainer && len(romModel.TrainerData) == 512 {
+		manifest.Trainer = true
+		manifest.TrainerFile = SPLIT_TRAINER_FILE
+		err = WriteBytesToFile(romModel.TrainerData, filepath.Join(splitDirectory, manifest.TrainerFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteBytesToFile(manifestBytes, manifestPath)
+}
+
+// Build an NES 2.0 ROM from a JSON header manifest and the section
+// files it names.  File names are relative to the manifest.  ROM sizes
+// come from the section files, so the size fields in the manifest are
+// only checked, not trusted.
+func AssembleROM(manifestPath string) (*NESTool.NESROM, error) {
+	manifestBytes, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &NESROMManifest{}
+	err = json.Unmarshal(manifestBytes, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.PrgRomFile == "" {
+		return nil, errors.New("Manifest doesn't name a PRG ROM file.")
+	}
+
+	// The header can't claim sections the ROM won't have
+	if manifest.Trainer && manifest.TrainerFile == "" {
+		return nil, errors.New("Manifest sets the trainer flag but doesn't name a trainer file.")
+	}
+
+	splitDirectory := filepath.Dir(manifestPath)
+	romModel := &NESTool.NESROM{}
+
+	romModel.PRGROMData, err = loadManifestSection(splitDirectory, manifest.PrgRomFile, manifest.PrgRomSize)
+	if err != nil {
+		return nil, err
+	}
+
+	romModel.CHRROMData, err = loadManifestSection(splitDirectory, manifest.ChrRomFile, manifest.ChrRomSize)
+	if err != nil {
+		return nil, err
+	}
+
+	romModel.MiscROMData, err = loadManifestSection(splitDirectory, manifest.MiscRomFile, manifest.MiscRomSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.MiscRoms > 0 && len(romModel.MiscROMData) == 0 {
+		return nil, errors.New("Manifest sets miscRoms but has no misc ROM data.")
+	}
+
+	if manifest.TrainerFile != "" {
+		romModel.TrainerData, err = loadManifestSection(splitDirectory, manifest.TrainerFile, 512)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	romModel.ROMData = make([]byte, 0)
+	romModel.ROMData = append(romModel.ROMData, romModel.PRGROMData...)
+	romModel.ROMData = append(romModel.ROMData, romModel.CHRROMData...)
+	romModel.ROMData = append(romModel.ROMData, romModel.MiscROMData...)
+
+	romModel.Header20 = &NESTool.NES20Header{}
+	romModel.Header20.PRGRAMSize = manifest.PrgRam
+	romModel.Header20.PRGNVRAMSize = manifest.PrgNvram
+	romModel.Header20.CHRRAMSize = manifest.ChrRam
+	romModel.Header20.CHRNVRAMSize = manifest.ChrNvram
+	romModel.Header20.MirroringType = manifest.MirroringType
+	romModel.Header20.Battery = manifest.Battery
+	romModel.Header20.Trainer = manifest.TrainerFile != ""
+	romModel.Header20.FourScreen = manifest.FourScreen
+	romModel.Header20.ConsoleType = manifest.ConsoleType
+	romModel.Header20.Mapper = manifest.Mapper
+	romModel.Header20.SubMapper = manifest.SubMapper
+	romModel.Header20.CPUPPUTiming = manifest.CpuPpuTiming
+	romModel.Header20.VsHardwareType = manifest.VsHardwareType
+	romModel.Header20.VsPPUType = manifest.VsPpuType
+	romModel.Header20.ExtendedConsoleType = manifest.ExtendedConsoleType
+	romModel.Header20.MiscROMs = manifest.MiscRoms
+	romModel.Header20.DefaultExpansion = manifest.DefaultExpansion
+
+	if len(romModel.MiscROMData) > 0 && romModel.Header20.MiscROMs == 0 {
+		romModel.Header20.MiscROMs = 1
+	}
+
+	err = NESTool.UpdateSizes(romModel, NESTool.PRG_CANONICAL_SIZE_ROM, NESTool.CHR_CANONICAL_SIZE_ROM)
+	if err != nil {
+		return nil, err
+	}
+
+	err = NESTool.UpdateChecksums(romModel)
+	if err != nil {
+		return nil, err
+	}
+
+	return romModel, nil
+}
+
+// Load a section named in a manifest.  A size of 0 skips the check.
+func loadManifestSection(splitDirectory string, sectionFile string, expectedSize uint64) ([]byte, error) {
+	if sectionFile == "" {
+		return make([]byte, 0), nil
+	}
+
+	sectionPath := sectionFile
+	if !filepath.IsAbs(sectionPath) {
+		sectionPath = filepath.Join(splitDirectory, sectionFile)
+	}
+
+	sectionData, err := ioutil.ReadFile(sectionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedSize > 0 && uint64(len(sectionData)) != expectedSize {
+		return nil, errors.New("Size of " + sectionPath + " doesn't match the manifest.")
+	}
+
+	return sectionData, nil
+}
@@ -0,0 +1,85 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssembleROM(t *testing.T) {
+	tests := []struct {
+		name            string
+		manifest        string
+		expectError     bool
+		expectedTrainer bool
+		expectedSize    int
+	}{
+		{name: "PRG ROM only", manifest: `{"prgRomFile": "prgrom.bin"}`, expectedSize: 16384},
+		{name: "trainer file", manifest: `{"prgRomFile": "prgrom.bin", "trainerFile": "trainer.bin"}`, expectedTrainer: true, expectedSize: 16384},
+		{name: "trainer file without the flag", manifest: `{"prgRomFile": "prgrom.bin", "trainer": false, "trainerFile": "trainer.bin"}`, expectedTrainer: true, expectedSize: 16384},
+		{name: "misc ROM file", manifest: `{"prgRomFile": "prgrom.bin", "miscRomFile": "miscrom.bin", "miscRoms": 1}`, expectedSize: 16384 + 16},
+		{name: "trainer flag without a trainer file", manifest: `{"prgRomFile": "prgrom.bin", "trainer": true}`, expectError: true},
+		{name: "misc ROMs without a misc ROM file", manifest: `{"prgRomFile": "prgrom.bin", "miscRoms": 1}`, expectError: true},
+		{name: "no PRG ROM file", manifest: `{"chrRomFile": "chrrom.bin"}`, expectError: true},
+		{name: "PRG ROM size mismatch", manifest: `{"prgRomFile": "prgrom.bin", "prgRomSize": 32768}`, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			splitDirectory := t.TempDir()
+			sections := map[string]int{SPLIT_PRG_ROM_FILE: 16384, SPLIT_MISC_ROM_FILE: 16, SPLIT_TRAINER_FILE: 512}
+			for sectionFile, sectionSize := range sections {
+				err := ioutil.WriteFile(filepath.Join(splitDirectory, sectionFile), make([]byte, sectionSize), 0644)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			manifestPath := filepath.Join(splitDirectory, "manifest.json")
+			err := ioutil.WriteFile(manifestPath, []byte(test.manifest), 0644)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			romModel, err := AssembleROM(manifestPath)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if romModel.Header20.Trainer != test.expectedTrainer {
+				t.Fatalf("expected trainer %t, got %t", test.expectedTrainer, romModel.Header20.Trainer)
+			}
+
+			if len(romModel.ROMData) != test.expectedSize {
+				t.Fatalf("expected %d bytes of ROM data, got %d", test.expectedSize, len(romModel.ROMData))
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ package FileTools
 import (
 	"NES20Tool/FDSTool"
 	"NES20Tool/NESTool"
+	"NES20Tool/UNIFTool"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/xml"
@@ -50,6 +51,7 @@ type NESXMLROM struct {
 	Header20   *NES20XMLFields `xml:"nes20"`
 	Header10   *NES10XMLFields `xml:"ines"`
 	FDSArchive *FDSXMLFields   `xml:"fds"`
+	UNIF       *UNIFXMLFields  `xml:"unif"`
 }
 
 type NES20XMLFields struct {
@@ -114,8 +116,8 @@ type NES20XMLFields struct {
 		Value bool   `xml:"value,attr"`
 	} `xml:"fourScreen"`
 	ConsoleType struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string              `xml:",chardata"`
+		Value NESTool.ConsoleType `xml:"value,attr"`
 	} `xml:"consoleType"`
 	Mapper struct {
 		Text  string `xml:",chardata"`
@@ -126,20 +128,20 @@ type NES20XMLFields struct {
 		Value uint8  `xml:"value,attr"`
 	} `xml:"subMapper"`
 	CpuPpuTiming struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string               `xml:",chardata"`
+		Value NESTool.CPUPPUTiming `xml:"value,attr"`
 	} `xml:"cpuPpuTiming"`
 	VsHardwareType struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string                 `xml:",chardata"`
+		Value NESTool.VsHardwareType `xml:"value,attr"`
 	} `xml:"vsHardwareType"`
 	VsPpuType struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string            `xml:",chardata"`
+		Value NESTool.VsPPUType `xml:"value,attr"`
 	} `xml:"vsPpuType"`
 	ExtendedConsoleType struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string                      `xml:",chardata"`
+		Value NESTool.ExtendedConsoleType `xml:"value,attr"`
 	} `xml:"extendedConsoleType"`
 	MiscRoms struct {
 		Text   string `xml:",chardata"`
@@ -152,8 +154,8 @@ type NES20XMLFields struct {
 		Sha256 string `xml:"sha256,attr"`
 	} `xml:"miscRoms"`
 	DefaultExpansion struct {
-		Text  string `xml:",chardata"`
-		Value uint8  `xml:"value,attr"`
+		Text  string                   `xml:",chardata"`
+		Value NESTool.DefaultExpansion `xml:"value,attr"`
 	} `xml:"defaultExpansion"`
 }
 
@@ -220,9 +222,39 @@ type NES10XMLFields struct {
 	} `xml:"tvSystem"`
 }
 
+type UNIFXMLFields struct {
+	Text       string                   `xml:",chardata"`
+	Version    uint32                   `xml:"version,attr"`
+	TvSystem   string                   `xml:"tvSystem,attr,omitempty"`
+	DumperInfo *UNIFDumperInfoXMLFields `xml:"dumperInfo"`
+	Chunks     []*UNIFChunkXMLFields    `xml:"chunk"`
+}
+
+type UNIFDumperInfoXMLFields struct {
+	Text         string `xml:",chardata"`
+	DumperName   string `xml:"dumperName,attr"`
+	DumpDate     string `xml:"dumpDate,attr,omitempty"`
+	DumpYear     uint16 `xml:"dumpYear,attr"`
+	DumpMonth    uint8  `xml:"dumpMonth,attr"`
+	DumpDay      uint8  `xml:"dumpDay,attr"`
+	DumpingAgent string `xml:"dumpingAgent,attr"`
+}
+
+type UNIFChunkXMLFields struct {
+	Text  string `xml:",chardata"`
+	ID    string `xml:"id,attr"`
+	Size  uint64 `xml:"size,attr"`
+	Crc32 string `xml:"crc32,attr"`
+	Value string `xml:"value,attr,omitempty"`
+}
+
 type FDSXMLFields struct {
-	Text           string              `xml:",chardata"`
-	FDSArchiveDisk []*FDSDiskXMLFields `xml:"fdsDisk"`
+	Text             string              `xml:",chardata"`
+	NormalizedCrc32  string              `xml:"normalizedCrc32,attr,omitempty"`
+	NormalizedMd5    string              `xml:"normalizedMd5,attr,omitempty"`
+	NormalizedSha1   string              `xml:"normalizedSha1,attr,omitempty"`
+	NormalizedSha256 string              `xml:"normalizedSha256,attr,omitempty"`
+	FDSArchiveDisk   []*FDSDiskXMLFields `xml:"fdsDisk"`
 }
 
 type FDSDiskXMLFields struct {
@@ -241,6 +273,7 @@ type FDSSideXMLFields struct {
 	ManufacturerCode struct {
 		Text  string `xml:",chardata"`
 		Value uint8  `xml:"value,attr"`
+		Name  string `xml:"name,attr,omitempty"`
 	} `xml:"manufacturerCode"`
 	FdsGameName struct {
 		Text  string `xml:",chardata"`
@@ -433,11 +466,13 @@ type FDSFileXMLFields struct {
 	} `xml:"fileData"`
 }
 
-// Marshal maps of NESROM and FDSArchiveFile structs to XML
-func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[string]*FDSTool.FDSArchiveFile, enableInes bool, preserveTrainer bool, enableOrganization bool) (string, error) {
+// Marshal maps of NESROM and FDSArchiveFile structs to XML.  ROMs come
+// first, then FDS archives, each sorted by relative path, then name, then
+// SHA256.
+func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[string]*FDSTool.FDSArchiveFile, enableInes bool, preserveTrainer bool, enableOrganization bool, canonicalXml bool) (string, error) {
 	romXml := &NESXML{}
 
-	for key := range nesRoms {
+	for _, key := range GetSortedNESROMKeys(nesRoms) {
 		if nesRoms[key].Header20 != nil {
 			tempXmlRom := &NESXMLROM{}
 			tempXmlHeader20 := &NES20XMLFields{}
@@ -456,7 +491,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 
 			if enableOrganization {
 				tempRelativePath := nesRoms[key].RelativePath
-				if tempRelativePath[0] == os.PathSeparator {
+				if len(tempRelativePath) > 0 && tempRelativePath[0] == os.PathSeparator {
 					tempRelativePath = tempRelativePath[1:]
 				}
 				tempRelativePath = strings.Replace(tempRelativePath, string(os.PathSeparator), "/", -1)
@@ -535,15 +570,15 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 			tempXmlRom.Header20.MirroringType.Value = nesRoms[key].Header20.MirroringType
 			tempXmlRom.Header20.Battery.Value = nesRoms[key].Header20.Battery
 			tempXmlRom.Header20.FourScreen.Value = nesRoms[key].Header20.FourScreen
-			tempXmlRom.Header20.ConsoleType.Value = nesRoms[key].Header20.ConsoleType
+			tempXmlRom.Header20.ConsoleType.Value = NESTool.ConsoleType(nesRoms[key].Header20.ConsoleType)
 			tempXmlRom.Header20.Mapper.Value = nesRoms[key].Header20.Mapper
 			tempXmlRom.Header20.SubMapper.Value = nesRoms[key].Header20.SubMapper
-			tempXmlRom.Header20.CpuPpuTiming.Value = nesRoms[key].Header20.CPUPPUTiming
-			tempXmlRom.Header20.VsHardwareType.Value = nesRoms[key].Header20.VsHardwareType
-			tempXmlRom.Header20.VsPpuType.Value = nesRoms[key].Header20.VsPPUType
-			tempXmlRom.Header20.ExtendedConsoleType.Value = nesRoms[key].Header20.ExtendedConsoleType
+			tempXmlRom.Header20.CpuPpuTiming.Value = NESTool.CPUPPUTiming(nesRoms[key].Header20.CPUPPUTiming)
+			tempXmlRom.Header20.VsHardwareType.Value = NESTool.VsHardwareType(nesRoms[key].Header20.VsHardwareType)
+			tempXmlRom.Header20.VsPpuType.Value = NESTool.VsPPUType(nesRoms[key].Header20.VsPPUType)
+			tempXmlRom.Header20.ExtendedConsoleType.Value = NESTool.ExtendedConsoleType(nesRoms[key].Header20.ExtendedConsoleType)
 			tempXmlRom.Header20.MiscRoms.Value = nesRoms[key].Header20.MiscROMs
-			tempXmlRom.Header20.DefaultExpansion.Value = nesRoms[key].Header20.DefaultExpansion
+			tempXmlRom.Header20.DefaultExpansion.Value = NESTool.DefaultExpansion(nesRoms[key].Header20.DefaultExpansion)
 
 			if nesRoms[key].Header20.MiscROMs > 0 {
 				tempXmlRom.Header20.MiscRoms.Size = nesRoms[key].Header20.MiscROMCalculatedSize
@@ -561,6 +596,10 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 				tempXmlRom.Header20.MiscRoms.Sha256 = strings.ToUpper(hex.EncodeToString(nesRoms[key].Header20.MiscROMSHA256[:]))
 			}
 
+			if len(nesRoms[key].UNIFChunks) > 0 {
+				tempXmlRom.UNIF = getUNIFXMLFields(nesRoms[key])
+			}
+
 			romXml.XMLROMs = append(romXml.XMLROMs, tempXmlRom)
 		} else if enableInes && nesRoms[key].Header10 != nil {
 			tempXmlRom := &NESXMLROM{}
@@ -580,7 +619,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 
 			if enableOrganization {
 				tempRelativePath := nesRoms[key].RelativePath
-				if tempRelativePath[0] == os.PathSeparator {
+				if len(tempRelativePath) > 0 && tempRelativePath[0] == os.PathSeparator {
 					tempRelativePath = tempRelativePath[1:]
 				}
 				tempRelativePath = strings.Replace(tempRelativePath, string(os.PathSeparator), "/", -1)
@@ -654,7 +693,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 		}
 	}
 
-	for key := range fdsArchives {
+	for _, key := range GetSortedFDSArchiveKeys(fdsArchives) {
 		tempXmlRom := &NESXMLROM{}
 		tempFdsArchive := &FDSXMLFields{}
 
@@ -663,7 +702,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 
 		if enableOrganization {
 			tempRelativePath := fdsArchives[key].RelativePath
-			if tempRelativePath[0] == os.PathSeparator {
+			if len(tempRelativePath) > 0 && tempRelativePath[0] == os.PathSeparator {
 				tempRelativePath = tempRelativePath[1:]
 			}
 			tempRelativePath = strings.Replace(tempRelativePath, string(os.PathSeparator), "/", -1)
@@ -677,6 +716,15 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 		tempXmlRom.Sha1 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].SHA1[:]))
 		tempXmlRom.Sha256 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].SHA256[:]))
 
+		if fdsArchives[key].HasNormalizedHashes() {
+			normalizedCrc32Bytes := make([]byte, 4)
+			binary.BigEndian.PutUint32(normalizedCrc32Bytes, fdsArchives[key].NormalizedCRC32)
+			tempFdsArchive.NormalizedCrc32 = strings.ToUpper(hex.EncodeToString(normalizedCrc32Bytes))
+			tempFdsArchive.NormalizedMd5 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].NormalizedMD5[:]))
+			tempFdsArchive.NormalizedSha1 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].NormalizedSHA1[:]))
+			tempFdsArchive.NormalizedSha256 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].NormalizedSHA256[:]))
+		}
+
 		for diskKey := range fdsArchives[key].ArchiveDisks {
 			tempDisk := &FDSDiskXMLFields{}
 			tempDisk.DiskNumber = fdsArchives[key].ArchiveDisks[diskKey].DiskNumber
@@ -693,6 +741,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 				tempSide.Sha256 = strings.ToUpper(hex.EncodeToString(fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].SHA256[:]))
 
 				tempSide.ManufacturerCode.Value = fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].ManufacturerCode
+				tempSide.ManufacturerCode.Name = FDSTool.GetFDSManufacturerName(fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].ManufacturerCode)
 				tempSide.FdsGameName.Value = strings.ToUpper(hex.EncodeToString([]byte(fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].FDSGameName)))
 				tempSide.GameType.Value = fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].GameType
 				tempSide.RevisionNumber.Value = fdsArchives[key].ArchiveDisks[diskKey].DiskSides[sideKey].RevisionNumber
@@ -769,7 +818,7 @@ func MarshalXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[st
 		return "", err
 	}
 
-	return string(xmlBytes), nil
+	return finishXMLPayload(xmlBytes, false, canonicalXml), nil
 }
 
 // Unmarshal an XML file to maps of NESROM and FDSArchiveFile structs
@@ -891,15 +940,15 @@ func UnmarshalXMLToROMMap(xmlPayload string, enableInes bool, preserveTrainer bo
 			tempRom.Header20.MirroringType = xmlStruct.XMLROMs[index].Header20.MirroringType.Value
 			tempRom.Header20.Battery = xmlStruct.XMLROMs[index].Header20.Battery.Value
 			tempRom.Header20.FourScreen = xmlStruct.XMLROMs[index].Header20.FourScreen.Value
-			tempRom.Header20.ConsoleType = xmlStruct.XMLROMs[index].Header20.ConsoleType.Value
+			tempRom.Header20.ConsoleType = uint8(xmlStruct.XMLROMs[index].Header20.ConsoleType.Value)
 			tempRom.Header20.Mapper = xmlStruct.XMLROMs[index].Header20.Mapper.Value
 			tempRom.Header20.SubMapper = xmlStruct.XMLROMs[index].Header20.SubMapper.Value
-			tempRom.Header20.CPUPPUTiming = xmlStruct.XMLROMs[index].Header20.CpuPpuTiming.Value
-			tempRom.Header20.VsHardwareType = xmlStruct.XMLROMs[index].Header20.VsHardwareType.Value
-			tempRom.Header20.VsPPUType = xmlStruct.XMLROMs[index].Header20.VsPpuType.Value
-			tempRom.Header20.ExtendedConsoleType = xmlStruct.XMLROMs[index].Header20.ExtendedConsoleType.Value
+			tempRom.Header20.CPUPPUTiming = uint8(xmlStruct.XMLROMs[index].Header20.CpuPpuTiming.Value)
+			tempRom.Header20.VsHardwareType = uint8(xmlStruct.XMLROMs[index].Header20.VsHardwareType.Value)
+			tempRom.Header20.VsPPUType = uint8(xmlStruct.XMLROMs[index].Header20.VsPpuType.Value)
+			tempRom.Header20.ExtendedConsoleType = uint8(xmlStruct.XMLROMs[index].Header20.ExtendedConsoleType.Value)
 			tempRom.Header20.MiscROMs = xmlStruct.XMLROMs[index].Header20.MiscRoms.Value
-			tempRom.Header20.DefaultExpansion = xmlStruct.XMLROMs[index].Header20.DefaultExpansion.Value
+			tempRom.Header20.DefaultExpansion = uint8(xmlStruct.XMLROMs[index].Header20.DefaultExpansion.Value)
 
 			err = NESTool.UpdateSizes(tempRom, NESTool.PRG_CANONICAL_SIZE_FACTORED, NESTool.CHR_CANONICAL_SIZE_FACTORED)
 			if err != nil {
@@ -982,6 +1031,19 @@ func UnmarshalXMLToROMMap(xmlPayload string, enableInes bool, preserveTrainer bo
 				tempRom.Header20.MiscROMCalculatedSize = 0
 			}
 
+			if xmlStruct.XMLROMs[index].UNIF != nil {
+				tempRom.UNIFVersion, tempRom.UNIFChunks = getUNIFChunksFromXMLFields(xmlStruct.XMLROMs[index].UNIF)
+
+				if xmlStruct.XMLROMs[index].UNIF.DumperInfo != nil {
+					tempRom.UNIFDumper = &NESTool.UNIFDumperInfo{}
+					tempRom.UNIFDumper.DumperName = xmlStruct.XMLROMs[index].UNIF.DumperInfo.DumperName
+					tempRom.UNIFDumper.DumpYear = xmlStruct.XMLROMs[index].UNIF.DumperInfo.DumpYear
+					tempRom.UNIFDumper.DumpMonth = xmlStruct.XMLROMs[index].UNIF.DumperInfo.DumpMonth
+					tempRom.UNIFDumper.DumpDay = xmlStruct.XMLROMs[index].UNIF.DumperInfo.DumpDay
+					tempRom.UNIFDumper.DumpingAgent = xmlStruct.XMLROMs[index].UNIF.DumperInfo.DumpingAgent
+				}
+			}
+
 			romMap["SHA256:"+strings.ToUpper(hex.EncodeToString(tempRom.SHA256[:]))] = tempRom
 		} else if enableInes && xmlStruct.XMLROMs[index].Header10 != nil {
 			tempRomHeader10 := &NESTool.NES10Header{}
@@ -1116,6 +1178,28 @@ func UnmarshalXMLToROMMap(xmlPayload string, enableInes bool, preserveTrainer bo
 				tempArchive.RelativePath = tempRelativePath
 			}
 
+			if xmlStruct.XMLROMs[index].FDSArchive.NormalizedSha256 != "" {
+				normalizedCrc32Bytes, err := hex.DecodeString(strings.ToLower(xmlStruct.XMLROMs[index].FDSArchive.NormalizedCrc32))
+				if err == nil && len(normalizedCrc32Bytes) == 4 {
+					tempArchive.NormalizedCRC32 = binary.BigEndian.Uint32(normalizedCrc32Bytes)
+				}
+
+				normalizedMd5Bytes, err := hex.DecodeString(strings.ToLower(xmlStruct.XMLROMs[index].FDSArchive.NormalizedMd5))
+				if err == nil {
+					copy(tempArchive.NormalizedMD5[:], normalizedMd5Bytes)
+				}
+
+				normalizedSha1Bytes, err := hex.DecodeString(strings.ToLower(xmlStruct.XMLROMs[index].FDSArchive.NormalizedSha1))
+				if err == nil {
+					copy(tempArchive.NormalizedSHA1[:], normalizedSha1Bytes)
+				}
+
+				normalizedSha256Bytes, err := hex.DecodeString(strings.ToLower(xmlStruct.XMLROMs[index].FDSArchive.NormalizedSha256))
+				if err == nil {
+					copy(tempArchive.NormalizedSHA256[:], normalizedSha256Bytes)
+				}
+			}
+
 			for diskKey := range xmlStruct.XMLROMs[index].FDSArchive.FDSArchiveDisk {
 				tempDisk := &FDSTool.FDSDisk{}
 				tempDisk.DiskNumber = xmlStruct.XMLROMs[index].FDSArchive.FDSArchiveDisk[diskKey].DiskNumber
@@ -1255,3 +1339,72 @@ func UnmarshalXMLToROMMap(xmlPayload string, enableInes bool, preserveTrainer bo
 
 	return romMap, archiveMap, nil
 }
+
+// Record every UNIF chunk in file order, including duplicates and
+// unknown chunks.  ROM chunk data is left out, since it's already
+// covered by the PRG and CHR ROM hashes.
+func getUNIFXMLFields(romModel *NESTool.NESROM) *UNIFXMLFields {
+	tempUnif := &UNIFXMLFields{}
+	tempUnif.Version = romModel.UNIFVersion
+
+	tvSystem, hasTvSystem := UNIFTool.UNIFChunkList(romModel.UNIFChunks).TVSystem()
+	if hasTvSystem {
+		tempUnif.TvSystem = UNIFTool.GetUNIFTVSystemString(tvSystem)
+	}
+
+	if romModel.UNIFDumper != nil {
+		tempUnif.DumperInfo = &UNIFDumperInfoXMLFields{}
+		tempUnif.DumperInfo.DumperName = romModel.UNIFDumper.DumperName
+		tempUnif.DumperInfo.DumpDate = romModel.UNIFDumper.GetDumpDateString()
+		tempUnif.DumperInfo.DumpYear = romModel.UNIFDumper.DumpYear
+		tempUnif.DumperInfo.DumpMonth = romModel.UNIFDumper.DumpMonth
+		tempUnif.DumperInfo.DumpDay = romModel.UNIFDumper.DumpDay
+		tempUnif.DumperInfo.DumpingAgent = romModel.UNIFDumper.DumpingAgent
+	}
+
+	for index := range romModel.UNIFChunks {
+		tempChunk := &UNIFChunkXMLFields{}
+		tempChunk.ID = romModel.UNIFChunks[index].ID
+		tempChunk.Size = romModel.UNIFChunks[index].Size
+
+		crc32Bytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc32Bytes, romModel.UNIFChunks[index].CRC32)
+		tempChunk.Crc32 = strings.ToUpper(hex.EncodeToString(crc32Bytes))
+
+		if !UNIFTool.IsUNIFROMChunk(romModel.UNIFChunks[index].ID) {
+			tempChunk.Value = UNIFTool.GetUNIFChunkValueString(romModel.UNIFChunks[index])
+			tempChunk.Text = strings.ToUpper(hex.EncodeToString(romModel.UNIFChunks[index].Data))
+		}
+
+		tempUnif.Chunks = append(tempUnif.Chunks, tempChunk)
+	}
+
+	return tempUnif
+}
+
+// Rebuild the UNIF chunk list recorded in an XML file
+func getUNIFChunksFromXMLFields(unifFields *UNIFXMLFields) (uint32, []*NESTool.UNIFChunk) {
+	unifChunks := make([]*NESTool.UNIFChunk, 0)
+
+	for index := range unifFields.Chunks {
+		tempChunk := &NESTool.UNIFChunk{}
+		tempChunk.ID = unifFields.Chunks[index].ID
+		tempChunk.Size = unifFields.Chunks[index].Size
+
+		crc32Bytes, err := hex.DecodeString(strings.ToLower(unifFields.Chunks[index].Crc32))
+		if err == nil && len(crc32Bytes) == 4 {
+			tempChunk.CRC32 = binary.BigEndian.Uint32(crc32Bytes)
+		}
+
+		if len(unifFields.Chunks[index].Text) > 0 {
+			chunkDataBytes, err := hex.DecodeString(strings.ToLower(strings.TrimSpace(unifFields.Chunks[index].Text)))
+			if err == nil {
+				tempChunk.Data = chunkDataBytes
+			}
+		}
+
+		unifChunks = append(unifChunks, tempChunk)
+	}
+
+	return unifFields.Version, unifChunks
+}
@@ -0,0 +1,159 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This keeps generated XML files stable between runs, so that files
+// kept in version control only change when the ROMs in them do.
+// Entries are sorted by relative path, then name, then SHA256, and
+// the nes20db date can be pinned or left out entirely.
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"NES20Tool/NESTool"
+	"bytes"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	NES20DB_DATE_FORMAT = "2006-01-02"
+	NES20DB_DATE_TODAY  = ""
+	NES20DB_DATE_NONE   = "none"
+	XML_DECLARATION     = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>"
+)
+
+// Get the date to write to an nes20db file.  The date can be today's,
+// a pinned date in YYYY-MM-DD format, or none at all.  Canonical output
+// leaves the date out unless one has been pinned, since it would
+// otherwise change every day.
+func GetNES20DBDate(dbDate string, canonicalXml bool) (string, error) {
+	if dbDate == NES20DB_DATE_NONE {
+		return "", nil
+	}
+
+	if dbDate == NES20DB_DATE_TODAY {
+		if canonicalXml {
+			return "", nil
+		}
+
+		return time.Now().Format(NES20DB_DATE_FORMAT), nil
+	}
+
+	_, err := time.Parse(NES20DB_DATE_FORMAT, dbDate)
+	if err != nil {
+		return "", errors.New("Invalid nes20db date, expected YYYY-MM-DD or \"" + NES20DB_DATE_NONE + "\": " + dbDate)
+	}
+
+	return dbDate, nil
+}
+
+// Get the keys of a ROM map, sorted by relative path, then name,
+// then SHA256.  SHA1 breaks any remaining ties, since ROMs read from
+// nes20db files don't have SHA256 hashes.
+func GetSortedNESROMKeys(nesRoms map[string]*NESTool.NESROM) []string {
+	keys := make([]string, 0, len(nesRoms))
+	for key := range nesRoms {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		romI := nesRoms[keys[i]]
+		romJ := nesRoms[keys[j]]
+
+		compare := compareXMLSortFields(romI.RelativePath, romI.Name, romI.SHA256[:], romI.SHA1[:], romJ.RelativePath, romJ.Name, romJ.SHA256[:], romJ.SHA1[:])
+		if compare != 0 {
+			return compare < 0
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// Get the keys of an FDS archive map, sorted the same way as ROM maps
+func GetSortedFDSArchiveKeys(fdsArchives map[string]*FDSTool.FDSArchiveFile) []string {
+	keys := make([]string, 0, len(fdsArchives))
+	for key := range fdsArchives {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		archiveI := fdsArchives[keys[i]]
+		archiveJ := fdsArchives[keys[j]]
+
+		compare := compareXMLSortFields(archiveI.RelativePath, archiveI.Name, archiveI.SHA256[:], archiveI.SHA1[:], archiveJ.RelativePath, archiveJ.Name, archiveJ.SHA256[:], archiveJ.SHA1[:])
+		if compare != 0 {
+			return compare < 0
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// Finish a marshalled XML payload.  Canonical output always starts
+// with an XML declaration and ends with a newline.
+func finishXMLPayload(xmlBytes []byte, includeDeclaration bool, canonicalXml bool) string {
+	returnString := string(xmlBytes)
+
+	if includeDeclaration || canonicalXml {
+		returnString = XML_DECLARATION + "\n" + returnString
+	}
+
+	if canonicalXml && !strings.HasSuffix(returnString, "\n") {
+		returnString = returnString + "\n"
+	}
+
+	return returnString
+}
+
+// Compare two sets of sort fields.  Relative paths are compared with
+// their separators normalized, so the order is the same on every OS.
+func compareXMLSortFields(relativePathA string, nameA string, sha256A []byte, sha1A []byte, relativePathB string, nameB string, sha256B []byte, sha1B []byte) int {
+	compare := strings.Compare(getXMLSortRelativePath(relativePathA), getXMLSortRelativePath(relativePathB))
+	if compare != 0 {
+		return compare
+	}
+
+	compare = strings.Compare(nameA, nameB)
+	if compare != 0 {
+		return compare
+	}
+
+	compare = bytes.Compare(sha256A, sha256B)
+	if compare != 0 {
+		return compare
+	}
+
+	return bytes.Compare(sha1A, sha1B)
+}
+
+func getXMLSortRelativePath(relativePath string) string {
+	if len(relativePath) > 0 && relativePath[0] == os.PathSeparator {
+		relativePath = relativePath[1:]
+	}
+
+	return strings.Replace(relativePath, string(os.PathSeparator), "/", -1)
+}
@@ -0,0 +1,286 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// This implements a split layout for the default XML format.  Rather
+// than one file for a whole ROM set, there's one fragment file for each
+// game directory, and an index file listing the fragments.  Changes to
+// a few ROMs then only touch the fragments they're in.
+
+package FileTools
+
+import (
+	"NES20Tool/FDSTool"
+	"NES20Tool/NESTool"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	XML_LAYOUT_SINGLE       = "single"
+	XML_LAYOUT_SPLIT        = "split"
+	XML_SPLIT_FRAGMENT_FILE = "nesroms.xml"
+	XML_SPLIT_INDEX_ELEMENT = "nesromsindex"
+)
+
+type NESXMLIndex struct {
+	XMLName   xml.Name               `xml:"nesromsindex"`
+	Text      string                 `xml:",chardata"`
+	Fragments []*NESXMLIndexFragment `xml:"fragment"`
+}
+
+type NESXMLIndexFragment struct {
+	Text string `xml:",chardata"`
+	Path string `xml:"path,attr"`
+}
+
+// Marshal maps of NESROM and FDSArchiveFile structs to a split XML
+// layout.  This returns the index payload, and a map of fragment
+// payloads keyed by their paths relative to the index, with "/" as the
+// separator.  ROMs are grouped by the directory in their relative path,
+// and ROMs without one go in the fragment next to the index.  Fragments
+// always record relative paths, since they're what places each ROM in
+// its fragment when the layout is read and written again.
+func MarshalSplitXMLFromROMMap(nesRoms map[string]*NESTool.NESROM, fdsArchives map[string]*FDSTool.FDSArchiveFile, enableInes bool, preserveTrainer bool, canonicalXml bool) (string, map[string]string, error) {
+	fragmentRoms := make(map[string]map[string]*NESTool.NESROM)
+	fragmentArchives := make(map[string]map[string]*FDSTool.FDSArchiveFile)
+
+	for key := range nesRoms {
+		fragmentPath, err := getXMLFragmentPath(nesRoms[key].RelativePath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if fragmentRoms[fragmentPath] == nil {
+			fragmentRoms[fragmentPath] = make(map[string]*NESTool.NESROM)
+		}
+
+		fragmentRoms[fragmentPath][key] = nesRoms[key]
+	}
+
+	for key := range fdsArchives {
+		fragmentPath, err := getXMLFragmentPath(fdsArchives[key].RelativePath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if fragmentArchives[fragmentPath] == nil {
+			fragmentArchives[fragmentPath] = make(map[string]*FDSTool.FDSArchiveFile)
+		}
+
+		fragmentArchives[fragmentPath][key] = fdsArchives[key]
+	}
+
+	fragmentPaths := make([]string, 0, len(fragmentRoms)+len(fragmentArchives))
+	for fragmentPath := range fragmentRoms {
+		fragmentPaths = append(fragmentPaths, fragmentPath)
+	}
+
+	for fragmentPath := range fragmentArchives {
+		if fragmentRoms[fragmentPath] == nil {
+			fragmentPaths = append(fragmentPaths, fragmentPath)
+		}
+	}
+
+	sort.Strings(fragmentPaths)
+
+	indexXml := &NESXMLIndex{}
+	fragmentPayloads := make(map[string]string)
+
+	for _, fragmentPath := range fragmentPaths {
+		fragmentPayload, err := MarshalXMLFromROMMap(fragmentRoms[fragmentPath], fragmentArchives[fragmentPath], enableInes, preserveTrainer, true, canonicalXml)
+		if err != nil {
+			return "", nil, err
+		}
+
+		fragmentPayloads[fragmentPath] = fragmentPayload
+		indexXml.Fragments = append(indexXml.Fragments, &NESXMLIndexFragment{Path: fragmentPath})
+	}
+
+	xmlBytes, err := xml.MarshalIndent(indexXml, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return finishXMLPayload(xmlBytes, false, canonicalXml), fragmentPayloads, nil
+}
+
+// Write a split XML layout, with the fragments placed relative to the
+// directory the index is in.  Fragments which are no longer listed in
+// the index are left alone, and are ignored when the layout is read.
+// Every fragment path is checked before anything is written.
+func WriteSplitXML(indexPayload string, fragmentPayloads map[string]string, indexPath string) error {
+	indexDirectory := filepath.Dir(indexPath)
+
+	fragmentPaths := make([]string, 0, len(fragmentPayloads))
+	for fragmentPath := range fragmentPayloads {
+		if !isValidXMLFragmentPath(fragmentPath) {
+			return errors.New("Invalid XML fragment path: " + fragmentPath)
+		}
+
+		fragmentFilePath := filepath.Join(indexDirectory, filepath.FromSlash(fragmentPath))
+		if filepath.Clean(fragmentFilePath) == filepath.Clean(indexPath) {
+			return errors.New("XML index file conflicts with fragment file: " + indexPath)
+		}
+
+		fragmentPaths = append(fragmentPaths, fragmentPath)
+	}
+
+	sort.Strings(fragmentPaths)
+
+	for _, fragmentPath := range fragmentPaths {
+		fragmentFilePath := filepath.Join(indexDirectory, filepath.FromSlash(fragmentPath))
+
+		err := os.MkdirAll(filepath.Dir(fragmentFilePath), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = WriteStringToFile(fragmentPayloads[fragmentPath], fragmentFilePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return WriteStringToFile(indexPayload, indexPath)
+}
+
+// Check whether an XML payload is the index of a split XML layout
+func IsXMLIndex(xmlPayload string) bool {
+	decoder := xml.NewDecoder(strings.NewReader(xmlPayload))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+
+		if startElement, isStartElement := token.(xml.StartElement); isStartElement {
+			return startElement.Name.Local == XML_SPLIT_INDEX_ELEMENT
+		}
+	}
+}
+
+// Unmarshal a split XML layout to maps of NESROM and FDSArchiveFile
+// structs, reading the fragments relative to the directory the index
+// is in.  The same ROM in more than one fragment is an error, rather
+// than one silently replacing the other.  Relative paths are always
+// read, so the ROMs stay in the same fragments if they're written out
+// in the split layout again.
+func UnmarshalSplitXMLToROMMap(indexPayload string, indexPath string, enableInes bool, preserveTrainer bool) (map[string]*NESTool.NESROM, map[string]*FDSTool.FDSArchiveFile, error) {
+	fragmentPaths, fragmentPayloads, err := readXMLFragments(indexPayload, indexPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	romMap := make(map[string]*NESTool.NESROM)
+	archiveMap := make(map[string]*FDSTool.FDSArchiveFile)
+
+	for index := range fragmentPaths {
+		fragmentPath := fragmentPaths[index]
+		fragmentRoms, fragmentArchives, err := UnmarshalXMLToROMMap(fragmentPayloads[index], enableInes, preserveTrainer, true)
+		if err != nil {
+			return nil, nil, errors.New("Unable to read XML fragment " + fragmentPath + ": " + err.Error())
+		}
+
+		for key := range fragmentRoms {
+			if romMap[key] != nil {
+				return nil, nil, errors.New("Duplicate ROM " + key + " in XML fragment: " + fragmentPath)
+			}
+
+			romMap[key] = fragmentRoms[key]
+		}
+
+		for key := range fragmentArchives {
+			if archiveMap[key] != nil {
+				return nil, nil, errors.New("Duplicate FDS archive " + key + " in XML fragment: " + fragmentPath)
+			}
+
+			archiveMap[key] = fragmentArchives[key]
+		}
+	}
+
+	return romMap, archiveMap, nil
+}
+
+// Unmarshal a default format XML payload read from xmlPath, which can
+// either be a whole ROM set or the index of a split layout
+func UnmarshalXMLFileToROMMap(xmlPayload string, xmlPath string, enableInes bool, preserveTrainer bool, enableOrganization bool) (map[string]*NESTool.NESROM, map[string]*FDSTool.FDSArchiveFile, error) {
+	if IsXMLIndex(xmlPayload) {
+		return UnmarshalSplitXMLToROMMap(xmlPayload, xmlPath, enableInes, preserveTrainer)
+	}
+
+	return UnmarshalXMLToROMMap(xmlPayload, enableInes, preserveTrainer, enableOrganization)
+}
+
+// Read the fragments listed in a split XML index, returning their
+// paths and payloads in the order they're listed
+func readXMLFragments(indexPayload string, indexPath string) ([]string, []string, error) {
+	indexXml := &NESXMLIndex{}
+	err := xml.Unmarshal([]byte(indexPayload), indexXml)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fragmentPaths := make([]string, 0, len(indexXml.Fragments))
+	fragmentPayloads := make([]string, 0, len(indexXml.Fragments))
+	indexDirectory := filepath.Dir(indexPath)
+
+	for index := range indexXml.Fragments {
+		fragmentPath := indexXml.Fragments[index].Path
+		if !isValidXMLFragmentPath(fragmentPath) {
+			return nil, nil, errors.New("Invalid fragment path in XML index: " + fragmentPath)
+		}
+
+		fragmentPayload, err := ioutil.ReadFile(filepath.Join(indexDirectory, filepath.FromSlash(fragmentPath)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fragmentPaths = append(fragmentPaths, fragmentPath)
+		fragmentPayloads = append(fragmentPayloads, string(fragmentPayload))
+	}
+
+	return fragmentPaths, fragmentPayloads, nil
+}
+
+// Get the path of the fragment a ROM belongs in, from its relative path
+func getXMLFragmentPath(relativePath string) (string, error) {
+	fragmentPath := path.Join(path.Dir(getXMLSortRelativePath(relativePath)), XML_SPLIT_FRAGMENT_FILE)
+	if !isValidXMLFragmentPath(fragmentPath) {
+		return "", errors.New("Relative path can't be placed in an XML fragment: " + relativePath)
+	}
+
+	return fragmentPath, nil
+}
+
+// Fragments have to stay inside the directory the index is in
+func isValidXMLFragmentPath(fragmentPath string) bool {
+	if fragmentPath == "" || path.IsAbs(fragmentPath) || filepath.IsAbs(filepath.FromSlash(fragmentPath)) {
+		return false
+	}
+
+	cleanPath := path.Clean(fragmentPath)
+	return cleanPath != ".." && !strings.HasPrefix(cleanPath, "../")
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package FileTools
+
+import (
+	"NES20Tool/NESTool"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func getTestSplitXMLROM(name string, relativePath string, hashByte byte) *NESTool.NESROM {
+	testRom := getTestDatabaseROM(name, hashByte, 0)
+	testRom.RelativePath = filepath.FromSlash(relativePath)
+	testRom.SHA1 = [20]byte{hashByte}
+	testRom.SHA256 = [32]byte{hashByte}
+
+	return testRom
+}
+
+func getSplitXMLFragmentPaths(fragmentPayloads map[string]string) []string {
+	fragmentPaths := make([]string, 0, len(fragmentPayloads))
+	for fragmentPath := range fragmentPayloads {
+		fragmentPaths = append(fragmentPaths, fragmentPath)
+	}
+
+	sort.Strings(fragmentPaths)
+
+	return fragmentPaths
+}
+
+func TestSplitXMLRoundTrip(t *testing.T) {
+	nesRoms := map[string]*NESTool.NESROM{
+		"a": getTestSplitXMLROM("A", "Dir A/A.nes", 1),
+		"b": getTestSplitXMLROM("B", "B.nes", 2),
+		"c": getTestSplitXMLROM("C", "Dir B/Sub/C.nes", 3),
+	}
+
+	indexPayload, fragmentPayloads, err := MarshalSplitXMLFromROMMap(nesRoms, nil, false, false, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedPaths := []string{"Dir A/nesroms.xml", "Dir B/Sub/nesroms.xml", "nesroms.xml"}
+	if !reflect.DeepEqual(getSplitXMLFragmentPaths(fragmentPayloads), expectedPaths) {
+		t.Fatalf("expected fragments %v, got %v", expectedPaths, getSplitXMLFragmentPaths(fragmentPayloads))
+	}
+
+	indexPath := filepath.Join(t.TempDir(), "index.xml")
+	err = WriteSplitXML(indexPayload, fragmentPayloads, indexPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Read back the way transform does without -rom-output-base-path
+	readPayload, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	readRoms, _, err := UnmarshalXMLFileToROMMap(string(readPayload), indexPath, false, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(readRoms) != len(nesRoms) {
+		t.Fatalf("expected %d ROMs, got %d", len(nesRoms), len(readRoms))
+	}
+
+	rewrittenIndexPayload, rewrittenFragmentPayloads, err := MarshalSplitXMLFromROMMap(readRoms, nil, false, false, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rewrittenIndexPayload != indexPayload {
+		t.Fatalf("expected the same index, got:\n%s", rewrittenIndexPayload)
+	}
+
+	if !reflect.DeepEqual(rewrittenFragmentPayloads, fragmentPayloads) {
+		t.Fatalf("expected the same fragments, got %v", getSplitXMLFragmentPaths(rewrittenFragmentPayloads))
+	}
+}
+
+func TestWriteSplitXML(t *testing.T) {
+	tests := []struct {
+		name             string
+		indexFile        string
+		fragmentPayloads map[string]string
+		expectError      bool
+	}{
+		{name: "fragments in directories", indexFile: "index.xml", fragmentPayloads: map[string]string{"nesroms.xml": "<nesroms></nesroms>", "Dir A/nesroms.xml": "<nesroms></nesroms>"}},
+		{name: "index clashes with a fragment", indexFile: "nesroms.xml", fragmentPayloads: map[string]string{"Dir A/nesroms.xml": "<nesroms></nesroms>", "Dir B/nesroms.xml": "<nesroms></nesroms>", "nesroms.xml": "<nesroms></nesroms>"}, expectError: true},
+		{name: "fragment outside the index directory", indexFile: "index.xml", fragmentPayloads: map[string]string{"Dir A/nesroms.xml": "<nesroms></nesroms>", "../nesroms.xml": "<nesroms></nesroms>"}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexDirectory := filepath.Join(t.TempDir(), "set")
+			indexPath := filepath.Join(indexDirectory, test.indexFile)
+
+			err := WriteSplitXML("<nesromsindex></nesromsindex>", test.fragmentPayloads, indexPath)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				// Nothing is written when any fragment path is bad
+				_, statErr := os.Stat(indexDirectory)
+				if !os.IsNotExist(statErr) {
+					t.Fatalf("expected nothing to be written, got %v", statErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for fragmentPath := range test.fragmentPayloads {
+				_, err = os.Stat(filepath.Join(indexDirectory, filepath.FromSlash(fragmentPath)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
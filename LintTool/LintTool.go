@@ -0,0 +1,483 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://wiki.nesdev.com/w/index.php/NES_2.0
+// https://wiki.nesdev.com/w/index.php/INES
+// Header lint rules.  These look for header values that decode fine but
+// are inconsistent with each other or with the ROM data, and are meant
+// to be usable from any operation that has a decoded ROM.  Raw files
+// can be linted as well, including ones that don't decode.
+
+package LintTool
+
+import (
+	"NES20Tool/NESTool"
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+var (
+	LINT_SEVERITY_INFO    uint8 = 0
+	LINT_SEVERITY_WARNING uint8 = 1
+	LINT_SEVERITY_ERROR   uint8 = 2
+)
+
+type LintRule struct {
+	ID          string
+	Severity    uint8
+	Description string
+	check       func(rom *NESTool.NESROM) []string
+	checkFile   func(fileData []byte) []string
+}
+
+type LintResult struct {
+	RuleID   string
+	Severity uint8
+	Message  string
+}
+
+// All rules, in the order they're run
+var LINT_RULES = []*LintRule{
+	{ID: "NES001", Severity: LINT_SEVERITY_ERROR, Description: "PRG ROM size is zero", check: checkPRGROMZero},
+	{ID: "NES002", Severity: LINT_SEVERITY_WARNING, Description: "ROM data length doesn't match the sizes in the header", checkFile: checkROMDataLength},
+	{ID: "NES003", Severity: LINT_SEVERITY_ERROR, Description: "Trainer flag is set without a 512-byte trainer", checkFile: checkTrainer},
+	{ID: "NES004", Severity: LINT_SEVERITY_WARNING, Description: "CHR ROM size is zero with no CHR RAM", check: checkCHRMemory},
+	{ID: "NES005", Severity: LINT_SEVERITY_WARNING, Description: "Battery is set with no NVRAM", check: checkBatteryWithoutNVRAM},
+	{ID: "NES006", Severity: LINT_SEVERITY_WARNING, Description: "NVRAM is declared without the battery flag", check: checkNVRAMWithoutBattery},
+	{ID: "NES007", Severity: LINT_SEVERITY_INFO, Description: "Mirroring is set along with four-screen mode", check: checkMirroringWithFourScreen},
+	{ID: "NES008", Severity: LINT_SEVERITY_WARNING, Description: "Byte 13 is non-zero for a console type that doesn't use it", check: checkUnusedByte13},
+	{ID: "NES009", Severity: LINT_SEVERITY_WARNING, Description: "Reserved header bits are set", check: checkReservedBits},
+	{ID: "NES010", Severity: LINT_SEVERITY_WARNING, Description: "Enumerated header value isn't a defined value", check: checkUndefinedValues},
+	{ID: "NES011", Severity: LINT_SEVERITY_WARNING, Description: "Header is archaic iNES, so bytes 7-15 are unreliable", check: checkArchaicHeader},
+	{ID: "NES012", Severity: LINT_SEVERITY_WARNING, Description: "ROM sizes aren't ones the mapper's boards use", check: checkMapperROMSizes},
+	{ID: "NES013", Severity: LINT_SEVERITY_WARNING, Description: "Submapper isn't defined for the mapper", check: checkSubMapper},
+	{ID: "NES014", Severity: LINT_SEVERITY_INFO, Description: "Battery is set for a mapper whose boards don't usually have one", check: checkMapperBattery},
+
+	// Only LintNESROMFile can find this, and it's reported first
+	{ID: "NES015", Severity: LINT_SEVERITY_ERROR, Description: "ROM can't be decoded"},
+}
+
+func (result *LintResult) String() string {
+	return "[" + GetLintSeverityString(result.Severity) + "] " + result.RuleID + ": " + result.Message
+}
+
+func GetLintSeverityString(severity uint8) string {
+	switch severity {
+	case LINT_SEVERITY_INFO:
+		return "INFO"
+	case LINT_SEVERITY_WARNING:
+		return "WARNING"
+	case LINT_SEVERITY_ERROR:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Find a rule by its ID, returning nil if there isn't one
+func GetLintRule(ruleId string) *LintRule {
+	for index := range LINT_RULES {
+		if LINT_RULES[index].ID == ruleId {
+			return LINT_RULES[index]
+		}
+	}
+
+	return nil
+}
+
+// Run every rule against a ROM.  ROMs without a decoded header have
+// nothing to check, so they produce no results.  Rules that check the
+// file length run against the file the ROM was decoded from.
+func LintNESROM(rom *NESTool.NESROM) []*LintResult {
+	results := make([]*LintResult, 0)
+	if rom == nil || (rom.Header20 == nil && rom.Header10 == nil) {
+		return results
+	}
+
+	fileData := getNESROMFileData(rom)
+
+	for index := range LINT_RULES {
+		var messages []string
+		if LINT_RULES[index].check != nil {
+			messages = LINT_RULES[index].check(rom)
+		} else if LINT_RULES[index].checkFile != nil && fileData != nil {
+			messages = LINT_RULES[index].checkFile(fileData)
+		}
+
+		results = appendLintResults(results, LINT_RULES[index], messages)
+	}
+
+	return results
+}
+
+// Decode and lint a raw NES ROM file.  A file that can't be decoded is
+// a finding rather than a failure, and the rules that check the file
+// length still run against it, since a truncated file is the usual
+// reason it can't be decoded.
+func LintNESROMFile(fileData []byte, cleanHeader bool) []*LintResult {
+	rom, err := NESTool.DecodeNESROM(fileData, true, true, "")
+	if err == nil {
+		if cleanHeader {
+			NESTool.CleanNESROMHeader(rom)
+		}
+
+		return LintNESROM(rom)
+	}
+
+	results := appendLintResults(make([]*LintResult, 0), GetLintRule("NES015"), []string{"ROM can't be decoded: " + err.Error()})
+	if len(fileData) < 16 || !bytes.Equal(fileData[0:4], []byte(NESTool.NES_HEADER_MAGIC)) {
+		return results
+	}
+
+	for index := range LINT_RULES {
+		if LINT_RULES[index].checkFile != nil {
+			results = appendLintResults(results, LINT_RULES[index], LINT_RULES[index].checkFile(fileData))
+		}
+	}
+
+	return results
+}
+
+// Get the highest severity in a set of results, or -1 if there aren't any
+func GetHighestLintSeverity(results []*LintResult) int {
+	highestSeverity := -1
+	for index := range results {
+		if int(results[index].Severity) > highestSeverity {
+			highestSeverity = int(results[index].Severity)
+		}
+	}
+
+	return highestSeverity
+}
+
+func checkPRGROMZero(rom *NESTool.NESROM) []string {
+	if rom.Header20 != nil && rom.Header20.PRGROMCalculatedSize == 0 {
+		return []string{"PRG ROM size is zero"}
+	} else if rom.Header20 == nil && rom.Header10.PRGROMCalculatedSize == 0 {
+		return []string{"PRG ROM size is zero"}
+	}
+
+	return nil
+}
+
+// A file too short for its trainer is left to the trainer rule
+func checkROMDataLength(fileData []byte) []string {
+	prgRomSize, chrRomSize, trainerSize, isNES20 := NESTool.GetNESHeaderDeclaredSizes(fileData)
+	if uint64(len(fileData)) < 16+trainerSize {
+		return nil
+	}
+
+	dataSize := uint64(len(fileData)) - 16 - trainerSize
+	declaredSize := prgRomSize + chrRomSize
+	if declaredSize < prgRomSize {
+		declaredSize = ^uint64(0)
+	}
+
+	if dataSize < declaredSize {
+		return []string{"ROM data is " + strconv.FormatUint(dataSize, 10) + " bytes, but the header declares " + strconv.FormatUint(declaredSize, 10) + " bytes of PRG and CHR ROM"}
+	}
+
+	if isNES20 {
+		// Anything after PRG and CHR ROM is misc ROM data in NES 2.0
+		miscRoms := fileData[14] & 0b00000011
+		if dataSize > declaredSize && miscRoms == 0 {
+			return []string{strconv.FormatUint(dataSize-declaredSize, 10) + " bytes of data follow PRG and CHR ROM, but no misc ROMs are declared"}
+		} else if dataSize == declaredSize && miscRoms > 0 {
+			return []string{strconv.Itoa(int(miscRoms)) + " misc ROMs are declared, but there's no data after PRG and CHR ROM"}
+		}
+
+		return nil
+	}
+
+	if dataSize != declaredSize {
+		return []string{"ROM data is " + strconv.FormatUint(dataSize, 10) + " bytes, but the header declares " + strconv.FormatUint(declaredSize, 10) + " bytes of PRG and CHR ROM"}
+	}
+
+	return nil
+}
+
+func checkTrainer(fileData []byte) []string {
+	_, _, trainerSize, _ := NESTool.GetNESHeaderDeclaredSizes(fileData)
+	if trainerSize > 0 && uint64(len(fileData)) < 16+trainerSize {
+		return []string{"Trainer flag is set, but the file only has " + strconv.Itoa(len(fileData)-16) + " bytes after the header"}
+	}
+
+	return nil
+}
+
+// iNES always means 8 KiB of CHR RAM when there's no CHR ROM, so only
+// NES 2.0 headers can get this wrong.
+func checkCHRMemory(rom *NESTool.NESROM) []string {
+	if rom.Header20 == nil {
+		return nil
+	}
+
+	if rom.Header20.CHRROMCalculatedSize == 0 && rom.Header20.CHRRAMSize == 0 && rom.Header20.CHRNVRAMSize == 0 {
+		return []string{"CHR ROM size is zero, but no CHR RAM or CHR NVRAM is declared"}
+	}
+
+	return nil
+}
+
+func checkBatteryWithoutNVRAM(rom *NESTool.NESROM) []string {
+	if rom.Header20 == nil {
+		return nil
+	}
+
+	if rom.Header20.Battery && rom.Header20.PRGNVRAMSize == 0 && rom.Header20.CHRNVRAMSize == 0 {
+		return []string{"Battery flag is set, but no PRG NVRAM or CHR NVRAM is declared"}
+	}
+
+	return nil
+}
+
+func checkNVRAMWithoutBattery(rom *NESTool.NESROM) []string {
+	if rom.Header20 == nil {
+		return nil
+	}
+
+	if !rom.Header20.Battery && (rom.Header20.PRGNVRAMSize > 0 || rom.Header20.CHRNVRAMSize > 0) {
+		return []string{"PRG NVRAM or CHR NVRAM is declared, but the battery flag isn't set"}
+	}
+
+	return nil
+}
+
+// Some mappers, such as 30 and 218, use the combination of the
+// mirroring and four-screen bits to select other mirroring modes.
+func checkMirroringWithFourScreen(rom *NESTool.NESROM) []string {
+	mapper, _ := getMapperAndSubMapper(rom)
+	mirroringType, fourScreen := getMirroring(rom)
+
+	if fourScreen && mirroringType && !NESTool.HasMapperSpecificMirroring(mapper) {
+		return []string{"Vertical mirroring is set along with four-screen mode, which overrides it"}
+	}
+
+	return nil
+}
+
+func checkUnusedByte13(rom *NESTool.NESROM) []string {
+	headerBytes := getHeaderBytes(rom)
+	if rom.Header20 == nil || headerBytes == nil {
+		return nil
+	}
+
+	if rom.Header20.ConsoleType != uint8(NESTool.CONSOLE_TYPE_VS_SYSTEM) && rom.Header20.ConsoleType != uint8(NESTool.CONSOLE_TYPE_EXTENDED) && headerBytes[13] != 0 {
+		return []string{"Byte 13 is $" + strings.ToUpper(hex.EncodeToString(headerBytes[13:14])) + ", but it's only used by Vs. System and extended console types"}
+	}
+
+	return nil
+}
+
+func checkReservedBits(rom *NESTool.NESROM) []string {
+	headerBytes := getHeaderBytes(rom)
+	if headerBytes == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+
+	if rom.Header20 != nil {
+		if headerBytes[12]&0b11111100 != 0 {
+			messages = append(messages, "Reserved bits 2-7 of byte 12 are set")
+		}
+
+		if headerBytes[14]&0b11111100 != 0 {
+			messages = append(messages, "Reserved bits 2-7 of byte 14 are set")
+		}
+
+		if headerBytes[15]&0b11000000 != 0 {
+			messages = append(messages, "Reserved bits 6-7 of byte 15 are set")
+		}
+	} else if rom.HeaderType != NESTool.NES_HEADER_TYPE_ARCHAIC_INES {
+		// Archaic headers get their own rule, since bytes 7-15 are garbage there
+		if headerBytes[9]&0b11111110 != 0 {
+			messages = append(messages, "Reserved bits 1-7 of byte 9 are set")
+		}
+
+		// Byte 10 is an unofficial extension, but bits 2-3 and 6-7 aren't used by it
+		if headerBytes[10]&0b11001100 != 0 {
+			messages = append(messages, "Reserved bits 2-3 and 6-7 of byte 10 are set")
+		}
+
+		if headerBytes[11] != 0 || headerBytes[12] != 0 || headerBytes[13] != 0 || headerBytes[14] != 0 || headerBytes[15] != 0 {
+			messages = append(messages, "Unused bytes 11-15 aren't zero")
+		}
+	}
+
+	return messages
+}
+
+func checkUndefinedValues(rom *NESTool.NESROM) []string {
+	if rom.Header20 == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+
+	if rom.Header20.ConsoleType == uint8(NESTool.CONSOLE_TYPE_VS_SYSTEM) {
+		if NESTool.VsPPUType(rom.Header20.VsPPUType) > NESTool.VS_PPU_TYPE_RC2C05_05 {
+			messages = append(messages, "Vs. PPU Type "+strconv.Itoa(int(rom.Header20.VsPPUType))+" isn't defined")
+		}
+
+		if NESTool.VsHardwareType(rom.Header20.VsHardwareType) > NESTool.VS_HARDWARE_TYPE_DUAL_SYSTEM_RAID {
+			messages = append(messages, "Vs. Hardware Type "+strconv.Itoa(int(rom.Header20.VsHardwareType))+" isn't defined")
+		}
+	}
+
+	if rom.Header20.ConsoleType == uint8(NESTool.CONSOLE_TYPE_EXTENDED) {
+		extendedConsoleType := NESTool.ExtendedConsoleType(rom.Header20.ExtendedConsoleType)
+		if extendedConsoleType < NESTool.EXTENDED_CONSOLE_TYPE_DECIMAL_FAMICLONE {
+			messages = append(messages, "Extended Console Type "+strconv.Itoa(int(extendedConsoleType))+" should be written as Console Type "+strconv.Itoa(int(extendedConsoleType)))
+		} else if extendedConsoleType > NESTool.EXTENDED_CONSOLE_TYPE_UM6578 {
+			messages = append(messages, "Extended Console Type "+strconv.Itoa(int(extendedConsoleType))+" isn't defined")
+		}
+	}
+
+	if rom.Header20.DefaultExpansion > 52 {
+		messages = append(messages, "Default Expansion Device "+strconv.Itoa(int(rom.Header20.DefaultExpansion))+" isn't defined")
+	}
+
+	return messages
+}
+
+func checkArchaicHeader(rom *NESTool.NESROM) []string {
+	if rom.HeaderType != NESTool.NES_HEADER_TYPE_ARCHAIC_INES {
+		return nil
+	}
+
+	headerBytes := getHeaderBytes(rom)
+	if headerBytes != nil && NESTool.IsDiskDudeHeader(headerBytes) {
+		return []string{"Header has \"DiskDude!\" in bytes 7-15, so the upper mapper nibble is garbage"}
+	}
+
+	return []string{"Header is archaic iNES, so bytes 7-15 are unreliable"}
+}
+
+func checkMapperROMSizes(rom *NESTool.NESROM) []string {
+	// Archaic headers don't have a trustworthy mapper number
+	if rom.HeaderType == NESTool.NES_HEADER_TYPE_ARCHAIC_INES {
+		return nil
+	}
+
+	mapper, _ := getMapperAndSubMapper(rom)
+	prgRomSize, chrRomSize := uint64(0), uint64(0)
+	if rom.Header20 != nil {
+		prgRomSize, chrRomSize = rom.Header20.PRGROMCalculatedSize, rom.Header20.CHRROMCalculatedSize
+	} else {
+		prgRomSize, chrRomSize = rom.Header10.PRGROMCalculatedSize, rom.Header10.CHRROMCalculatedSize
+	}
+
+	messages := make([]string, 0)
+
+	if !NESTool.IsValidPRGROMSizeForMapper(mapper, prgRomSize) {
+		messages = append(messages, "PRG ROM size of "+strconv.FormatUint(prgRomSize, 10)+" bytes isn't used by "+NESTool.GetMapperName(mapper)+" boards")
+	}
+
+	if !NESTool.IsValidCHRROMSizeForMapper(mapper, chrRomSize) {
+		messages = append(messages, "CHR ROM size of "+strconv.FormatUint(chrRomSize, 10)+" bytes isn't used by "+NESTool.GetMapperName(mapper)+" boards")
+	}
+
+	return messages
+}
+
+func checkSubMapper(rom *NESTool.NESROM) []string {
+	if rom.Header20 == nil {
+		return nil
+	}
+
+	if !NESTool.IsKnownSubMapper(rom.Header20.Mapper, rom.Header20.SubMapper) {
+		return []string{"Submapper " + strconv.Itoa(int(rom.Header20.SubMapper)) + " isn't defined for mapper " + strconv.Itoa(int(rom.Header20.Mapper))}
+	}
+
+	return nil
+}
+
+func checkMapperBattery(rom *NESTool.NESROM) []string {
+	if rom.HeaderType == NESTool.NES_HEADER_TYPE_ARCHAIC_INES {
+		return nil
+	}
+
+	mapper, _ := getMapperAndSubMapper(rom)
+	mapperInfo := NESTool.GetMapperInfo(mapper)
+	hasBattery := (rom.Header20 != nil && rom.Header20.Battery) || (rom.Header10 != nil && rom.Header10.Battery)
+
+	if mapperInfo != nil && hasBattery && !mapperInfo.BatterySupported {
+		return []string{"Battery flag is set, but " + mapperInfo.Name + " boards don't usually have one"}
+	}
+
+	return nil
+}
+
+func getMirroring(rom *NESTool.NESROM) (bool, bool) {
+	if rom.Header20 != nil {
+		return rom.Header20.MirroringType, rom.Header20.FourScreen
+	}
+
+	return rom.Header10.MirroringType, rom.Header10.FourScreen
+}
+
+func getMapperAndSubMapper(rom *NESTool.NESROM) (uint16, uint8) {
+	if rom.Header20 != nil {
+		return rom.Header20.Mapper, rom.Header20.SubMapper
+	}
+
+	return uint16(rom.Header10.Mapper), 0
+}
+
+func appendLintResults(results []*LintResult, rule *LintRule, messages []string) []*LintResult {
+	for index := range messages {
+		results = append(results, &LintResult{RuleID: rule.ID, Severity: rule.Severity, Message: messages[index]})
+	}
+
+	return results
+}
+
+// Rebuild the file a ROM was decoded from.  Decoding always strips the
+// trainer, so one that wasn't kept is stood in for by zeroes.
+func getNESROMFileData(rom *NESTool.NESROM) []byte {
+	headerBytes := getHeaderBytes(rom)
+	if headerBytes == nil {
+		return nil
+	}
+
+	fileData := make([]byte, 0, 16+512+len(rom.ROMData))
+	fileData = append(fileData, headerBytes...)
+
+	if headerBytes[6]&0b00000100 == 0b00000100 {
+		if len(rom.TrainerData) == 512 {
+			fileData = append(fileData, rom.TrainerData...)
+		} else {
+			fileData = append(fileData, make([]byte, 512)...)
+		}
+	}
+
+	return append(fileData, rom.ROMData...)
+}
+
+// Get the original header, if the ROM was decoded from an NES file
+func getHeaderBytes(rom *NESTool.NESROM) []byte {
+	if rom.HeaderData == nil || len(rom.HeaderData) != 16 || !bytes.Equal(rom.HeaderData[0:4], []byte(NESTool.NES_HEADER_MAGIC)) {
+		return nil
+	}
+
+	return rom.HeaderData
+}
@@ -0,0 +1,160 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package LintTool
+
+import (
+	"NES20Tool/NESTool"
+	"testing"
+)
+
+// Build an NROM file with 16 KiB of PRG ROM and 8 KiB of CHR ROM.
+// Header bytes 6 and 7 pick iNES or NES 2.0, and the data is cut or
+// padded by extraBytes.
+func getTestNESROMFile(headerByte6 byte, headerByte7 byte, extraBytes int) []byte {
+	fileData := []byte{'N', 'E', 'S', 0x1a, 1, 1, headerByte6, headerByte7, 0, 0, 0, 0, 0, 0, 0, 0}
+	dataSize := 16*1024 + 8*1024
+	if headerByte6&0b00000100 == 0b00000100 {
+		dataSize = dataSize + 512
+	}
+
+	return append(fileData, make([]byte, dataSize+extraBytes)...)
+}
+
+func TestLintNESROMFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		fileData        []byte
+		expectedRuleIds []string
+	}{
+		{name: "NES 2.0 ROM", fileData: getTestNESROMFile(0, 0x08, 0), expectedRuleIds: []string{}},
+		{name: "iNES ROM", fileData: getTestNESROMFile(0, 0, 0), expectedRuleIds: []string{}},
+		{name: "NES 2.0 ROM with a trainer", fileData: getTestNESROMFile(0b00000100, 0x08, 0), expectedRuleIds: []string{}},
+		{name: "truncated NES 2.0 ROM", fileData: getTestNESROMFile(0, 0x08, -100), expectedRuleIds: []string{"NES015", "NES002"}},
+		{name: "truncated iNES ROM", fileData: getTestNESROMFile(0, 0, -8*1024), expectedRuleIds: []string{"NES015", "NES002"}},
+		{name: "trainer flag without a trainer", fileData: getTestNESROMFile(0b00000100, 0x08, -(16*1024 + 8*1024 + 100)), expectedRuleIds: []string{"NES015", "NES003"}},
+		{name: "data after an NES 2.0 ROM", fileData: getTestNESROMFile(0, 0x08, 100), expectedRuleIds: []string{"NES002"}},
+		{name: "data after an iNES ROM", fileData: getTestNESROMFile(0, 0, 100), expectedRuleIds: []string{"NES002"}},
+		{name: "header only", fileData: getTestNESROMFile(0, 0x08, -(16*1024 + 8*1024)), expectedRuleIds: []string{"NES015", "NES002"}},
+		{name: "too short for a header", fileData: []byte{'N', 'E', 'S', 0x1a}, expectedRuleIds: []string{"NES015"}},
+		{name: "no NES magic", fileData: make([]byte, 64), expectedRuleIds: []string{"NES015"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := LintNESROMFile(test.fileData, false)
+
+			ruleIds := make([]string, 0, len(results))
+			for index := range results {
+				ruleIds = append(ruleIds, results[index].RuleID)
+			}
+
+			if len(ruleIds) != len(test.expectedRuleIds) {
+				t.Fatalf("expected rules %v, got %v", test.expectedRuleIds, results)
+			}
+
+			for index := range ruleIds {
+				if ruleIds[index] != test.expectedRuleIds[index] {
+					t.Fatalf("expected rules %v, got %v", test.expectedRuleIds, ruleIds)
+				}
+			}
+		})
+	}
+}
+
+// A decoded ROM is linted against the file it came from, even when
+// its trainer wasn't kept
+func TestLintNESROMDecodedTrainer(t *testing.T) {
+	fileData := getTestNESROMFile(0b00000100, 0x08, 0)
+	for _, preserveTrainer := range []bool{false, true} {
+		rom, err := NESTool.DecodeNESROM(fileData, true, preserveTrainer, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		results := LintNESROM(rom)
+		for index := range results {
+			if results[index].RuleID == "NES002" || results[index].RuleID == "NES003" {
+				t.Fatalf("unexpected result with preserveTrainer %v: %v", preserveTrainer, results[index])
+			}
+		}
+	}
+}
+
+// Header values are linted on NROM files built by getTestNESROMFile,
+// with the given header bytes overridden
+func TestLintNESROMFileHeaderValues(t *testing.T) {
+	tests := []struct {
+		name            string
+		headerByte6     byte
+		headerByte7     byte
+		headerBytes     map[int]byte
+		extraBytes      int
+		expectedRuleIds []string
+	}{
+		{name: "no CHR ROM or CHR RAM", headerByte7: 0x08, headerBytes: map[int]byte{5: 0}, extraBytes: -8 * 1024, expectedRuleIds: []string{"NES004"}},
+		{name: "no CHR ROM with CHR RAM", headerByte7: 0x08, headerBytes: map[int]byte{5: 0, 11: 0x07}, extraBytes: -8 * 1024, expectedRuleIds: []string{}},
+		{name: "battery without NVRAM", headerByte6: 0x12, headerByte7: 0x08, headerBytes: map[int]byte{4: 2}, extraBytes: 16 * 1024, expectedRuleIds: []string{"NES005"}},
+		{name: "battery with PRG NVRAM", headerByte6: 0x12, headerByte7: 0x08, headerBytes: map[int]byte{4: 2, 10: 0x70}, extraBytes: 16 * 1024, expectedRuleIds: []string{}},
+		{name: "PRG NVRAM without battery", headerByte7: 0x08, headerBytes: map[int]byte{10: 0x70}, expectedRuleIds: []string{"NES006"}},
+		{name: "vertical mirroring with four-screen", headerByte6: 0x09, headerByte7: 0x08, expectedRuleIds: []string{"NES007"}},
+		{name: "vertical mirroring with four-screen for Magic Floor", headerByte6: 0xA9, headerByte7: 0xD8, headerBytes: map[int]byte{5: 0, 11: 0x07}, extraBytes: -8 * 1024, expectedRuleIds: []string{}},
+		{name: "byte 13 set for NES/Famicom", headerByte7: 0x08, headerBytes: map[int]byte{13: 0x01}, expectedRuleIds: []string{"NES008"}},
+		{name: "byte 13 set for Vs. System", headerByte7: 0x09, headerBytes: map[int]byte{13: 0x01}, expectedRuleIds: []string{}},
+		{name: "NES 2.0 reserved bits", headerByte7: 0x08, headerBytes: map[int]byte{12: 0x04, 14: 0x04, 15: 0x40}, expectedRuleIds: []string{"NES009", "NES009", "NES009"}},
+		{name: "iNES reserved bits", headerBytes: map[int]byte{9: 0x02, 10: 0x04, 11: 0x01}, expectedRuleIds: []string{"NES009", "NES009", "NES009"}},
+		{name: "undefined Vs. PPU Type and Hardware Type", headerByte7: 0x09, headerBytes: map[int]byte{13: 0x7F}, expectedRuleIds: []string{"NES010", "NES010"}},
+		{name: "undefined extended console type", headerByte7: 0x0B, headerBytes: map[int]byte{13: 0x0F}, expectedRuleIds: []string{"NES010"}},
+		{name: "standard console type written as an extended one", headerByte7: 0x0B, headerBytes: map[int]byte{13: 0x01}, expectedRuleIds: []string{"NES010"}},
+		{name: "undefined default expansion device", headerByte7: 0x08, headerBytes: map[int]byte{15: 0x3F}, expectedRuleIds: []string{"NES010"}},
+		{name: "archaic iNES header", headerBytes: map[int]byte{12: 'a', 13: 'b', 14: 'c', 15: 'd'}, expectedRuleIds: []string{"NES011"}},
+		{name: "PRG ROM size NROM boards don't use", headerByte7: 0x08, headerBytes: map[int]byte{4: 3}, extraBytes: 32 * 1024, expectedRuleIds: []string{"NES012"}},
+		{name: "CHR ROM size NROM boards don't use", headerByte7: 0x08, headerBytes: map[int]byte{5: 2}, extraBytes: 8 * 1024, expectedRuleIds: []string{"NES012"}},
+		{name: "undefined MMC1 submapper", headerByte6: 0x10, headerByte7: 0x08, headerBytes: map[int]byte{4: 2, 8: 0x30}, extraBytes: 16 * 1024, expectedRuleIds: []string{"NES013"}},
+		{name: "defined MMC1 submapper", headerByte6: 0x10, headerByte7: 0x08, headerBytes: map[int]byte{4: 2, 8: 0x50}, extraBytes: 16 * 1024, expectedRuleIds: []string{}},
+		{name: "battery on NROM", headerByte6: 0x02, expectedRuleIds: []string{"NES014"}},
+		{name: "battery on NES 2.0 NROM", headerByte6: 0x02, headerByte7: 0x08, headerBytes: map[int]byte{10: 0x70}, expectedRuleIds: []string{"NES014"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileData := getTestNESROMFile(test.headerByte6, test.headerByte7, test.extraBytes)
+			for index, value := range test.headerBytes {
+				fileData[index] = value
+			}
+
+			results := LintNESROMFile(fileData, false)
+
+			ruleIds := make([]string, 0, len(results))
+			for index := range results {
+				ruleIds = append(ruleIds, results[index].RuleID)
+			}
+
+			if len(ruleIds) != len(test.expectedRuleIds) {
+				t.Fatalf("expected rules %v, got %v", test.expectedRuleIds, results)
+			}
+
+			for index := range ruleIds {
+				if ruleIds[index] != test.expectedRuleIds[index] {
+					t.Fatalf("expected rules %v, got %v", test.expectedRuleIds, ruleIds)
+				}
+			}
+		})
+	}
+}
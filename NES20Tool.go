@@ -22,8 +22,11 @@ package main
 import (
 	"NES20Tool/FDSTool"
 	"NES20Tool/FileTools"
+	"NES20Tool/LintTool"
 	"NES20Tool/NESTool"
 	"NES20Tool/ProcessingTools"
+	"NES20Tool/UNIFTool"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -35,36 +38,53 @@ import (
 
 func main() {
 	// Parse the CLI options
+	var databaseSources databaseSourceList
+	flag.Var(&databaseSources, "db", "A database to use, as format:path, where format is one of {default|nes20db}.  A path on its own is read as a default format XML file.  Can be given more than once.  db-diff takes the old database, then the new one, db-check checks each one given, and db-merge and write take databases in order of precedence, highest first.  With write, any -xml-file comes first.")
 	romSetEnableFDS := flag.Bool("enable-fds", false, "Enable FDS support.")
 	romSetEnableFDSHeaders := flag.Bool("enable-fds-headers", false, "Enable writing FDS headers for organization.")
+	fdsOrganizationTemplate := flag.String("fds-organization-template", "", "A naming template for FDS archives written by the write operation with -organization, such as \"{manufacturer}/{filename}\" to group disks by publisher.  Fields are {name}, {relative-path}, {directory} and {filename}, taken from the database, and {manufacturer} and {manufacturer-code}, taken from the first disk side.")
+	mergeRules := flag.String("merge-rules", "", "Fields to take from a particular database with the db-merge operation, as comma-separated field=number pairs, where number is the database's position in the -db options, starting from 1.  Fields are the editheaderfield fields, along with name and relative-path.")
+	romSetCanonicalXml := flag.Bool("canonical-xml", false, "Write XML in a canonical form, with an XML declaration, a trailing newline, and no nes20db date unless one is set with -nes20db-date, so unchanged ROM sets produce identical files.")
+	romSetCleanHeaders := flag.Bool("clean-headers", false, "Clear garbage bytes, such as \"DiskDude!\", from archaic iNES headers and re-derive the mapper from what's left.")
+	romSetEnableUNIF := flag.Bool("enable-unif", false, "Enable reading UNIF ROMs alongside NES ROMs.  NES ROMs take priority when both have the same contents.")
 	romSetEnableV1 := flag.Bool("enable-ines", false, "Enable iNES header support.  iNES headers will always be lower priority for operations than NES 2.0 headers.")
 	romSetGenerateFDSCRCs := flag.Bool("generate-fds-crcs", false, "Generate FDS CRCs for data chunks.  Few, if any, emulators use these.")
-	romSetCommand := flag.String("operation", "", "Required.  Operation to perform on the ROM or ROM set. {read|write|transform|rominfo|editheaderfield}")
+	romSetNormalizeFDSHashes := flag.Bool("normalize-fds-hashes", false, "Generate and match FDS hashes with disk writer metadata and unallocated space zeroed.")
+	romSetCommand := flag.String("operation", "", "Required.  Operation to perform on the ROM or ROM set. {read|write|transform|rominfo|editheaderfield|unif-to-nes|nes-to-unif|upgrade-header|downgrade-header|split|assemble|editheader|lint|explain-header|db-diff|db-merge|db-check}")
+	romSetLint := flag.Bool("lint", false, "Check ROM headers against the lint rules as they're read, and print any findings.")
+	nes20dbDate := flag.String("nes20db-date", "", "The date to write to nes20db XML files, in YYYY-MM-DD format, or \"none\" to leave it out.  Defaults to today's date.")
 	romSetOrganization := flag.Bool("organization", false, "Read/write relative file location information for automatic organization.")
 	romSetPrintChecksums := flag.Bool("print-checksums", false, "Print checksums as ROMs are loaded or processed.")
+	romSetStripHeaders := flag.Bool("strip-headers", false, "Write headerless ROMs with the write operation, for No-Intro style sets.  Trainers are only kept with -preserve-trainers.")
+	romSetHeaderedFromDB := flag.Bool("headered-from-db", true, "Write ROMs with headers from the database with the write operation.  This is the default, and is overridden by -strip-headers.")
 	romSetTruncateRoms := flag.Bool("truncate-roms", false, "Truncate PRGROM and CHRROM to the sizes specified in the header.")
+	romSetUpgradeMiscROM := flag.Bool("upgrade-misc-rom", false, "Keep data after the CHR ROM as a miscellaneous ROM with the upgrade-header operation.  Without this, the data is kept as it is but only reported, since it's often overdump or padding.")
 	romSetPreserveTrainers := flag.Bool("preserve-trainers", false, "Preserve trainers in read/write process.")
 	romOutputBasePath := flag.String("rom-output-base-path", "", "The path to use for writing organized NES and/or FDS ROMs.")
 	romSetSourceDirectory := flag.String("rom-source-path", "", "Required.  The path to a directory with NES and/or FDS ROMs to use for the operation.")
-	romSetXmlFile := flag.String("xml-file", "", "The path to an XML file to use for the operation.")
+	romSetXmlFile := flag.String("xml-file", "", "The path to an XML file to use for the operation.  With explain-header, the ROM's header is compared to the one from this file.")
+	xmlLayout := flag.String("xml-layout", "single", "The layout of default format XML written by the read and transform operations.  The split layout writes one fragment file for each game directory, plus an index file at the XML path, which can be read back like any other XML file.  Fragments always record relative paths. {single|split}")
 	xmlFormat := flag.String("xml-format", "default", "The format of the imported or exported XML file. {default|nes20db}")
-	formatTransformDestination := flag.String("format-transform-destination", "", "Destination file for format transform operations.")
-	formatTransformType := flag.String("format-transform-type", "", "Format of destination file for transform operations. {default|nes20db|sanni}")
-	romToAnalyze := flag.String("rom-file", "", "An NES ROM file to analyze with the rominfo operation.")
-	inputRom := flag.String("input-rom", "", "The ROM to edit when editing a header field.")
-	outputRom := flag.String("output-rom", "", "The ROM to write when editing a header field.")
+	formatTransformDestination := flag.String("format-transform-destination", "", "Destination file for format transform and db-merge operations.")
+	formatTransformType := flag.String("format-transform-type", "", "Format of destination file for transform and db-merge operations. {default|nes20db|sanni}")
+	romInfoOutput := flag.String("output", "text", "The output format for the rominfo, db-diff and db-merge operations.  A single ROM is printed in full as text or JSON, and a directory from -rom-source-path is printed as one row per ROM in JSON lines or CSV.  db-diff and db-merge reports are text or JSON. {text|json|csv}")
+	romToAnalyze := flag.String("rom-file", "", "An NES ROM, UNIF ROM, or FDS archive file to analyze with the rominfo operation, or an NES ROM to check with the lint or explain-header operations.")
+	inputRom := flag.String("input-rom", "", "The ROM to edit when editing, upgrading or downgrading a header, the ROM to split, or the ROM to convert between UNIF and NES formats.")
+	outputRom := flag.String("output-rom", "", "The ROM to write when editing, upgrading or downgrading a header, assembling a ROM, or converting between UNIF and NES formats.")
+	manifestFile := flag.String("manifest-file", "", "The JSON header manifest to write with the split operation, or to read with the assemble operation.  Section files are kept next to it.")
+	headerSpecFile := flag.String("header-spec", "", "A file of header fields to apply with the editheader operation, as JSON or \"field: value\" lines.  Field names are the same as for editheaderfield.")
 	romFieldName := flag.String("rom-field-name", "", "The ROM field to edit when editing a header field.")
 	romFieldValue := flag.String("rom-field-value", "", "The data to apply to the specified ROM field when editing a header field.")
 
 	flag.Parse()
 
 	// Options validation
-	if *romSetCommand != "read" && *romSetCommand != "write" && *romSetCommand != "transform" && *romSetCommand != "rominfo" && *romSetCommand != "editheaderfield" {
+	if *romSetCommand != "read" && *romSetCommand != "write" && *romSetCommand != "transform" && *romSetCommand != "rominfo" && *romSetCommand != "editheaderfield" && *romSetCommand != "unif-to-nes" && *romSetCommand != "nes-to-unif" && *romSetCommand != "upgrade-header" && *romSetCommand != "downgrade-header" && *romSetCommand != "split" && *romSetCommand != "assemble" && *romSetCommand != "editheader" && *romSetCommand != "lint" && *romSetCommand != "explain-header" && *romSetCommand != "db-diff" && *romSetCommand != "db-merge" && *romSetCommand != "db-check" {
 		printUsage()
 		os.Exit(1)
 	}
 
-	if *romSetSourceDirectory == "" && *romSetCommand != "transform" && *romSetCommand != "rominfo" && *romSetCommand != "editheaderfield" {
+	if *romSetSourceDirectory == "" && *romSetCommand != "transform" && *romSetCommand != "rominfo" && *romSetCommand != "editheaderfield" && *romSetCommand != "unif-to-nes" && *romSetCommand != "nes-to-unif" && *romSetCommand != "upgrade-header" && *romSetCommand != "downgrade-header" && *romSetCommand != "split" && *romSetCommand != "assemble" && *romSetCommand != "editheader" && *romSetCommand != "lint" && *romSetCommand != "explain-header" && *romSetCommand != "db-diff" && *romSetCommand != "db-merge" && *romSetCommand != "db-check" {
 		printUsage()
 		os.Exit(1)
 	}
@@ -78,11 +98,48 @@ func main() {
 		}
 	}
 
+	if *fdsOrganizationTemplate != "" && (*romSetCommand != "write" || !*romSetOrganization || !*romSetEnableFDS) {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "write" && *romSetXmlFile == "" && len(databaseSources) == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "write" && !*romSetStripHeaders && !*romSetHeaderedFromDB {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetStripHeaders {
+		*romSetHeaderedFromDB = false
+	}
+
 	if *xmlFormat != "default" && *xmlFormat != "nes20db" {
 		printUsage()
 		os.Exit(1)
 	}
 
+	if *xmlLayout != FileTools.XML_LAYOUT_SINGLE && *xmlLayout != FileTools.XML_LAYOUT_SPLIT {
+		printUsage()
+		os.Exit(1)
+	}
+
+	// Split layouts are only supported for the default XML format
+	if *xmlLayout == FileTools.XML_LAYOUT_SPLIT && ((*romSetCommand == "read" && *xmlFormat != "default") || ((*romSetCommand == "transform" || *romSetCommand == "db-merge") && *formatTransformType != "default")) {
+		printUsage()
+		os.Exit(1)
+	}
+
+	_, err := FileTools.GetNES20DBDate(*nes20dbDate, *romSetCanonicalXml)
+	if err != nil {
+		println(err.Error())
+		printUsage()
+		os.Exit(1)
+	}
+
 	// nes20db functionality is only for NES 2.0 ROMs
 	if *xmlFormat == "nes20db" {
 		*romSetEnableV1 = false
@@ -108,16 +165,46 @@ func main() {
 		*romSetSourceDirectory = tempSourceDirectory
 	}
 
-	if *romSetCommand == "transform" && (*formatTransformDestination == "" || *formatTransformType == "") {
+	if (*romSetCommand == "transform" || *romSetCommand == "db-merge") && (*formatTransformDestination == "" || *formatTransformType == "") {
 		printUsage()
 		os.Exit(1)
 	}
 
-	if *romSetCommand == "transform" {
+	if *romSetCommand == "transform" || *romSetCommand == "db-merge" {
 		*romSetOrganization = true
 	}
 
-	if *romSetCommand == "rominfo" && *romToAnalyze == "" {
+	if *romSetCommand == "db-merge" && (len(databaseSources) == 0 || *romInfoOutput == "csv") {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "rominfo" && *romToAnalyze == "" && *romSetSourceDirectory == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romInfoOutput != "text" && *romInfoOutput != "json" && *romInfoOutput != "csv" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "db-diff" && (len(databaseSources) != 2 || *romInfoOutput == "csv") {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "db-check" && len(databaseSources) == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "explain-header" && *romToAnalyze == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "lint" && *romToAnalyze == "" && *romSetSourceDirectory == "" {
 		printUsage()
 		os.Exit(1)
 	}
@@ -127,6 +214,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	if (*romSetCommand == "unif-to-nes" || *romSetCommand == "nes-to-unif" || *romSetCommand == "upgrade-header" || *romSetCommand == "downgrade-header") && (*inputRom == "" || *outputRom == "") {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "editheader" && (*headerSpecFile == "" || ((*inputRom == "" || *outputRom == "") && (*romSetSourceDirectory == "" || *romOutputBasePath == ""))) {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "split" && (*inputRom == "" || *manifestFile == "") {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *romSetCommand == "assemble" && (*manifestFile == "" || *outputRom == "") {
+		printUsage()
+		os.Exit(1)
+	}
+
 	// Read a directory structure and generate an XML file to represent it
 	if *romSetCommand == "read" {
 		println("Loading NES 2.0 ROMs from: " + *romSetSourceDirectory)
@@ -135,11 +242,42 @@ func main() {
 			panic(err)
 		}
 
+		if *romSetCleanHeaders {
+			for key := range romMap {
+				if NESTool.CleanNESROMHeader(romMap[key]) {
+					println("Cleaned archaic iNES header: " + romMap[key].Filename)
+				}
+			}
+		}
+
+		if *romSetLint {
+			for key := range romMap {
+				lintResults := LintTool.LintNESROM(romMap[key])
+				for index := range lintResults {
+					println(romMap[key].Filename + ": " + lintResults[index].String())
+				}
+			}
+		}
+
+		if *romSetEnableUNIF && *xmlFormat == "default" {
+			println("Loading UNIF ROMs from: " + *romSetSourceDirectory)
+			unifMap, err := FileTools.LoadUNIFRecursiveMap(*romSetSourceDirectory, ProcessingTools.HASH_TYPE_SHA256, *romSetPrintChecksums)
+			if err != nil {
+				panic(err)
+			}
+
+			for key := range unifMap {
+				if romMap[key] == nil {
+					romMap[key] = unifMap[key]
+				}
+			}
+		}
+
 		archiveMap := make(map[string]*FDSTool.FDSArchiveFile, 0)
 
 		if *romSetEnableFDS {
 			println("Loading FDS archives from: " + *romSetSourceDirectory)
-			archiveMap, err = FileTools.LoadFDSArchiveRecursiveMap(*romSetSourceDirectory, *romSetGenerateFDSCRCs, ProcessingTools.HASH_TYPE_SHA256, *romSetPrintChecksums)
+			archiveMap, err = FileTools.LoadFDSArchiveRecursiveMap(*romSetSourceDirectory, *romSetGenerateFDSCRCs, *romSetNormalizeFDSHashes, ProcessingTools.HASH_TYPE_SHA256, *romSetPrintChecksums)
 			if err != nil {
 				panic(err)
 			}
@@ -148,13 +286,26 @@ func main() {
 		println("Generating XML")
 		var xmlPayload string
 
-		if *xmlFormat == "default" {
-			xmlPayload, err = FileTools.MarshalXMLFromROMMap(romMap, archiveMap, *romSetEnableV1, *romSetPreserveTrainers, *romSetOrganization)
+		if *xmlFormat == "default" && *xmlLayout == FileTools.XML_LAYOUT_SPLIT {
+			indexPayload, fragmentPayloads, err := FileTools.MarshalSplitXMLFromROMMap(romMap, archiveMap, *romSetEnableV1, *romSetPreserveTrainers, *romSetCanonicalXml)
+			if err != nil {
+				panic(err)
+			}
+
+			println("Writing XML index and " + strconv.Itoa(len(fragmentPayloads)) + " fragments to: " + *romSetXmlFile)
+			err = FileTools.WriteSplitXML(indexPayload, fragmentPayloads, *romSetXmlFile)
+			if err != nil {
+				panic(err)
+			}
+
+			os.Exit(0)
+		} else if *xmlFormat == "default" {
+			xmlPayload, err = FileTools.MarshalXMLFromROMMap(romMap, archiveMap, *romSetEnableV1, *romSetPreserveTrainers, *romSetOrganization, *romSetCanonicalXml)
 			if err != nil {
 				panic(err)
 			}
 		} else if *xmlFormat == "nes20db" {
-			xmlPayload, err = FileTools.MarshalNES20DBXMLFromROMMap(romMap, *romSetOrganization)
+			xmlPayload, err = FileTools.MarshalNES20DBXMLFromROMMap(romMap, *romSetOrganization, *nes20dbDate, *romSetCanonicalXml)
 			if err != nil {
 				panic(err)
 			}
@@ -171,33 +322,44 @@ func main() {
 		// Read an XML file and a source ROM set, match the ROMs in it, and
 		// write out a ROM set in a destination location.
 	} else if *romSetCommand == "write" {
-		println("Loading XML file from: " + *romSetXmlFile)
-		xmlPayload, err := ioutil.ReadFile(*romSetXmlFile)
-		if err != nil {
-			panic(err)
+		writeSources := make([]*FileTools.DatabaseSource, 0, len(databaseSources)+1)
+		if *romSetXmlFile != "" {
+			writeSources = append(writeSources, &FileTools.DatabaseSource{Format: *xmlFormat, Path: *romSetXmlFile})
 		}
 
-		println("Reading XML file")
-		var romData map[string]*NESTool.NESROM
-		var archiveData map[string]*FDSTool.FDSArchiveFile
+		writeSources = append(writeSources, databaseSources...)
+
+		romMaps := make([]map[string]*NESTool.NESROM, 0, len(writeSources))
+		archiveMaps := make([]map[string]*FDSTool.FDSArchiveFile, 0, len(writeSources))
+		sourceNames := make([]string, 0, len(writeSources))
 		var hashTypeMatch uint64
 
-		if *xmlFormat == "default" {
-			romData, archiveData, err = FileTools.UnmarshalXMLToROMMap(string(xmlPayload), *romSetEnableV1, *romSetPreserveTrainers, *romOutputBasePath != "")
-			if err != nil {
-				panic(err)
+		for index := range writeSources {
+			println("Loading database from: " + writeSources[index].String())
+
+			// Default format files always read their organization data for
+			// writing, since there's always an output path by this point
+			sourceOrganization := *romSetOrganization
+			if writeSources[index].Format == FileTools.DATABASE_FORMAT_DEFAULT {
+				sourceOrganization = *romOutputBasePath != ""
+				hashTypeMatch = hashTypeMatch | ProcessingTools.HASH_TYPE_SHA256
+			} else {
+				hashTypeMatch = hashTypeMatch | ProcessingTools.HASH_TYPE_SHA1
 			}
 
-			hashTypeMatch = ProcessingTools.HASH_TYPE_SHA256
-		} else if *xmlFormat == "nes20db" {
-			romData, err = FileTools.UnmarshalNES20DBXMLToROMMap(string(xmlPayload), *romSetOrganization)
+			sourceRoms, sourceArchives, err := FileTools.LoadDatabase(writeSources[index], *romSetEnableV1, *romSetPreserveTrainers, sourceOrganization)
 			if err != nil {
 				panic(err)
 			}
 
-			hashTypeMatch = ProcessingTools.HASH_TYPE_SHA1
+			romMaps = append(romMaps, sourceRoms)
+			archiveMaps = append(archiveMaps, sourceArchives)
+			sourceNames = append(sourceNames, writeSources[index].String())
 		}
 
+		romData := FileTools.CombineDatabases(romMaps, sourceNames)
+		archiveData := FileTools.MergeFDSArchives(archiveMaps, sourceNames)
+
 		println("Processing NES ROMs in: " + *romSetSourceDirectory)
 		rawRoms, err := FileTools.LoadROMRecursive(*romSetSourceDirectory, *romSetEnableV1, *romSetPreserveTrainers, *romSetPrintChecksums)
 		if err != nil {
@@ -221,12 +383,22 @@ func main() {
 
 		if *romSetEnableFDS {
 			println("Processing FDS archives in: " + *romSetSourceDirectory)
-			rawArchives, err = FileTools.LoadFDSArchiveRecursive(*romSetSourceDirectory, false, *romSetPrintChecksums)
+			rawArchives, err = FileTools.LoadFDSArchiveRecursive(*romSetSourceDirectory, false, *romSetNormalizeFDSHashes, *romSetPrintChecksums)
 			if err != nil {
 				panic(err)
 			}
 
 			matchedArchives = ProcessingTools.ProcessFDSROMs(rawArchives, archiveData, ProcessingTools.HASH_TYPE_SHA256, *romSetOrganization)
+
+			if *fdsOrganizationTemplate != "" {
+				for index := range matchedArchives {
+					matchedArchives[index].RelativePath, err = FileTools.GetFDSOrganizedRelativePath(matchedArchives[index], *fdsOrganizationTemplate)
+					if err != nil {
+						println(err.Error())
+						os.Exit(1)
+					}
+				}
+			}
 		}
 
 		tempBasePath := *romOutputBasePath
@@ -245,14 +417,25 @@ func main() {
 				tempRelativePath = matchedRoms[index].Name + ".nes"
 			}
 
+			// Note where the header came from when there's a choice
+			headerSourceNote := ""
+			if len(writeSources) > 1 {
+				headerSourceNote = " (header from " + matchedRoms[index].HeaderSource + ")"
+			}
+
 			if *romOutputBasePath == "" {
-				println("Writing NES ROM: " + tempFilename)
+				println("Writing NES ROM: " + tempFilename + headerSourceNote)
 			} else {
-				println("Writing NES ROM: " + tempBasePath + tempRelativePath)
+				println("Writing NES ROM: " + tempBasePath + tempRelativePath + headerSourceNote)
 			}
 
 			if matchedRoms[index].Header20 != nil || (*romSetEnableV1 && matchedRoms[index].Header10 != nil) {
-				err = FileTools.WriteROM(matchedRoms[index], *romSetEnableV1, *romSetTruncateRoms, *romSetPreserveTrainers, *romOutputBasePath)
+				if *romSetHeaderedFromDB {
+					err = FileTools.WriteROM(matchedRoms[index], *romSetEnableV1, *romSetTruncateRoms, *romSetPreserveTrainers, *romOutputBasePath)
+				} else {
+					err = FileTools.WriteHeaderlessROM(matchedRoms[index], *romSetEnableV1, *romSetTruncateRoms, *romSetPreserveTrainers, *romOutputBasePath)
+				}
+
 				if err != nil {
 					if *romOutputBasePath == "" {
 						println("Error writing ROM: " + tempFilename)
@@ -275,10 +458,15 @@ func main() {
 				tempRelativePath = matchedArchives[index].Name + ".nes"
 			}
 
+			archiveSourceNote := ""
+			if len(writeSources) > 1 {
+				archiveSourceNote = " (entry from " + matchedArchives[index].HeaderSource + ")"
+			}
+
 			if *romOutputBasePath == "" {
-				println("Writing FDS archive: " + tempFilename)
+				println("Writing FDS archive: " + tempFilename + archiveSourceNote)
 			} else {
-				println("Writing FDS archive: " + tempBasePath + tempRelativePath)
+				println("Writing FDS archive: " + tempBasePath + tempRelativePath + archiveSourceNote)
 			}
 
 			err = FileTools.WriteFDSArchive(matchedArchives[index], *romSetEnableFDSHeaders, *romOutputBasePath)
@@ -293,39 +481,97 @@ func main() {
 		}
 
 		os.Exit(0)
-	} else if *romSetCommand == "transform" {
-		println("Loading XML file from: " + *romSetXmlFile)
-		xmlPayload, err := ioutil.ReadFile(*romSetXmlFile)
-		if err != nil {
-			panic(err)
-		}
-
-		println("Reading source data")
+		// Transform an XML file to another format, or merge several
+		// databases and write the result the same way.
+	} else if *romSetCommand == "transform" || *romSetCommand == "db-merge" {
 		var romData map[string]*NESTool.NESROM
 		var archiveData map[string]*FDSTool.FDSArchiveFile
 
-		if *xmlFormat == "default" {
-			romData, archiveData, err = FileTools.UnmarshalXMLToROMMap(string(xmlPayload), *romSetEnableV1, *romSetPreserveTrainers, *romOutputBasePath != "")
+		if *romSetCommand == "transform" {
+			println("Loading XML file from: " + *romSetXmlFile)
+			xmlPayload, err := ioutil.ReadFile(*romSetXmlFile)
 			if err != nil {
 				panic(err)
 			}
-		} else if *xmlFormat == "nes20db" {
-			romData, err = FileTools.UnmarshalNES20DBXMLToROMMap(string(xmlPayload), *romSetOrganization)
+
+			println("Reading source data")
+			if *xmlFormat == "default" {
+				romData, archiveData, err = FileTools.UnmarshalXMLFileToROMMap(string(xmlPayload), *romSetXmlFile, *romSetEnableV1, *romSetPreserveTrainers, *romOutputBasePath != "")
+				if err != nil {
+					panic(err)
+				}
+			} else if *xmlFormat == "nes20db" {
+				romData, err = FileTools.UnmarshalNES20DBXMLToROMMap(string(xmlPayload), *romSetOrganization)
+				if err != nil {
+					panic(err)
+				}
+			}
+		} else {
+			parsedMergeRules, err := FileTools.ParseDatabaseMergeRules(*mergeRules, len(databaseSources))
+			if err != nil {
+				panic(err)
+			}
+
+			romMaps := make([]map[string]*NESTool.NESROM, 0, len(databaseSources))
+			archiveMaps := make([]map[string]*FDSTool.FDSArchiveFile, 0, len(databaseSources))
+			sourceNames := make([]string, 0, len(databaseSources))
+
+			for index := range databaseSources {
+				println("Loading database from: " + databaseSources[index].String())
+				sourceRoms, sourceArchives, err := FileTools.LoadDatabase(databaseSources[index], *romSetEnableV1, *romSetPreserveTrainers, *romSetOrganization)
+				if err != nil {
+					panic(err)
+				}
+
+				romMaps = append(romMaps, sourceRoms)
+				archiveMaps = append(archiveMaps, sourceArchives)
+				sourceNames = append(sourceNames, databaseSources[index].String())
+			}
+
+			println("Merging databases")
+			var mergeReport *FileTools.DatabaseMergeReport
+			romData, mergeReport, err = FileTools.MergeDatabases(romMaps, sourceNames, parsedMergeRules)
 			if err != nil {
 				panic(err)
 			}
+
+			archiveData = FileTools.MergeFDSArchives(archiveMaps, sourceNames)
+
+			if *romInfoOutput == "json" {
+				reportPayload, err := FileTools.MarshalDatabaseMergeReport(mergeReport)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println(reportPayload)
+			} else {
+				fmt.Print(mergeReport.String())
+			}
 		}
 
 		transformPayloadString := ""
 		transformPayloadBytes := make([]byte, 0)
 
-		if *formatTransformType == "default" {
-			transformPayloadString, err = FileTools.MarshalXMLFromROMMap(romData, archiveData, *romSetEnableV1, *romSetPreserveTrainers, *romSetOrganization)
+		if *formatTransformType == "default" && *xmlLayout == FileTools.XML_LAYOUT_SPLIT {
+			indexPayload, fragmentPayloads, err := FileTools.MarshalSplitXMLFromROMMap(romData, archiveData, *romSetEnableV1, *romSetPreserveTrainers, *romSetCanonicalXml)
+			if err != nil {
+				panic(err)
+			}
+
+			println("Writing transformed XML index and " + strconv.Itoa(len(fragmentPayloads)) + " fragments to: " + *formatTransformDestination)
+			err = FileTools.WriteSplitXML(indexPayload, fragmentPayloads, *formatTransformDestination)
+			if err != nil {
+				panic(err)
+			}
+
+			os.Exit(0)
+		} else if *formatTransformType == "default" {
+			transformPayloadString, err = FileTools.MarshalXMLFromROMMap(romData, archiveData, *romSetEnableV1, *romSetPreserveTrainers, *romSetOrganization, *romSetCanonicalXml)
 			if err != nil {
 				panic(err)
 			}
 		} else if *formatTransformType == "nes20db" {
-			transformPayloadString, err = FileTools.MarshalNES20DBXMLFromROMMap(romData, *romSetOrganization)
+			transformPayloadString, err = FileTools.MarshalNES20DBXMLFromROMMap(romData, *romSetOrganization, *nes20dbDate, *romSetCanonicalXml)
 			if err != nil {
 				panic(err)
 			}
@@ -349,464 +595,659 @@ func main() {
 		}
 
 		os.Exit(0)
-	} else if *romSetCommand == "rominfo" {
-		rom, err := FileTools.LoadROM(*romToAnalyze, true, true, "", false)
+	} else if *romSetCommand == "rominfo" && *romToAnalyze == "" {
+		println("Loading ROMs from: " + *romSetSourceDirectory)
+		nesRoms, err := FileTools.LoadROMRecursive(*romSetSourceDirectory, true, true, *romSetPrintChecksums)
 		if err != nil {
 			panic(err)
 		}
 
-		fmt.Println(rom)
-
-		os.Exit(0)
-	} else if *romSetCommand == "editheaderfield" {
-		inputFileName := filepath.Base(*inputRom)
-		inputFilePath := filepath.Dir(*inputRom)
-		outputFileName := filepath.Base(*outputRom)
-		outputFilePath := filepath.Dir(*outputRom)
+		if *romSetEnableUNIF {
+			println("Loading UNIF ROMs from: " + *romSetSourceDirectory)
+			unifRoms, err := FileTools.LoadUNIFRecursive(*romSetSourceDirectory, *romSetPrintChecksums)
+			if err != nil {
+				panic(err)
+			}
 
-		nesRom, err := FileTools.LoadROM(inputFileName, true, true, inputFilePath, false)
-		if err != nil {
-			panic(err)
+			nesRoms = append(nesRoms, unifRoms...)
 		}
 
-		if nesRom == nil {
-			println("Unable to read ROM: " + *inputRom)
-			os.Exit(1)
+		csvWriter := csv.NewWriter(os.Stdout)
+		if *romInfoOutput == "csv" {
+			err = csvWriter.Write(FileTools.ROM_INFO_CSV_COLUMNS)
+			if err != nil {
+				panic(err)
+			}
 		}
 
-		paramInt, strConvErr := strconv.ParseUint(*romFieldValue, 10, 64)
-
-		switch *romFieldName {
-		case "prg-rom-byte-size":
-			if strConvErr != nil {
-				panic(err)
+		for index := range nesRoms {
+			if *romSetCleanHeaders {
+				NESTool.CleanNESROMHeader(nesRoms[index])
 			}
 
-			if nesRom.Header20 != nil {
-				err = NESTool.UpdateSizes(nesRom, paramInt, nesRom.Header20.CHRROMCalculatedSize)
-			} else if nesRom.Header10 != nil {
-				err = NESTool.UpdateSizes(nesRom, paramInt, nesRom.Header10.CHRROMCalculatedSize)
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
+			if *romInfoOutput == "text" {
+				fmt.Println(nesRoms[index])
+				fmt.Println()
+				continue
 			}
 
+			romInfo, err := FileTools.GetNESROMInfo(nesRoms[index])
 			if err != nil {
-				panic(err)
-			}
-		case "prg-ram-size":
-			if strConvErr != nil {
-				panic(err)
+				println("Unable to get ROM info for: " + nesRoms[index].Filename)
+				continue
 			}
 
-			if nesRom.Header20 != nil {
-				if paramInt > 15 {
-					println("For NES 2.0 ROMs, PRG RAM size is calculated as 64*x^X bytes, where X is 0-15")
-					os.Exit(1)
-				}
+			romInfoRow := FileTools.GetNESROMInfoRow(romInfo)
 
-				nesRom.Header20.PRGRAMSize = uint8(paramInt)
-			} else if nesRom.Header10 != nil {
-				if paramInt > 255 {
-					println("For iNES ROMs, PRG RAM can have no more than 255 8KB units")
-					os.Exit(1)
+			if *romInfoOutput == "json" {
+				rowPayload, err := FileTools.MarshalNESROMInfoRow(romInfoRow)
+				if err != nil {
+					panic(err)
 				}
 
-				nesRom.Header10.PRGRAMSize = uint8(paramInt)
+				fmt.Println(rowPayload)
 			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
-		case "prg-nvram-size":
-			if nesRom.Header20 == nil {
-				println("PRG NVRAM is only available in NES 2.0 headers")
-				os.Exit(1)
-			}
-
-			if strConvErr != nil {
-				panic(err)
+				err = csvWriter.Write(romInfoRow.CSVRecord())
+				if err != nil {
+					panic(err)
+				}
 			}
+		}
 
-			if paramInt > 15 {
-				println("PRG NVRAM size is calculated as 64*x^X bytes, where X is 0-15")
-				os.Exit(1)
-			}
+		csvWriter.Flush()
+		if csvWriter.Error() != nil {
+			panic(csvWriter.Error())
+		}
 
-			nesRom.Header20.PRGNVRAMSize = uint8(paramInt)
-		case "chr-rom-byte-size":
-			if strConvErr != nil {
+		os.Exit(0)
+	} else if *romSetCommand == "rominfo" {
+		if strings.ToLower(filepath.Ext(*romToAnalyze)) == ".fds" {
+			archive, err := FileTools.LoadFDSArchive(*romToAnalyze, "", false, true, false)
+			if err != nil {
 				panic(err)
 			}
 
-			if nesRom.Header20 != nil {
-				err = NESTool.UpdateSizes(nesRom, nesRom.Header20.PRGROMCalculatedSize, paramInt)
-			} else if nesRom.Header10 != nil {
-				err = NESTool.UpdateSizes(nesRom, nesRom.Header10.PRGROMCalculatedSize, paramInt)
-			} else {
-				println("No valid ROM found")
+			if archive == nil {
+				println("Unable to read FDS archive: " + *romToAnalyze)
 				os.Exit(1)
 			}
 
-			if err != nil {
-				panic(err)
-			}
-		case "chr-ram-size":
-			if nesRom.Header20 == nil {
-				println("CHR RAM is only available in NES 2.0 headers")
+			if *romInfoOutput != "text" {
+				println("Only text output is available for FDS archives")
 				os.Exit(1)
 			}
 
-			if strConvErr != nil {
-				panic(err)
-			}
-
-			if paramInt > 255 {
-				println("CHR RAM can have no more than 255 8KB units")
-				os.Exit(1)
-			}
+			fmt.Println(archive)
 
-			nesRom.Header20.CHRRAMSize = uint8(paramInt)
-		case "chr-nvram-size":
-			if nesRom.Header20 == nil {
-				println("CHR NVRAM is only available in NES 2.0 headers")
-				os.Exit(1)
-			}
+			os.Exit(0)
+		}
 
-			if strConvErr != nil {
+		if strings.ToLower(filepath.Ext(*romToAnalyze)) == ".unf" || strings.ToLower(filepath.Ext(*romToAnalyze)) == ".unif" {
+			rom, err := FileTools.LoadUNIF(*romToAnalyze, "", false)
+			if err != nil {
 				panic(err)
 			}
 
-			if paramInt > 15 {
-				println("CHR NVRAM size is calculated as 64*x^X bytes, where X is 0-15")
+			if rom == nil {
+				println("Unable to read UNIF ROM: " + *romToAnalyze)
 				os.Exit(1)
 			}
 
-			nesRom.Header20.CHRNVRAMSize = uint8(paramInt)
-		case "number-of-misc-roms":
-			if nesRom.Header20 == nil {
-				println("Misc ROMs are only available in NES 2.0 ROMs")
-				os.Exit(1)
+			if *romInfoOutput != "text" {
+				printStructuredROMInfo(rom, *romInfoOutput)
+				os.Exit(0)
 			}
 
-			if strConvErr != nil {
-				panic(err)
-			}
+			fmt.Println(rom)
+			fmt.Println("UNIF Version: " + strconv.FormatUint(uint64(rom.UNIFVersion), 10))
+			fmt.Println(UNIFTool.UNIFChunkList(rom.UNIFChunks))
 
-			if paramInt > 3 {
-				println("ROM can have no more than 3 misc ROMs")
-				os.Exit(1)
-			}
+			os.Exit(0)
+		}
 
-			nesRom.Header20.MiscROMs = uint8(paramInt)
-		case "has-trainer":
-			var hasTrainer bool
+		rom, err := FileTools.LoadROM(*romToAnalyze, true, true, "", false)
+		if err != nil {
+			panic(err)
+		}
 
-			if *romFieldValue == "true" {
-				hasTrainer = true
-			} else if *romFieldValue == "false" {
-				hasTrainer = false
-			} else {
-				println("has-trainer must be one of {true|false}")
-				os.Exit(1)
-			}
+		if rom == nil {
+			println("Unable to read ROM: " + *romToAnalyze)
+			os.Exit(1)
+		}
 
-			if nesRom.Header20 != nil {
-				nesRom.Header20.Trainer = hasTrainer
-			} else if nesRom.Header10 != nil {
-				nesRom.Header10.Trainer = hasTrainer
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
-		case "mirroring-type":
-			var mirroringType bool
+		if *romSetCleanHeaders {
+			NESTool.CleanNESROMHeader(rom)
+		}
 
-			if *romFieldValue == "vertical" {
-				mirroringType = true
-			} else if *romFieldValue == "horizontal" {
-				mirroringType = false
-			} else {
-				println("mirroring-type must be one of {horizontal|vertical}")
-				os.Exit(1)
-			}
+		if *romInfoOutput != "text" {
+			printStructuredROMInfo(rom, *romInfoOutput)
+			os.Exit(0)
+		}
 
-			if nesRom.Header20 != nil {
-				nesRom.Header20.MirroringType = mirroringType
-			} else if nesRom.Header10 != nil {
-				nesRom.Header10.MirroringType = mirroringType
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
-		case "four-screen":
-			var hasFourScreen bool
+		fmt.Println(rom)
 
-			if *romFieldValue == "true" {
-				hasFourScreen = true
-			} else if *romFieldValue == "false" {
-				hasFourScreen = false
-			} else {
-				println("four-screen must be one of {true|false}")
-				os.Exit(1)
-			}
+		os.Exit(0)
+	} else if *romSetCommand == "editheaderfield" {
+		inputFilePath := filepath.Dir(*inputRom)
+		outputFileName := filepath.Base(*outputRom)
+		outputFilePath := filepath.Dir(*outputRom)
 
-			if nesRom.Header20 != nil {
-				nesRom.Header20.FourScreen = hasFourScreen
-			} else if nesRom.Header10 != nil {
-				nesRom.Header10.FourScreen = hasFourScreen
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
-		case "has-battery":
-			var hasBattery bool
+		nesRom, err := FileTools.LoadROM(*inputRom, true, true, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
 
-			if *romFieldValue == "true" {
-				hasBattery = true
-			} else if *romFieldValue == "false" {
-				hasBattery = false
-			} else {
-				println("has-battery must be one of {true|false}")
-				os.Exit(1)
-			}
+		if nesRom == nil {
+			println("Unable to read ROM: " + *inputRom)
+			os.Exit(1)
+		}
 
-			if nesRom.Header20 != nil {
-				nesRom.Header20.Battery = hasBattery
-			} else if nesRom.Header10 != nil {
-				nesRom.Header10.Battery = hasBattery
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
-		case "console-type":
-			if nesRom.Header20 == nil {
-				println("Console Type is only available in NES 2.0 headers")
-				os.Exit(1)
-			}
+		if *romSetCleanHeaders {
+			NESTool.CleanNESROMHeader(nesRom)
+		}
+
+		if !NESTool.IsNESHeaderFieldName(*romFieldName) {
+			printUsage()
+			os.Exit(1)
+		}
+
+		err = NESTool.SetNESROMHeaderField(nesRom, *romFieldName, *romFieldValue)
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+
+		nesRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		nesRom.RelativePath = outputFileName
 
-			if strConvErr != nil {
+		err = FileTools.WriteROM(nesRom, true, false, true, outputFilePath)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "explain-header" {
+		nesRom, err := FileTools.LoadROM(*romToAnalyze, true, true, "", false)
+		if err != nil {
+			panic(err)
+		}
+
+		if nesRom == nil || nesRom.HeaderData == nil {
+			println("Unable to read ROM header: " + *romToAnalyze)
+			os.Exit(1)
+		}
+
+		existingHeader := make([]byte, len(nesRom.HeaderData))
+		copy(existingHeader, nesRom.HeaderData)
+
+		if *romSetCleanHeaders {
+			NESTool.CleanNESROMHeader(nesRom)
+		}
+
+		// Apply the database's header the same way the write operation does
+		if *romSetXmlFile != "" {
+			println("Loading XML file from: " + *romSetXmlFile)
+			xmlPayload, err := ioutil.ReadFile(*romSetXmlFile)
+			if err != nil {
 				panic(err)
 			}
 
-			if paramInt > 3 {
-				println("Console Type must be a value from 0-3")
-				os.Exit(1)
-			} else if paramInt == 1 {
-				nesRom.Header20.ExtendedConsoleType = 0
-			} else if paramInt == 3 {
-				nesRom.Header20.VsHardwareType = 0
-				nesRom.Header20.VsPPUType = 0
+			var romData map[string]*NESTool.NESROM
+			var hashTypeMatch uint64
+
+			if *xmlFormat == "default" {
+				romData, _, err = FileTools.UnmarshalXMLFileToROMMap(string(xmlPayload), *romSetXmlFile, true, *romSetPreserveTrainers, false)
+				hashTypeMatch = ProcessingTools.HASH_TYPE_SHA256
 			} else {
-				nesRom.Header20.ExtendedConsoleType = 0
-				nesRom.Header20.VsHardwareType = 0
-				nesRom.Header20.VsPPUType = 0
+				romData, err = FileTools.UnmarshalNES20DBXMLToROMMap(string(xmlPayload), false)
+				hashTypeMatch = ProcessingTools.HASH_TYPE_SHA1
 			}
 
-			nesRom.Header20.ConsoleType = uint8(paramInt)
-		case "extended-console-type":
-			if nesRom.Header20 == nil {
-				println("Extended Console Type is only available in NES 2.0 headers")
-				os.Exit(1)
+			if err != nil {
+				panic(err)
 			}
 
-			if nesRom.Header20.ConsoleType != 3 {
-				println("Extended Console Type is only valid when Console Type is 3")
+			templateRom, err := ProcessingTools.MatchNESROM(nesRom, romData, hashTypeMatch, true)
+			if err != nil {
+				println(err.Error())
 				os.Exit(1)
 			}
 
-			if strConvErr != nil {
+			err = ProcessingTools.UpdateNESROM(nesRom, templateRom, false, false, true)
+			if err != nil {
 				panic(err)
 			}
+		}
 
-			if paramInt < 3 || paramInt > 15 {
-				println("Extended Console Type must be a value from 3-15")
-				os.Exit(1)
-			}
+		calculatedHeader, err := NESTool.EncodeNESROMHeader(nesRom, true, true)
+		if err != nil {
+			panic(err)
+		}
 
-			nesRom.Header20.ExtendedConsoleType = uint8(paramInt)
-		case "mapper-number":
-			if strConvErr != nil {
-				panic(err)
-			}
+		explanation, err := NESTool.ExplainNESHeader(existingHeader, calculatedHeader)
+		if err != nil {
+			panic(err)
+		}
 
-			if nesRom.Header20 != nil {
-				if paramInt > 4095 {
-					println("For NES 2.0 ROMs, mapper must be from 0-4095")
-					os.Exit(1)
-				}
-				nesRom.Header20.Mapper = uint16(paramInt)
-			} else if nesRom.Header10 != nil {
-				if paramInt > 255 {
-					println("For iNES ROMs, mapper must be from 0-255")
-					os.Exit(1)
-				}
-				nesRom.Header10.Mapper = uint8(paramInt)
-			} else {
-				println("No valid ROM found")
-				os.Exit(1)
-			}
+		fmt.Println(explanation)
 
+		os.Exit(0)
+	} else if *romSetCommand == "lint" {
+		romFiles := []string{*romToAnalyze}
+
+		if *romToAnalyze == "" {
+			println("Loading ROMs from: " + *romSetSourceDirectory)
+			foundFiles, err := FileTools.FindROMFilesRecursive(*romSetSourceDirectory)
 			if err != nil {
 				panic(err)
 			}
-		case "submapper-number":
-			if nesRom.Header20 == nil {
-				println("Submappers are only available in NES 2.0 headers")
-				os.Exit(1)
-			}
 
-			if strConvErr != nil {
+			romFiles = foundFiles
+		}
+
+		romsWithFindings := 0
+		highestSeverity := -1
+		for index := range romFiles {
+			println("Loading ROM: " + romFiles[index])
+			fileData, _, err := FileTools.LoadFile(romFiles[index], "")
+			if err != nil {
 				panic(err)
 			}
 
-			if paramInt > 15 {
-				println("Submapper must be from 0-15")
-				os.Exit(1)
+			// ROMs that can't be decoded are reported rather than stopping the run
+			lintResults := LintTool.LintNESROMFile(fileData, *romSetCleanHeaders)
+			if len(lintResults) == 0 {
+				continue
 			}
 
-			nesRom.Header20.SubMapper = uint8(paramInt)
-		case "cpu-ppu-timing":
-			if nesRom.Header20 == nil {
-				println("CPU/PPU Timing is only available in NES 2.0 headers")
-				os.Exit(1)
+			romsWithFindings++
+			fmt.Println(romFiles[index])
+			for resultIndex := range lintResults {
+				fmt.Println("  " + lintResults[resultIndex].String())
 			}
 
-			if strConvErr != nil {
-				panic(err)
+			if LintTool.GetHighestLintSeverity(lintResults) > highestSeverity {
+				highestSeverity = LintTool.GetHighestLintSeverity(lintResults)
 			}
+		}
 
-			if paramInt > 3 {
-				println("CPU/PPU Timing must be from 0-3")
-				os.Exit(1)
-			}
+		fmt.Println("Checked " + strconv.Itoa(len(romFiles)) + " ROMs, " + strconv.Itoa(romsWithFindings) + " with findings")
 
-			nesRom.Header20.CPUPPUTiming = uint8(paramInt)
-		case "vs-hardware-type":
-			if nesRom.Header20 == nil {
-				println("Vs. Hardware Type is only available in NES 2.0 headers")
-				os.Exit(1)
-			}
+		// Errors fail the operation, so this can be used in scripts
+		if highestSeverity >= int(LintTool.LINT_SEVERITY_ERROR) {
+			os.Exit(1)
+		}
 
-			if nesRom.Header20.ConsoleType != 1 {
-				println("Vs. Hardware Type is only valid when Console Type is 1")
+		os.Exit(0)
+	} else if *romSetCommand == "editheader" {
+		fieldValues, err := FileTools.LoadHeaderSpec(*headerSpecFile)
+		if err != nil {
+			panic(err)
+		}
+
+		for fieldName := range fieldValues {
+			if !NESTool.IsNESHeaderFieldName(fieldName) {
+				println("Unknown header field in " + *headerSpecFile + ": " + fieldName)
 				os.Exit(1)
 			}
+		}
+
+		if *inputRom != "" {
+			inputFilePath := filepath.Dir(*inputRom)
+			outputFileName := filepath.Base(*outputRom)
+			outputFilePath := filepath.Dir(*outputRom)
 
-			if strConvErr != nil {
+			nesRom, err := FileTools.LoadROM(*inputRom, true, true, inputFilePath, false)
+			if err != nil {
 				panic(err)
 			}
 
-			if paramInt > 15 {
-				println("Vs. Hardware Type must be from 0-15")
+			if nesRom == nil {
+				println("Unable to read ROM: " + *inputRom)
 				os.Exit(1)
 			}
 
-			nesRom.Header20.VsHardwareType = uint8(paramInt)
-		case "vs-ppu-type":
-			if nesRom.Header20 == nil {
-				println("Vs. PPU Type is only available in NES 2.0 headers")
-				os.Exit(1)
+			if *romSetCleanHeaders {
+				NESTool.CleanNESROMHeader(nesRom)
 			}
 
-			if nesRom.Header20.ConsoleType != 1 {
-				println("Vs. PPU Type is only valid when Console Type is 1")
+			err = NESTool.ApplyNESROMHeaderFields(nesRom, fieldValues)
+			if err != nil {
+				println(err.Error())
 				os.Exit(1)
 			}
 
-			if strConvErr != nil {
+			nesRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+			nesRom.RelativePath = outputFileName
+
+			err = FileTools.WriteROM(nesRom, true, false, true, outputFilePath)
+			if err != nil {
 				panic(err)
 			}
 
-			if paramInt > 15 {
-				println("Vs. PPU Type must be from 0-15")
-				os.Exit(1)
-			}
+			println("Finished writing " + *outputRom)
+			os.Exit(0)
+		}
 
-			nesRom.Header20.VsPPUType = uint8(paramInt)
-		case "default-expansion":
-			if nesRom.Header20 == nil {
-				println("Default Hardware Expansion is only available in NES 2.0 headers")
-				os.Exit(1)
+		println("Loading ROMs from: " + *romSetSourceDirectory)
+		nesRoms, err := FileTools.LoadROMRecursive(*romSetSourceDirectory, true, true, *romSetPrintChecksums)
+		if err != nil {
+			panic(err)
+		}
+
+		for index := range nesRoms {
+			if *romSetCleanHeaders {
+				NESTool.CleanNESROMHeader(nesRoms[index])
 			}
 
-			if strConvErr != nil {
-				panic(err)
+			err = NESTool.ApplyNESROMHeaderFields(nesRoms[index], fieldValues)
+			if err != nil {
+				println("Unable to edit ROM: " + nesRoms[index].Filename)
+				println(err.Error())
+				continue
 			}
 
-			if paramInt > 63 {
-				println("Default Hardware Expansion must be from 0-63")
-				os.Exit(1)
+			println("Writing NES ROM: " + filepath.Join(*romOutputBasePath, nesRoms[index].RelativePath))
+			err = FileTools.WriteROM(nesRoms[index], true, false, true, *romOutputBasePath)
+			if err != nil {
+				println("Error writing ROM: " + filepath.Join(*romOutputBasePath, nesRoms[index].RelativePath))
+				println(err.Error())
 			}
+		}
 
-			nesRom.Header20.DefaultExpansion = uint8(paramInt)
-		case "vs-unisystem":
-			if nesRom.Header10 == nil {
-				println("Vs. Unisystem is only available in iNES headers")
-				os.Exit(1)
+		os.Exit(0)
+	} else if *romSetCommand == "unif-to-nes" {
+		inputFilePath := filepath.Dir(*inputRom)
+		outputFileName := filepath.Base(*outputRom)
+		outputFilePath := filepath.Dir(*outputRom)
+
+		unifRom, err := FileTools.LoadUNIF(*inputRom, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
+
+		if unifRom == nil {
+			println("Unable to read UNIF ROM: " + *inputRom)
+			os.Exit(1)
+		}
+
+		unifRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		unifRom.RelativePath = outputFileName
+
+		err = FileTools.WriteROM(unifRom, false, false, false, outputFilePath)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "nes-to-unif" {
+		inputFilePath := filepath.Dir(*inputRom)
+
+		nesRom, err := FileTools.LoadROM(*inputRom, true, false, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
+
+		if nesRom == nil {
+			println("Unable to read ROM: " + *inputRom)
+			os.Exit(1)
+		}
+
+		if *romSetCleanHeaders {
+			NESTool.CleanNESROMHeader(nesRom)
+		}
+
+		unifBytes, err := UNIFTool.EncodeUNIFROM(nesRom)
+		if err != nil {
+			panic(err)
+		}
+
+		err = FileTools.WriteBytesToFile(unifBytes, *outputRom)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "upgrade-header" {
+		inputFilePath := filepath.Dir(*inputRom)
+		outputFileName := filepath.Base(*outputRom)
+		outputFilePath := filepath.Dir(*outputRom)
+
+		nesRom, err := FileTools.LoadROM(*inputRom, true, true, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
+
+		if nesRom == nil {
+			println("Unable to read ROM: " + *inputRom)
+			os.Exit(1)
+		}
+
+		if *romSetCleanHeaders {
+			NESTool.CleanNESROMHeader(nesRom)
+		}
+
+		guessedFields, err := NESTool.UpgradeNESROMHeader(nesRom, *romSetUpgradeMiscROM)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(guessedFields) > 0 {
+			fmt.Println("Guessed fields:")
+			for index := range guessedFields {
+				fmt.Println("  " + guessedFields[index])
 			}
+		}
 
-			var isVsUnisystem bool
+		nesRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		nesRom.RelativePath = outputFileName
 
-			if *romFieldValue == "true" {
-				isVsUnisystem = true
-			} else if *romFieldValue == "false" {
-				isVsUnisystem = false
-			} else {
-				println("vs-unisystem must be one of {true|false}")
-				os.Exit(1)
+		err = FileTools.WriteROM(nesRom, false, false, true, outputFilePath)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "downgrade-header" {
+		inputFilePath := filepath.Dir(*inputRom)
+		outputFileName := filepath.Base(*outputRom)
+		outputFilePath := filepath.Dir(*outputRom)
+
+		nesRom, err := FileTools.LoadROM(*inputRom, false, true, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
+
+		if nesRom == nil {
+			println("Unable to read ROM: " + *inputRom)
+			os.Exit(1)
+		}
+
+		lostFields, err := NESTool.DowngradeNESROMHeader(nesRom)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(lostFields) > 0 {
+			fmt.Println("Fields that can't be represented in iNES:")
+			for index := range lostFields {
+				fmt.Println("  " + lostFields[index])
 			}
+		}
 
-			nesRom.Header10.VsUnisystem = isVsUnisystem
-		case "playchoice-10":
-			if nesRom.Header10 == nil {
-				println("PlayChoice 10 is only available in iNES headers")
-				os.Exit(1)
+		nesRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		nesRom.RelativePath = outputFileName
+
+		err = FileTools.WriteROM(nesRom, true, false, true, outputFilePath)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "split" {
+		inputFilePath := filepath.Dir(*inputRom)
+
+		nesRom, err := FileTools.LoadROM(*inputRom, false, true, inputFilePath, false)
+		if err != nil {
+			panic(err)
+		}
+
+		if nesRom == nil {
+			println("Unable to read ROM: " + *inputRom)
+			os.Exit(1)
+		}
+
+		err = os.MkdirAll(filepath.Dir(*manifestFile), os.ModeDir|0770)
+		if err != nil {
+			panic(err)
+		}
+
+		err = FileTools.SplitROM(nesRom, *manifestFile)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *manifestFile)
+	} else if *romSetCommand == "assemble" {
+		outputFileName := filepath.Base(*outputRom)
+		outputFilePath := filepath.Dir(*outputRom)
+
+		println("Loading manifest: " + *manifestFile)
+		nesRom, err := FileTools.AssembleROM(*manifestFile)
+		if err != nil {
+			panic(err)
+		}
+
+		nesRom.Name = strings.TrimSuffix(outputFileName, filepath.Ext(outputFileName))
+		nesRom.RelativePath = outputFileName
+
+		err = FileTools.WriteROM(nesRom, false, false, true, outputFilePath)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Finished writing " + *outputRom)
+	} else if *romSetCommand == "db-diff" {
+		println("Loading old database from: " + databaseSources[0].String())
+		oldRoms, _, err := FileTools.LoadDatabase(databaseSources[0], true, false, true)
+		if err != nil {
+			panic(err)
+		}
+
+		println("Loading new database from: " + databaseSources[1].String())
+		newRoms, _, err := FileTools.LoadDatabase(databaseSources[1], true, false, true)
+		if err != nil {
+			panic(err)
+		}
+
+		diff, err := FileTools.DiffDatabases(oldRoms, newRoms)
+		if err != nil {
+			panic(err)
+		}
+
+		if *romInfoOutput == "json" {
+			diffPayload, err := FileTools.MarshalDatabaseDiff(diff)
+			if err != nil {
+				panic(err)
 			}
 
-			var isPlayChoice10 bool
+			fmt.Println(diffPayload)
+		} else {
+			fmt.Print(diff.String())
+		}
 
-			if *romFieldValue == "true" {
-				isPlayChoice10 = true
-			} else if *romFieldValue == "false" {
-				isPlayChoice10 = false
-			} else {
-				println("playchoice-10 must be one of {true|false}")
-				os.Exit(1)
+		os.Exit(0)
+	} else if *romSetCommand == "db-check" {
+		checkedEntries := 0
+		sourcesWithFindings := 0
+		highestSeverity := -1
+		for index := range databaseSources {
+			println("Checking database: " + databaseSources[index].String())
+			checkResults, sourceEntries, err := FileTools.CheckDatabase(databaseSources[index])
+			if err != nil {
+				panic(err)
 			}
 
-			nesRom.Header10.PlayChoice10 = isPlayChoice10
-		case "tv-system":
-			if nesRom.Header10 == nil {
-				println("TV System is only available in iNES headers")
-				os.Exit(1)
+			checkedEntries = checkedEntries + sourceEntries
+			if len(checkResults) == 0 {
+				continue
 			}
 
-			var tvSystem bool
+			sourcesWithFindings++
+			fmt.Println(databaseSources[index].String())
+			for resultIndex := range checkResults {
+				fmt.Println("  " + checkResults[resultIndex].String())
+			}
 
-			if *romFieldValue == "pal" {
-				tvSystem = true
-			} else if *romFieldValue == "ntsc" {
-				tvSystem = false
-			} else {
-				println("tv-system must be one of {ntsc|pal}")
-				os.Exit(1)
+			if LintTool.GetHighestLintSeverity(checkResults) > highestSeverity {
+				highestSeverity = LintTool.GetHighestLintSeverity(checkResults)
 			}
+		}
 
-			nesRom.Header10.TVSystem = tvSystem
-		default:
-			printUsage()
+		fmt.Println("Checked " + strconv.Itoa(checkedEntries) + " entries in " + strconv.Itoa(len(databaseSources)) + " databases, " + strconv.Itoa(sourcesWithFindings) + " with findings")
+
+		// Errors fail the operation, so this can be used in scripts
+		if highestSeverity >= int(LintTool.LINT_SEVERITY_ERROR) {
 			os.Exit(1)
 		}
 
-		nesRom.Name = outputFileName[:strings.LastIndex(outputFileName, ".")]
-		nesRom.RelativePath = outputFileName
+		os.Exit(0)
+	}
+}
 
-		err = FileTools.WriteROM(nesRom, true, false, true, outputFilePath)
+// A list of databases from repeated -db options
+type databaseSourceList []*FileTools.DatabaseSource
+
+func (sourceList *databaseSourceList) String() string {
+	sourceStrings := make([]string, 0, len(*sourceList))
+	for index := range *sourceList {
+		sourceStrings = append(sourceStrings, (*sourceList)[index].String())
+	}
+
+	return strings.Join(sourceStrings, ",")
+}
+
+func (sourceList *databaseSourceList) Set(sourceSpec string) error {
+	source, err := FileTools.ParseDatabaseSource(sourceSpec)
+	if err != nil {
+		return err
+	}
+
+	*sourceList = append(*sourceList, source)
+	return nil
+}
+
+// Print a single ROM as full JSON, or as a CSV table with one row
+func printStructuredROMInfo(rom *NESTool.NESROM, outputFormat string) {
+	romInfo, err := FileTools.GetNESROMInfo(rom)
+	if err != nil {
+		panic(err)
+	}
+
+	if outputFormat == "json" {
+		infoPayload, err := FileTools.MarshalNESROMInfo(romInfo)
 		if err != nil {
 			panic(err)
 		}
 
-		println("Finished writing " + *outputRom)
+		fmt.Println(infoPayload)
+		return
+	}
+
+	csvWriter := csv.NewWriter(os.Stdout)
+	err = csvWriter.Write(FileTools.ROM_INFO_CSV_COLUMNS)
+	if err != nil {
+		panic(err)
+	}
+
+	err = csvWriter.Write(FileTools.GetNESROMInfoRow(romInfo).CSVRecord())
+	if err != nil {
+		panic(err)
+	}
+
+	csvWriter.Flush()
+	if csvWriter.Error() != nil {
+		panic(csvWriter.Error())
 	}
 }
 
@@ -824,7 +1265,7 @@ func printUsage() {
 
 // Show field edit usage options.
 func printFieldEditOptions() {
-	println("Valid fields for editing.")
+	println("Valid fields for editing with editheaderfield, or in an editheader spec file.")
 	println("Descriptions marked with a * are NES 2.0-only fields.")
 	println("Descriptions marked with a ! are iNES-only fields.")
 	println("Values for assigned numbers can be found at:")
@@ -848,15 +1289,21 @@ func printFieldEditOptions() {
 	println("four-screen           :   Game uses four-screen mode {true|false}")
 	println("has-battery           :   Whether the game has a battery")
 	println("                          for save RAM {true|false}")
-	println("console-type          : * Which console type the ROM is intended for (0-3)")
-	println("extended-console-type : * Extended console type (3-15)")
+	println("console-type          : * Which console type the ROM is intended for")
+	println("                          (0-3, or a name such as vs-system)")
+	println("extended-console-type : * Extended console type")
+	println("                          (3-15, or a name such as vt03)")
 	println("mapper-number         :   The mapper number for the ROM to use")
 	println("                          (0-4095 for NES 2.0 ROMs and 0-255 for iNES ROMs)")
 	println("submapper-number      : * The submapper number for the ROM to use (0-15)")
-	println("cpu-ppu-timing        : * The CPU/PPU timing mode to use (0-3)")
-	println("vs-hardware-type      : * The hardware type of the Vs. system (0-15)")
-	println("vs-ppu-type           : * The PPU in the Vs. system (0-15)")
-	println("default-expansion     : * The default hardware expansion to use (0-63)")
+	println("cpu-ppu-timing        : * The CPU/PPU timing mode to use")
+	println("                          (0-3, or {ntsc|pal|multi-region|dendy})")
+	println("vs-hardware-type      : * The hardware type of the Vs. system")
+	println("                          (0-15, or a name such as dual-system)")
+	println("vs-ppu-type           : * The PPU in the Vs. system")
+	println("                          (0-15, or a name such as rp2c04-0003)")
+	println("default-expansion     : * The default hardware expansion to use")
+	println("                          (0-63, or a name such as zapper)")
 	println("vs-unisystem          : ! Whether the ROM is for a")
 	println("                          Vs. system {true|false}")
 	println("playchoice-10         : ! Whether the ROM is for a")
@@ -0,0 +1,266 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"strconv"
+)
+
+// Convert an iNES header to an NES 2.0 header.  Everything iNES can
+// represent carries over, and NES 2.0 fields that iNES has no place
+// for are filled in from mapper defaults.  Data after the CHR ROM is
+// only described as a miscellaneous ROM if keepMiscROM is set, since
+// it's as likely to be overdump or padding.  Returns a description of each
+// field that had to be guessed.
+func UpgradeNESROMHeader(rom *NESROM, keepMiscROM bool) ([]string, error) {
+	if rom.Header20 != nil {
+		return nil, &NESROMError{Text: "ROM already has an NES 2.0 header."}
+	}
+
+	if rom.Header10 == nil {
+		return nil, &NESROMError{Text: "No iNES header to upgrade."}
+	}
+
+	guessedFields := make([]string, 0)
+	header10 := rom.Header10
+	header20 := &NES20Header{}
+
+	header20.Mapper = uint16(header10.Mapper)
+	header20.MirroringType = header10.MirroringType
+	header20.Battery = header10.Battery
+	header20.Trainer = header10.Trainer
+	header20.FourScreen = header10.FourScreen
+
+	// iNES has no submappers, so the original board is the safest guess
+	header20.SubMapper = 0
+	guessedFields = append(guessedFields, "Submapper: 0 (iNES has no submappers)")
+
+	if header10.VsUnisystem {
+		header20.ConsoleType = 1
+		header20.VsPPUType = 0
+		header20.VsHardwareType = 0
+		guessedFields = append(guessedFields, "Vs. PPU Type: 0 (iNES only says the ROM is for a Vs. System)")
+		guessedFields = append(guessedFields, "Vs. Hardware Type: 0 (iNES only says the ROM is for a Vs. System)")
+	} else if header10.PlayChoice10 {
+		header20.ConsoleType = 2
+	}
+
+	if header10.TVSystem {
+		header20.CPUPPUTiming = 1
+	} else {
+		header20.CPUPPUTiming = 0
+		guessedFields = append(guessedFields, "CPU/PPU Timing: NTSC (the iNES TV system flag is rarely set)")
+	}
+
+	// Mapper defaults only come from the registry, so unknown mappers
+	// fall back to what the iNES header says
+	mapperInfo := GetMapperInfo(uint16(header10.Mapper))
+	if mapperInfo == nil {
+		mapperInfo = &MapperInfo{}
+	}
+
+	if mapperInfo.EEPROMSize > 0 && header10.Battery {
+		header20.PRGNVRAMSize = mapperInfo.EEPROMSize
+		guessedFields = append(guessedFields, "PRG NVRAM Size: "+strconv.Itoa(int(header20.PRGNVRAMSize))+" (mapper default EEPROM)")
+	} else if header10.Battery {
+		header20.PRGNVRAMSize = getNES20RAMSizeForINES(header10.PRGRAMSize)
+		guessedFields = append(guessedFields, "PRG NVRAM Size: "+strconv.Itoa(int(header20.PRGNVRAMSize))+" (battery present, so work RAM is assumed to be battery-backed)")
+	} else if header10.PRGRAMSize > 0 || mapperInfo.PRGRAMTypical {
+		header20.PRGRAMSize = getNES20RAMSizeForINES(header10.PRGRAMSize)
+		guessedFields = append(guessedFields, "PRG RAM Size: "+strconv.Itoa(int(header20.PRGRAMSize))+" (mapper default work RAM)")
+	}
+
+	if header10.CHRROMSize == 0 {
+		header20.CHRRAMSize = GetDefaultCHRRAMSizeForMapper(uint16(header10.Mapper))
+
+		guessedFields = append(guessedFields, "CHR RAM Size: "+strconv.Itoa(int(header20.CHRRAMSize))+" (no CHR ROM, so the mapper default CHR RAM is assumed)")
+	}
+
+	// iNES decoding drops anything past the CHR ROM, but NES 2.0 can
+	// describe it as a miscellaneous ROM
+	romSize := header10.PRGROMCalculatedSize + header10.CHRROMCalculatedSize
+	if uint64(len(rom.ROMData)) > romSize {
+		trailingSize := strconv.FormatUint(uint64(len(rom.ROMData))-romSize, 10)
+		if keepMiscROM {
+			rom.MiscROMData = rom.ROMData[romSize:]
+			header20.MiscROMs = 1
+			guessedFields = append(guessedFields, "Misc ROMs: 1 ("+trailingSize+" bytes of data follow the CHR ROM)")
+		} else {
+			guessedFields = append(guessedFields, "Misc ROMs: 0 ("+trailingSize+" bytes of data follow the CHR ROM, but may be overdump or padding, so they're left undescribed)")
+		}
+	}
+
+	rom.Header20 = header20
+	rom.Header10 = nil
+	rom.HeaderType = NES_HEADER_TYPE_NES20
+
+	err := UpdateSizes(rom, PRG_CANONICAL_SIZE_ROM, CHR_CANONICAL_SIZE_ROM)
+	if err != nil {
+		return nil, err
+	}
+
+	err = UpdateChecksums(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	return guessedFields, nil
+}
+
+// iNES PRG RAM sizes are in 8 KiB units, with 0 meaning 8 KiB for
+// compatibility.  NES 2.0 sizes are shift counts of 64 bytes.
+func getNES20RAMSizeForINES(inesRamSize uint8) uint8 {
+	if inesRamSize <= 1 {
+		return 7
+	}
+
+	var shiftCount uint8 = 7
+	for ramSize := uint64(inesRamSize); ramSize > 1 && shiftCount < 15; ramSize = ramSize >> 1 {
+		shiftCount++
+	}
+
+	return shiftCount
+}
+
+// Convert an NES 2.0 header to an iNES header.  Fails if the mapper or
+// ROM sizes can't be represented at all, and otherwise returns a
+// description of each NES 2.0 field that was lost in the conversion.
+func DowngradeNESROMHeader(rom *NESROM) ([]string, error) {
+	if rom.Header20 == nil {
+		return nil, &NESROMError{Text: "No NES 2.0 header to downgrade."}
+	}
+
+	header20 := rom.Header20
+
+	if header20.Mapper > 255 {
+		return nil, &NESROMError{Text: "Mapper " + strconv.Itoa(int(header20.Mapper)) + " can't be represented in an iNES header."}
+	}
+
+	if header20.PRGROMSize == 0 && header20.PRGROMCalculatedSize > 0 {
+		return nil, &NESROMError{Text: "PRG ROM sizes in exponent notation can't be represented in an iNES header."}
+	}
+
+	if header20.CHRROMSize == 0 && header20.CHRROMCalculatedSize > 0 {
+		return nil, &NESROMError{Text: "CHR ROM sizes in exponent notation can't be represented in an iNES header."}
+	}
+
+	if header20.PRGROMSize > 255 || header20.CHRROMSize > 255 {
+		return nil, &NESROMError{Text: "ROM sizes over 255 units can't be represented in an iNES header."}
+	}
+
+	lostFields := make([]string, 0)
+	header10 := &NES10Header{}
+
+	header10.PRGROMSize = uint8(header20.PRGROMSize)
+	header10.CHRROMSize = uint8(header20.CHRROMSize)
+	header10.Mapper = uint8(header20.Mapper)
+	header10.MirroringType = header20.MirroringType
+	header10.Battery = header20.Battery
+	header10.Trainer = header20.Trainer
+	header10.FourScreen = header20.FourScreen
+
+	if header20.SubMapper != 0 {
+		lostFields = append(lostFields, "Submapper: "+strconv.Itoa(int(header20.SubMapper)))
+	}
+
+	switch header20.ConsoleType {
+	case 1:
+		header10.VsUnisystem = true
+		if header20.VsPPUType != 0 {
+			lostFields = append(lostFields, "Vs. PPU Type: "+VsPPUType(header20.VsPPUType).String())
+		}
+
+		if header20.VsHardwareType != 0 {
+			lostFields = append(lostFields, "Vs. Hardware Type: "+VsHardwareType(header20.VsHardwareType).String())
+		}
+	case 2:
+		header10.PlayChoice10 = true
+	case 3:
+		lostFields = append(lostFields, "Console Type: "+ExtendedConsoleType(header20.ExtendedConsoleType).String())
+	}
+
+	switch header20.CPUPPUTiming {
+	case 1:
+		header10.TVSystem = true
+	case 2:
+		lostFields = append(lostFields, "CPU/PPU Timing: Multiple-region (written as NTSC)")
+	case 3:
+		header10.TVSystem = true
+		lostFields = append(lostFields, "CPU/PPU Timing: UMC 6527P (\"Dendy\") (written as PAL)")
+	}
+
+	// iNES only has a single PRG RAM size, in 8 KiB units
+	var prgRamBytes uint64 = 0
+	if header20.PRGRAMSize > 0 {
+		prgRamBytes = prgRamBytes + (64 << header20.PRGRAMSize)
+	}
+
+	if header20.PRGNVRAMSize > 0 {
+		prgRamBytes = prgRamBytes + (64 << header20.PRGNVRAMSize)
+	}
+
+	if header20.PRGRAMSize > 0 && header20.PRGNVRAMSize > 0 {
+		lostFields = append(lostFields, "PRG RAM/PRG NVRAM split: "+strconv.FormatUint(64<<header20.PRGRAMSize, 10)+" bytes of RAM and "+strconv.FormatUint(64<<header20.PRGNVRAMSize, 10)+" bytes of NVRAM")
+	}
+
+	if prgRamBytes%8192 != 0 {
+		lostFields = append(lostFields, "PRG RAM Size: "+strconv.FormatUint(prgRamBytes, 10)+" bytes (rounded up to 8 KiB units)")
+	} else if prgRamBytes == 0 {
+		lostFields = append(lostFields, "PRG RAM Size: 0 bytes (iNES readers assume 8 KiB)")
+	}
+
+	if (prgRamBytes+8191)/8192 > 255 {
+		header10.PRGRAMSize = 255
+	} else {
+		header10.PRGRAMSize = uint8((prgRamBytes + 8191) / 8192)
+	}
+
+	if header20.CHRRAMSize != 0 && header20.CHRRAMSize != 7 {
+		lostFields = append(lostFields, "CHR RAM Size: "+strconv.FormatUint(64<<header20.CHRRAMSize, 10)+" bytes (iNES readers assume 8 KiB)")
+	}
+
+	if header20.CHRNVRAMSize != 0 {
+		lostFields = append(lostFields, "CHR NVRAM Size: "+strconv.FormatUint(64<<header20.CHRNVRAMSize, 10)+" bytes")
+	}
+
+	if header20.MiscROMs != 0 {
+		lostFields = append(lostFields, "Misc ROMs: "+strconv.Itoa(int(header20.MiscROMs))+" (the data is kept after the CHR ROM, but iNES can't describe it)")
+	}
+
+	if header20.DefaultExpansion != 0 {
+		lostFields = append(lostFields, "Default Expansion Device: "+DefaultExpansion(header20.DefaultExpansion).String())
+	}
+
+	rom.Header10 = header10
+	rom.Header20 = nil
+	rom.HeaderType = NES_HEADER_TYPE_INES
+
+	err := UpdateSizes(rom, PRG_CANONICAL_SIZE_FACTORED, CHR_CANONICAL_SIZE_FACTORED)
+	if err != nil {
+		return nil, err
+	}
+
+	err = UpdateChecksums(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	return lostFields, nil
+}
@@ -0,0 +1,575 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Enumerated NES 2.0 header values.  Each type can be parsed from either
+// its number or its short name (e.g. "zapper", "dendy", "rp2c04-0003"),
+// and has a String() method giving a human-readable description.
+// https://wiki.nesdev.com/w/index.php/NES_2.0
+
+package NESTool
+
+import (
+	"strconv"
+	"strings"
+)
+
+type ConsoleType uint8
+type ExtendedConsoleType uint8
+type VsPPUType uint8
+type VsHardwareType uint8
+type CPUPPUTiming uint8
+type DefaultExpansion uint8
+
+var (
+	CONSOLE_TYPE_NES          ConsoleType = 0
+	CONSOLE_TYPE_VS_SYSTEM    ConsoleType = 1
+	CONSOLE_TYPE_PLAYCHOICE10 ConsoleType = 2
+	CONSOLE_TYPE_EXTENDED     ConsoleType = 3
+)
+
+var (
+	EXTENDED_CONSOLE_TYPE_NES               ExtendedConsoleType = 0
+	EXTENDED_CONSOLE_TYPE_VS_SYSTEM         ExtendedConsoleType = 1
+	EXTENDED_CONSOLE_TYPE_PLAYCHOICE10      ExtendedConsoleType = 2
+	EXTENDED_CONSOLE_TYPE_DECIMAL_FAMICLONE ExtendedConsoleType = 3
+	EXTENDED_CONSOLE_TYPE_VT01_MONOCHROME   ExtendedConsoleType = 4
+	EXTENDED_CONSOLE_TYPE_VT01_STN          ExtendedConsoleType = 5
+	EXTENDED_CONSOLE_TYPE_VT02              ExtendedConsoleType = 6
+	EXTENDED_CONSOLE_TYPE_VT03              ExtendedConsoleType = 7
+	EXTENDED_CONSOLE_TYPE_VT09              ExtendedConsoleType = 8
+	EXTENDED_CONSOLE_TYPE_VT32              ExtendedConsoleType = 9
+	EXTENDED_CONSOLE_TYPE_VT369             ExtendedConsoleType = 10
+	EXTENDED_CONSOLE_TYPE_UM6578            ExtendedConsoleType = 11
+)
+
+var (
+	VS_PPU_TYPE_RP2C03B     VsPPUType = 0
+	VS_PPU_TYPE_RP2C03G     VsPPUType = 1
+	VS_PPU_TYPE_RP2C04_0001 VsPPUType = 2
+	VS_PPU_TYPE_RP2C04_0002 VsPPUType = 3
+	VS_PPU_TYPE_RP2C04_0003 VsPPUType = 4
+	VS_PPU_TYPE_RP2C04_0004 VsPPUType = 5
+	VS_PPU_TYPE_RC2C03B     VsPPUType = 6
+	VS_PPU_TYPE_RC2C03C     VsPPUType = 7
+	VS_PPU_TYPE_RC2C05_01   VsPPUType = 8
+	VS_PPU_TYPE_RC2C05_02   VsPPUType = 9
+	VS_PPU_TYPE_RC2C05_03   VsPPUType = 10
+	VS_PPU_TYPE_RC2C05_04   VsPPUType = 11
+	VS_PPU_TYPE_RC2C05_05   VsPPUType = 12
+)
+
+var (
+	VS_HARDWARE_TYPE_UNISYSTEM               VsHardwareType = 0
+	VS_HARDWARE_TYPE_UNISYSTEM_RBI_BASEBALL  VsHardwareType = 1
+	VS_HARDWARE_TYPE_UNISYSTEM_TKO_BOXING    VsHardwareType = 2
+	VS_HARDWARE_TYPE_UNISYSTEM_SUPER_XEVIOUS VsHardwareType = 3
+	VS_HARDWARE_TYPE_UNISYSTEM_ICE_CLIMBER   VsHardwareType = 4
+	VS_HARDWARE_TYPE_DUAL_SYSTEM             VsHardwareType = 5
+	VS_HARDWARE_TYPE_DUAL_SYSTEM_RAID        VsHardwareType = 6
+)
+
+var (
+	CPU_PPU_TIMING_NTSC         CPUPPUTiming = 0
+	CPU_PPU_TIMING_PAL          CPUPPUTiming = 1
+	CPU_PPU_TIMING_MULTI_REGION CPUPPUTiming = 2
+	CPU_PPU_TIMING_DENDY        CPUPPUTiming = 3
+)
+
+var (
+	DEFAULT_EXPANSION_UNSPECIFIED       DefaultExpansion = 0
+	DEFAULT_EXPANSION_STANDARD          DefaultExpansion = 1
+	DEFAULT_EXPANSION_FOUR_SCORE        DefaultExpansion = 2
+	DEFAULT_EXPANSION_FOUR_PLAYERS      DefaultExpansion = 3
+	DEFAULT_EXPANSION_VS_SYSTEM         DefaultExpansion = 4
+	DEFAULT_EXPANSION_VS_SYSTEM_REVERSE DefaultExpansion = 5
+	DEFAULT_EXPANSION_VS_PINBALL        DefaultExpansion = 6
+	DEFAULT_EXPANSION_VS_ZAPPER         DefaultExpansion = 7
+	DEFAULT_EXPANSION_ZAPPER            DefaultExpansion = 8
+	DEFAULT_EXPANSION_TWO_ZAPPERS       DefaultExpansion = 9
+	DEFAULT_EXPANSION_POWER_PAD_A       DefaultExpansion = 11
+	DEFAULT_EXPANSION_POWER_PAD_B       DefaultExpansion = 12
+	DEFAULT_EXPANSION_VAUS_NES          DefaultExpansion = 15
+	DEFAULT_EXPANSION_VAUS_FAMICOM      DefaultExpansion = 16
+	DEFAULT_EXPANSION_FAMILY_BASIC      DefaultExpansion = 35
+	DEFAULT_EXPANSION_SNES_MOUSE        DefaultExpansion = 41
+	DEFAULT_EXPANSION_MULTICART         DefaultExpansion = 42
+)
+
+// Short names, indexed by value
+var consoleTypeNames = []string{
+	"nes",
+	"vs-system",
+	"playchoice-10",
+	"extended",
+}
+
+var extendedConsoleTypeNames = []string{
+	"nes",
+	"vs-system",
+	"playchoice-10",
+	"decimal-famiclone",
+	"vt01-monochrome",
+	"vt01-stn",
+	"vt02",
+	"vt03",
+	"vt09",
+	"vt32",
+	"vt369",
+	"um6578",
+}
+
+var vsPPUTypeNames = []string{
+	"rp2c03b",
+	"rp2c03g",
+	"rp2c04-0001",
+	"rp2c04-0002",
+	"rp2c04-0003",
+	"rp2c04-0004",
+	"rc2c03b",
+	"rc2c03c",
+	"rc2c05-01",
+	"rc2c05-02",
+	"rc2c05-03",
+	"rc2c05-04",
+	"rc2c05-05",
+}
+
+var vsHardwareTypeNames = []string{
+	"unisystem",
+	"unisystem-rbi-baseball",
+	"unisystem-tko-boxing",
+	"unisystem-super-xevious",
+	"unisystem-ice-climber",
+	"dual-system",
+	"dual-system-raid-on-bungeling-bay",
+}
+
+var cpuPPUTimingNames = []string{
+	"ntsc",
+	"pal",
+	"multi-region",
+	"dendy",
+}
+
+var defaultExpansionNames = []string{
+	"unspecified",
+	"standard",
+	"four-score",
+	"four-players-adapter",
+	"vs-system",
+	"vs-system-reversed",
+	"vs-pinball",
+	"vs-zapper",
+	"zapper",
+	"two-zappers",
+	"bandai-hyper-shot",
+	"power-pad-a",
+	"power-pad-b",
+	"family-trainer-a",
+	"family-trainer-b",
+	"vaus-nes",
+	"vaus-famicom",
+	"two-vaus-data-recorder",
+	"konami-hyper-shot",
+	"pachinko",
+	"punching-bag",
+	"jissen-mahjong",
+	"party-tap",
+	"oeka-kids",
+	"barcode-battler",
+	"miracle-piano",
+	"pokkun-moguraa",
+	"top-rider",
+	"double-fisted",
+	"famicom-3d",
+	"doremikko",
+	"rob-gyro",
+	"data-recorder",
+	"turbo-file",
+	"storage-battle-box",
+	"family-basic-keyboard",
+	"pec-586-keyboard",
+	"bit-79-keyboard",
+	"subor-keyboard",
+	"subor-keyboard-mouse-3x8",
+	"subor-keyboard-mouse-24",
+	"snes-mouse",
+	"multicart",
+	"two-snes-controllers",
+	"racermate",
+	"u-force",
+	"rob-stack-up",
+	"city-patrolman",
+	"sharp-c1",
+	"swapped-controller",
+	"sudoku-pad",
+	"abl-pinball",
+	"golden-nugget-casino",
+}
+
+func ParseConsoleType(value string) (ConsoleType, error) {
+	enumValue, err := parseHeaderEnum("Console Type", value, consoleTypeNames, 3)
+	return ConsoleType(enumValue), err
+}
+
+func ParseExtendedConsoleType(value string) (ExtendedConsoleType, error) {
+	enumValue, err := parseHeaderEnum("Extended Console Type", value, extendedConsoleTypeNames, 15)
+	return ExtendedConsoleType(enumValue), err
+}
+
+func ParseVsPPUType(value string) (VsPPUType, error) {
+	enumValue, err := parseHeaderEnum("Vs. PPU Type", value, vsPPUTypeNames, 15)
+	return VsPPUType(enumValue), err
+}
+
+func ParseVsHardwareType(value string) (VsHardwareType, error) {
+	enumValue, err := parseHeaderEnum("Vs. Hardware Type", value, vsHardwareTypeNames, 15)
+	return VsHardwareType(enumValue), err
+}
+
+func ParseCPUPPUTiming(value string) (CPUPPUTiming, error) {
+	enumValue, err := parseHeaderEnum("CPU/PPU Timing", value, cpuPPUTimingNames, 3)
+	return CPUPPUTiming(enumValue), err
+}
+
+func ParseDefaultExpansion(value string) (DefaultExpansion, error) {
+	enumValue, err := parseHeaderEnum("Default Hardware Expansion", value, defaultExpansionNames, 63)
+	return DefaultExpansion(enumValue), err
+}
+
+// Short names, falling back to the number for values without one
+func (consoleType ConsoleType) Name() string {
+	return getHeaderEnumName(uint8(consoleType), consoleTypeNames)
+}
+
+func (extendedConsoleType ExtendedConsoleType) Name() string {
+	return getHeaderEnumName(uint8(extendedConsoleType), extendedConsoleTypeNames)
+}
+
+func (vsPpuType VsPPUType) Name() string {
+	return getHeaderEnumName(uint8(vsPpuType), vsPPUTypeNames)
+}
+
+func (vsHardwareType VsHardwareType) Name() string {
+	return getHeaderEnumName(uint8(vsHardwareType), vsHardwareTypeNames)
+}
+
+func (cpuPpuTiming CPUPPUTiming) Name() string {
+	return getHeaderEnumName(uint8(cpuPpuTiming), cpuPPUTimingNames)
+}
+
+func (defaultExpansion DefaultExpansion) Name() string {
+	return getHeaderEnumName(uint8(defaultExpansion), defaultExpansionNames)
+}
+
+// These let the XML readers accept names as well as numbers.  There are
+// deliberately no MarshalText methods, so written files keep using numbers.
+func (consoleType *ConsoleType) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseConsoleType(getHeaderEnumText(text))
+	*consoleType = parsedValue
+	return err
+}
+
+func (extendedConsoleType *ExtendedConsoleType) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseExtendedConsoleType(getHeaderEnumText(text))
+	*extendedConsoleType = parsedValue
+	return err
+}
+
+func (vsPpuType *VsPPUType) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseVsPPUType(getHeaderEnumText(text))
+	*vsPpuType = parsedValue
+	return err
+}
+
+func (vsHardwareType *VsHardwareType) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseVsHardwareType(getHeaderEnumText(text))
+	*vsHardwareType = parsedValue
+	return err
+}
+
+func (cpuPpuTiming *CPUPPUTiming) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseCPUPPUTiming(getHeaderEnumText(text))
+	*cpuPpuTiming = parsedValue
+	return err
+}
+
+func (defaultExpansion *DefaultExpansion) UnmarshalText(text []byte) error {
+	parsedValue, err := ParseDefaultExpansion(getHeaderEnumText(text))
+	*defaultExpansion = parsedValue
+	return err
+}
+
+func (consoleType ConsoleType) String() string {
+	switch consoleType {
+	case CONSOLE_TYPE_NES:
+		return "Regular NES/Famicom/Dendy"
+	case CONSOLE_TYPE_VS_SYSTEM:
+		return "Nintendo Vs. System"
+	case CONSOLE_TYPE_PLAYCHOICE10:
+		return "Playchoice 10"
+	case CONSOLE_TYPE_EXTENDED:
+		return "Extended Console Type"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func (extendedConsoleType ExtendedConsoleType) String() string {
+	switch extendedConsoleType {
+	case EXTENDED_CONSOLE_TYPE_DECIMAL_FAMICLONE:
+		return "Regular Famiclone, but with CPU that supports Decimal Mode (e.g. Bit Corporation Creator)"
+	case EXTENDED_CONSOLE_TYPE_VT01_MONOCHROME:
+		return "V.R. Technology VT01 with monochrome palette"
+	case EXTENDED_CONSOLE_TYPE_VT01_STN:
+		return "V.R. Technology VT01 with red/cyan STN palette"
+	case EXTENDED_CONSOLE_TYPE_VT02:
+		return "V.R. Technology VT02"
+	case EXTENDED_CONSOLE_TYPE_VT03:
+		return "V.R. Technology VT03"
+	case EXTENDED_CONSOLE_TYPE_VT09:
+		return "V.R. Technology VT09"
+	case EXTENDED_CONSOLE_TYPE_VT32:
+		return "V.R. Technology VT32"
+	case EXTENDED_CONSOLE_TYPE_VT369:
+		return "V.R. Technology VT369"
+	case EXTENDED_CONSOLE_TYPE_UM6578:
+		return "UMC UM6578"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func (vsPpuType VsPPUType) String() string {
+	switch vsPpuType {
+	case VS_PPU_TYPE_RP2C03B:
+		return "RP2C03B"
+	case VS_PPU_TYPE_RP2C03G:
+		return "RP2C03G"
+	case VS_PPU_TYPE_RP2C04_0001:
+		return "RP2C04-0001"
+	case VS_PPU_TYPE_RP2C04_0002:
+		return "RP2C04-0002"
+	case VS_PPU_TYPE_RP2C04_0003:
+		return "RP2C04-0003"
+	case VS_PPU_TYPE_RP2C04_0004:
+		return "RP2C04-0004"
+	case VS_PPU_TYPE_RC2C03B:
+		return "RC2C03B"
+	case VS_PPU_TYPE_RC2C03C:
+		return "RC2C03C"
+	case VS_PPU_TYPE_RC2C05_01:
+		return "RC2C05-01 ($2002 AND $?? =$1B)"
+	case VS_PPU_TYPE_RC2C05_02:
+		return "RC2C05-02 ($2002 AND $3F =$3D)"
+	case VS_PPU_TYPE_RC2C05_03:
+		return "RC2C05-03 ($2002 AND $1F =$1C)"
+	case VS_PPU_TYPE_RC2C05_04:
+		return "RC2C05-04 ($2002 AND $1F =$1B)"
+	case VS_PPU_TYPE_RC2C05_05:
+		return "RC2C05-05 ($2002 AND $1F =unknown)"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func (vsHardwareType VsHardwareType) String() string {
+	switch vsHardwareType {
+	case VS_HARDWARE_TYPE_UNISYSTEM:
+		return "Vs. Unisystem (normal)"
+	case VS_HARDWARE_TYPE_UNISYSTEM_RBI_BASEBALL:
+		return "Vs. Unisystem (RBI Baseball protection)"
+	case VS_HARDWARE_TYPE_UNISYSTEM_TKO_BOXING:
+		return "Vs. Unisystem (TKO Boxing protection)"
+	case VS_HARDWARE_TYPE_UNISYSTEM_SUPER_XEVIOUS:
+		return "Vs. Unisystem (Super Xevious protection)"
+	case VS_HARDWARE_TYPE_UNISYSTEM_ICE_CLIMBER:
+		return "Vs. Unisystem (Vs. Ice Climber Japan protection)"
+	case VS_HARDWARE_TYPE_DUAL_SYSTEM:
+		return "Vs. Dual System (normal)"
+	case VS_HARDWARE_TYPE_DUAL_SYSTEM_RAID:
+		return "Vs. Dual System (Raid on Bungeling Bay protection)"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func (cpuPpuTiming CPUPPUTiming) String() string {
+	switch cpuPpuTiming {
+	case CPU_PPU_TIMING_NTSC:
+		return "RP2C02 (\"NTSC NES\")"
+	case CPU_PPU_TIMING_PAL:
+		return "RP2C07 (\"Licensed PAL NES\")"
+	case CPU_PPU_TIMING_MULTI_REGION:
+		return "Multiple-region"
+	case CPU_PPU_TIMING_DENDY:
+		return "UMC 6527P (\"Dendy\")"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func (defaultExpansion DefaultExpansion) String() string {
+	switch defaultExpansion {
+	case 0:
+		return "Unspecified"
+	case 1:
+		return "Standard NES/Famicom controllers"
+	case 2:
+		return "NES Four Score/Satellite with two additional standard controllers"
+	case 3:
+		return "Famicom Four Players Adapter with two additional standard controllers"
+	case 4:
+		return "Vs. System"
+	case 5:
+		return "Vs. System with reversed inputs"
+	case 6:
+		return "Vs. Pinball (Japan)"
+	case 7:
+		return "Vs. Zapper"
+	case 8:
+		return "Zapper ($4017)"
+	case 9:
+		return "Two Zappers"
+	case 10:
+		return "Bandai Hyper Shot Lightgun"
+	case 11:
+		return "Power Pad Side A"
+	case 12:
+		return "Power Pad Side B"
+	case 13:
+		return "Family Trainer Side A"
+	case 14:
+		return "Family Trainer Side B"
+	case 15:
+		return "Arkanoid Vaus Controller (NES)"
+	case 16:
+		return "Arkanoid Vaus Controller (Famicom)"
+	case 17:
+		return "Two Vaus Controllers plus Famicom Data Recorder"
+	case 18:
+		return "Konami Hyper Shot Controller"
+	case 19:
+		return "Coconuts Pachinko Controller"
+	case 20:
+		return "Exciting Boxing Punching Bag (Blowup Doll)"
+	case 21:
+		return "Jissen Mahjong Controller"
+	case 22:
+		return "Party Tap"
+	case 23:
+		return "Oeka Kids Tablet"
+	case 24:
+		return "Sunsoft Barcode Battler"
+	case 25:
+		return "Miracle Piano Keyboard"
+	case 26:
+		return "Pokkun Moguraa (Whack-a-Mole Mat and Mallet)"
+	case 27:
+		return "Top Rider (Inflatable Bicycle)"
+	case 28:
+		return "Double-Fisted (Requires or allows use of two controllers by one player)"
+	case 29:
+		return "Famicom 3D System"
+	case 30:
+		return "Doremikko Keyboard"
+	case 31:
+		return "R.O.B. Gyro Set"
+	case 32:
+		return "Famicom Data Recorder (don't emulate keyboard)"
+	case 33:
+		return "ASCII Turbo File"
+	case 34:
+		return "IGS Storage Battle Box"
+	case 35:
+		return "Family BASIC Keyboard plus Famicom Data Recorder"
+	case 36:
+		return "Dongda PEC-586 Keyboard"
+	case 37:
+		return "Bit Corp. Bit-79 Keyboard"
+	case 38:
+		return "Subor Keyboard"
+	case 39:
+		return "Subor Keyboard plus mouse (3x8-bit protocol)"
+	case 40:
+		return "Subor Keyboard plus mouse (24-bit protocol)"
+	case 41:
+		return "SNES Mouse ($4017.d0)"
+	case 42:
+		return "Multicart"
+	case 43:
+		return "Two SNES controllers replacing the two standard NES controllers"
+	case 44:
+		return "RacerMate Bicycle"
+	case 45:
+		return "U-Force"
+	case 46:
+		return "R.O.B. Stack-Up"
+	case 47:
+		return "City Patrolman Lightgun"
+	case 48:
+		return "Sharp C1 Cassette Interface"
+	case 49:
+		return "Standard Controller with swapped Left-Right/Up-Down/B-A"
+	case 50:
+		return "Excalibor Sudoku Pad"
+	case 51:
+		return "ABL Pinball"
+	case 52:
+		return "Golden Nugget Casino extra buttons"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+// Accept either a name from the list or a number up to maxValue
+func parseHeaderEnum(typeName string, value string, names []string, maxValue uint64) (uint8, error) {
+	normalizedValue := strings.ToLower(strings.TrimSpace(value))
+
+	for index := range names {
+		if names[index] == normalizedValue {
+			return uint8(index), nil
+		}
+	}
+
+	parsedValue, err := strconv.ParseUint(normalizedValue, 10, 8)
+	if err != nil || parsedValue > maxValue {
+		return 0, &NESROMError{Text: typeName + " must be a number from 0-" + strconv.FormatUint(maxValue, 10) + " or one of {" + strings.Join(names, "|") + "}"}
+	}
+
+	return uint8(parsedValue), nil
+}
+
+// XML readers took an empty attribute as 0 before names were accepted,
+// so they still do
+func getHeaderEnumText(text []byte) string {
+	if strings.TrimSpace(string(text)) == "" {
+		return "0"
+	}
+
+	return string(text)
+}
+
+func getHeaderEnumName(value uint8, names []string) string {
+	if int(value) < len(names) {
+		return names[value]
+	}
+
+	return strconv.Itoa(int(value))
+}
@@ -0,0 +1,111 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestParseHeaderEnum(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		maxValue      uint64
+		expectedValue uint8
+		expectError   bool
+	}{
+		{name: "name", value: "pal", maxValue: 3, expectedValue: 1},
+		{name: "name in upper case", value: "DENDY", maxValue: 3, expectedValue: 3},
+		{name: "name with spaces", value: "  multi-region ", maxValue: 3, expectedValue: 2},
+		{name: "number", value: "2", maxValue: 3, expectedValue: 2},
+		{name: "largest number", value: "3", maxValue: 3, expectedValue: 3},
+		{name: "number past the largest value", value: "4", maxValue: 3, expectError: true},
+		{name: "number past a byte", value: "256", maxValue: 255, expectError: true},
+		{name: "negative number", value: "-1", maxValue: 3, expectError: true},
+		{name: "unknown name", value: "secam", maxValue: 3, expectError: true},
+		{name: "empty", value: "", maxValue: 3, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enumValue, err := parseHeaderEnum("CPU/PPU Timing", test.value, cpuPPUTimingNames, test.maxValue)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %d", enumValue)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if enumValue != test.expectedValue {
+				t.Fatalf("expected %d, got %d", test.expectedValue, enumValue)
+			}
+		})
+	}
+}
+
+func TestHeaderEnumUnmarshalXML(t *testing.T) {
+	type testHeader struct {
+		ConsoleType      ConsoleType      `xml:"consoleType,attr"`
+		CPUPPUTiming     CPUPPUTiming     `xml:"cpuPpuTiming,attr"`
+		DefaultExpansion DefaultExpansion `xml:"defaultExpansion,attr"`
+	}
+
+	tests := []struct {
+		name        string
+		payload     string
+		expected    testHeader
+		expectError bool
+	}{
+		{name: "numbers", payload: `<header consoleType="1" cpuPpuTiming="2" defaultExpansion="42"/>`, expected: testHeader{ConsoleType: 1, CPUPPUTiming: 2, DefaultExpansion: 42}},
+		{name: "names", payload: `<header consoleType="vs-system" cpuPpuTiming="pal" defaultExpansion="standard"/>`, expected: testHeader{ConsoleType: 1, CPUPPUTiming: 1, DefaultExpansion: 1}},
+		{name: "empty attributes", payload: `<header consoleType="" cpuPpuTiming=" " defaultExpansion=""/>`, expected: testHeader{}},
+		{name: "missing attributes", payload: `<header/>`, expected: testHeader{}},
+		{name: "unknown name", payload: `<header consoleType="famicom"/>`, expectError: true},
+		{name: "number out of range", payload: `<header cpuPpuTiming="4"/>`, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := testHeader{}
+			err := xml.Unmarshal([]byte(test.payload), &header)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", header)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if header != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, header)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://wiki.nesdev.com/w/index.php/NES_2.0
+// https://wiki.nesdev.com/w/index.php/INES
+// Byte and bit-level annotation of NES headers, for seeing exactly
+// what an update to a header changes.
+
+package NESTool
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+type headerBitField struct {
+	byteIndex int
+	mask      byte
+	name      string
+	describe  func(value byte, header []byte) string
+}
+
+// Annotate every bit field of a header.  If a second header is passed,
+// the fields are laid out for it, and any fields that differ between
+// the two are marked with a "*" and shown as "old -> new".
+func ExplainNESHeader(existingHeader []byte, calculatedHeader []byte) (string, error) {
+	if existingHeader == nil || len(existingHeader) != 16 {
+		return "", &NESROMError{Text: "Existing header must be 16 bytes."}
+	}
+
+	if calculatedHeader != nil && len(calculatedHeader) != 16 {
+		return "", &NESROMError{Text: "Calculated header must be 16 bytes."}
+	}
+
+	displayHeader := existingHeader
+	if calculatedHeader != nil {
+		displayHeader = calculatedHeader
+	}
+
+	returnString := "Existing Header:   " + strings.ToUpper(hex.EncodeToString(existingHeader)) + "\n"
+	if calculatedHeader != nil {
+		returnString = returnString + "Calculated Header: " + strings.ToUpper(hex.EncodeToString(calculatedHeader)) + "\n"
+	}
+
+	returnString = returnString + "Header Format: " + getHeaderFormatString(displayHeader)
+	if calculatedHeader != nil && isNES20HeaderData(existingHeader) != isNES20HeaderData(calculatedHeader) {
+		returnString = returnString + " (existing header is " + getHeaderFormatString(existingHeader) + ", so its bits are shown with this layout)"
+	}
+	returnString = returnString + "\n\n"
+
+	returnString = returnString + "Bytes 0-3: " + strings.ToUpper(hex.EncodeToString(displayHeader[0:4]))
+	if calculatedHeader != nil && !bytes.Equal(existingHeader[0:4], calculatedHeader[0:4]) {
+		returnString = returnString + " (was " + strings.ToUpper(hex.EncodeToString(existingHeader[0:4])) + ")"
+	}
+
+	if bytes.Equal(displayHeader[0:4], []byte(NES_HEADER_MAGIC)) {
+		returnString = returnString + "  Magic: NES<EOF>\n"
+	} else {
+		returnString = returnString + "  Magic: Invalid\n"
+	}
+
+	bitFields := getHeaderBitFields(displayHeader)
+	changedBytes := 0
+	if !bytes.Equal(existingHeader[0:4], displayHeader[0:4]) {
+		changedBytes = changedBytes + 4
+	}
+
+	for byteIndex := 4; byteIndex < 16; byteIndex++ {
+		returnString = returnString + "Byte " + strconv.Itoa(byteIndex) + ": $" + getHeaderByteString(displayHeader[byteIndex])
+		if existingHeader[byteIndex] != displayHeader[byteIndex] {
+			changedBytes++
+			returnString = returnString + " (was $" + getHeaderByteString(existingHeader[byteIndex]) + ")"
+		}
+		returnString = returnString + "\n"
+
+		for fieldIndex := range bitFields {
+			field := bitFields[fieldIndex]
+			if field.byteIndex != byteIndex {
+				continue
+			}
+
+			oldValue := getHeaderBitFieldValue(existingHeader[byteIndex], field.mask)
+			newValue := getHeaderBitFieldValue(displayHeader[byteIndex], field.mask)
+
+			if oldValue != newValue {
+				returnString = returnString + "  * " + getHeaderBitPattern(displayHeader[byteIndex], field.mask) + "  " + field.name + ": " + field.describe(oldValue, existingHeader) + " -> " + field.describe(newValue, displayHeader) + "\n"
+			} else {
+				returnString = returnString + "    " + getHeaderBitPattern(displayHeader[byteIndex], field.mask) + "  " + field.name + ": " + field.describe(newValue, displayHeader) + "\n"
+			}
+		}
+	}
+
+	if calculatedHeader != nil {
+		returnString = returnString + "\nBytes Changed: " + strconv.Itoa(changedBytes)
+	}
+
+	return returnString, nil
+}
+
+func getHeaderBitFields(header []byte) []*headerBitField {
+	bitFields := make([]*headerBitField, 0)
+
+	if isNES20HeaderData(header) {
+		if header[9]&0x0F == 0x0F {
+			bitFields = append(bitFields, &headerBitField{4, 0xFC, "PRG ROM size exponent", describeHeaderNumber})
+			bitFields = append(bitFields, &headerBitField{4, 0x03, "PRG ROM size multiplier", describePRGROMSize})
+		} else {
+			bitFields = append(bitFields, &headerBitField{4, 0xFF, "PRG ROM size LSB", describePRGROMSize})
+		}
+
+		if header[9]&0xF0 == 0xF0 {
+			bitFields = append(bitFields, &headerBitField{5, 0xFC, "CHR ROM size exponent", describeHeaderNumber})
+			bitFields = append(bitFields, &headerBitField{5, 0x03, "CHR ROM size multiplier", describeCHRROMSize})
+		} else {
+			bitFields = append(bitFields, &headerBitField{5, 0xFF, "CHR ROM size LSB", describeCHRROMSize})
+		}
+	} else {
+		bitFields = append(bitFields, &headerBitField{4, 0xFF, "PRG ROM size in 16 KiB units", describePRGROMSize})
+		bitFields = append(bitFields, &headerBitField{5, 0xFF, "CHR ROM size in 8 KiB units", describeCHRROMSize})
+	}
+
+	bitFields = append(bitFields, &headerBitField{6, 0x01, "Mirroring", describeHeaderMirroring})
+	bitFields = append(bitFields, &headerBitField{6, 0x02, "Battery", describeHeaderFlag})
+	bitFields = append(bitFields, &headerBitField{6, 0x04, "Trainer", describeHeaderFlag})
+	bitFields = append(bitFields, &headerBitField{6, 0x08, "Four-screen", describeHeaderFlag})
+	bitFields = append(bitFields, &headerBitField{6, 0xF0, "Mapper bits 0-3", describeHeaderMapper})
+
+	if isNES20HeaderData(header) {
+		bitFields = append(bitFields, &headerBitField{7, 0x03, "Console type", func(value byte, header []byte) string {
+			return strconv.Itoa(int(value)) + " (" + ConsoleType(value).Name() + ")"
+		}})
+	} else {
+		bitFields = append(bitFields, &headerBitField{7, 0x01, "Vs. Unisystem", describeHeaderFlag})
+		bitFields = append(bitFields, &headerBitField{7, 0x02, "PlayChoice-10", describeHeaderFlag})
+	}
+
+	bitFields = append(bitFields, &headerBitField{7, 0x0C, "Header identifier", describeHeaderIdentifier})
+	bitFields = append(bitFields, &headerBitField{7, 0xF0, "Mapper bits 4-7", describeHeaderMapper})
+
+	if !isNES20HeaderData(header) {
+		bitFields = append(bitFields, &headerBitField{8, 0xFF, "PRG RAM size in 8 KiB units", describeHeaderNumber})
+		bitFields = append(bitFields, &headerBitField{9, 0x01, "TV system", func(value byte, header []byte) string {
+			if value == 0 {
+				return "0 (NTSC)"
+			}
+
+			return "1 (PAL)"
+		}})
+		bitFields = append(bitFields, &headerBitField{9, 0xFE, "Reserved", describeHeaderReserved})
+
+		// Byte 10 is an unofficial extension that few emulators use
+		bitFields = append(bitFields, &headerBitField{10, 0x03, "TV system (unofficial)", func(value byte, header []byte) string {
+			switch value {
+			case 0:
+				return "0 (NTSC)"
+			case 2:
+				return "2 (PAL)"
+			default:
+				return strconv.Itoa(int(value)) + " (dual compatible)"
+			}
+		}})
+		bitFields = append(bitFields, &headerBitField{10, 0x10, "PRG RAM absent (unofficial)", describeHeaderFlag})
+		bitFields = append(bitFields, &headerBitField{10, 0x20, "Bus conflicts (unofficial)", describeHeaderFlag})
+		bitFields = append(bitFields, &headerBitField{10, 0xCC, "Reserved", describeHeaderReserved})
+
+		for byteIndex := 11; byteIndex < 16; byteIndex++ {
+			bitFields = append(bitFields, &headerBitField{byteIndex, 0xFF, "Unused", describeHeaderReserved})
+		}
+
+		return bitFields
+	}
+
+	bitFields = append(bitFields, &headerBitField{8, 0x0F, "Mapper bits 8-11", describeHeaderMapper})
+	bitFields = append(bitFields, &headerBitField{8, 0xF0, "Submapper", func(value byte, header []byte) string {
+		subMapperName := GetSubMapperName(getHeaderMapper(header), value)
+		if subMapperName == "" {
+			return strconv.Itoa(int(value))
+		}
+
+		return strconv.Itoa(int(value)) + " (" + subMapperName + ")"
+	}})
+	bitFields = append(bitFields, &headerBitField{9, 0x0F, "PRG ROM size MSB", describeHeaderSizeMSB})
+	bitFields = append(bitFields, &headerBitField{9, 0xF0, "CHR ROM size MSB", describeHeaderSizeMSB})
+	bitFields = append(bitFields, &headerBitField{10, 0x0F, "PRG RAM size", describeHeaderRAMSize})
+	bitFields = append(bitFields, &headerBitField{10, 0xF0, "PRG NVRAM size", describeHeaderRAMSize})
+	bitFields = append(bitFields, &headerBitField{11, 0x0F, "CHR RAM size", describeHeaderRAMSize})
+	bitFields = append(bitFields, &headerBitField{11, 0xF0, "CHR NVRAM size", describeHeaderRAMSize})
+	bitFields = append(bitFields, &headerBitField{12, 0x03, "CPU/PPU timing", func(value byte, header []byte) string {
+		return strconv.Itoa(int(value)) + " (" + CPUPPUTiming(value).Name() + ")"
+	}})
+	bitFields = append(bitFields, &headerBitField{12, 0xFC, "Reserved", describeHeaderReserved})
+
+	if ConsoleType(header[7]&0x03) == CONSOLE_TYPE_VS_SYSTEM {
+		bitFields = append(bitFields, &headerBitField{13, 0x0F, "Vs. PPU type", func(value byte, header []byte) string {
+			return strconv.Itoa(int(value)) + " (" + VsPPUType(value).Name() + ")"
+		}})
+		bitFields = append(bitFields, &headerBitField{13, 0xF0, "Vs. hardware type", func(value byte, header []byte) string {
+			return strconv.Itoa(int(value)) + " (" + VsHardwareType(value).Name() + ")"
+		}})
+	} else if ConsoleType(header[7]&0x03) == CONSOLE_TYPE_EXTENDED {
+		bitFields = append(bitFields, &headerBitField{13, 0x0F, "Extended console type", func(value byte, header []byte) string {
+			return strconv.Itoa(int(value)) + " (" + ExtendedConsoleType(value).Name() + ")"
+		}})
+		bitFields = append(bitFields, &headerBitField{13, 0xF0, "Reserved", describeHeaderReserved})
+	} else {
+		bitFields = append(bitFields, &headerBitField{13, 0xFF, "Unused for this console type", describeHeaderReserved})
+	}
+
+	bitFields = append(bitFields, &headerBitField{14, 0x03, "Misc ROMs", describeHeaderNumber})
+	bitFields = append(bitFields, &headerBitField{14, 0xFC, "Reserved", describeHeaderReserved})
+	bitFields = append(bitFields, &headerBitField{15, 0x3F, "Default expansion", func(value byte, header []byte) string {
+		return strconv.Itoa(int(value)) + " (" + DefaultExpansion(value).Name() + ")"
+	}})
+	bitFields = append(bitFields, &headerBitField{15, 0xC0, "Reserved", describeHeaderReserved})
+
+	return bitFields
+}
+
+func describeHeaderNumber(value byte, header []byte) string {
+	return strconv.Itoa(int(value))
+}
+
+func describeHeaderFlag(value byte, header []byte) string {
+	if value == 0 {
+		return "0 (no)"
+	}
+
+	return "1 (yes)"
+}
+
+func describeHeaderReserved(value byte, header []byte) string {
+	if value == 0 {
+		return "0"
+	}
+
+	return strconv.Itoa(int(value)) + " (should be zero)"
+}
+
+func describeHeaderMirroring(value byte, header []byte) string {
+	if value == 0 {
+		return "0 (horizontal or mapper-controlled)"
+	}
+
+	return "1 (vertical)"
+}
+
+func describeHeaderIdentifier(value byte, header []byte) string {
+	switch value {
+	case 0:
+		return "0 (iNES)"
+	case 2:
+		return "2 (NES 2.0)"
+	default:
+		return strconv.Itoa(int(value)) + " (archaic iNES or invalid)"
+	}
+}
+
+// Each part of the mapper number shows the whole number it belongs to
+func describeHeaderMapper(value byte, header []byte) string {
+	mapper := getHeaderMapper(header)
+	return strconv.Itoa(int(value)) + " (mapper " + strconv.Itoa(int(mapper)) + ", " + GetMapperName(mapper) + ")"
+}
+
+func describeHeaderSizeMSB(value byte, header []byte) string {
+	if value == 0x0F && isNES20HeaderData(header) {
+		return "15 (exponent-multiplier notation)"
+	}
+
+	return strconv.Itoa(int(value))
+}
+
+func describeHeaderRAMSize(value byte, header []byte) string {
+	if value == 0 {
+		return "0 (none)"
+	}
+
+	return strconv.Itoa(int(value)) + " (" + strconv.FormatUint(64<<value, 10) + " bytes)"
+}
+
+func describePRGROMSize(value byte, header []byte) string {
+	return strconv.Itoa(int(value)) + " (PRG ROM is " + strconv.FormatUint(getHeaderROMSize(header, 4, 0x0F, 16384), 10) + " bytes)"
+}
+
+func describeCHRROMSize(value byte, header []byte) string {
+	return strconv.Itoa(int(value)) + " (CHR ROM is " + strconv.FormatUint(getHeaderROMSize(header, 5, 0xF0, 8192), 10) + " bytes)"
+}
+
+// Work out a ROM size from the raw header, in either notation
+func getHeaderROMSize(header []byte, lsbIndex int, msbMask byte, unitSize uint64) uint64 {
+	if !isNES20HeaderData(header) {
+		return uint64(header[lsbIndex]) * unitSize
+	}
+
+	msbValue := getHeaderBitFieldValue(header[9], msbMask)
+	if msbValue == 0x0F {
+		exponent := header[lsbIndex] >> 2
+		multiplier := uint64(header[lsbIndex]&0x03)*2 + 1
+		if exponent > 40 {
+			return 0
+		}
+
+		return (uint64(1) << exponent) * multiplier
+	}
+
+	return (uint64(msbValue)<<8 | uint64(header[lsbIndex])) * unitSize
+}
+
+func getHeaderMapper(header []byte) uint16 {
+	mapper := uint16(header[6]>>4) | uint16(header[7]&0xF0)
+	if isNES20HeaderData(header) {
+		mapper = mapper | uint16(header[8]&0x0F)<<8
+	}
+
+	return mapper
+}
+
+func getHeaderFormatString(header []byte) string {
+	if isNES20HeaderData(header) {
+		return "NES 2.0"
+	}
+
+	return "iNES"
+}
+
+func isNES20HeaderData(header []byte) bool {
+	return header[7]&0x0C == 0x08
+}
+
+func getHeaderBitFieldValue(headerByte byte, mask byte) byte {
+	value := headerByte & mask
+	for shiftMask := mask; shiftMask&0x01 == 0 && shiftMask != 0; shiftMask = shiftMask >> 1 {
+		value = value >> 1
+	}
+
+	return value
+}
+
+// Show the bits a field covers, with "." for the rest of the byte
+func getHeaderBitPattern(headerByte byte, mask byte) string {
+	bitPattern := ""
+	for bit := 7; bit >= 0; bit-- {
+		if mask&(1<<uint(bit)) == 0 {
+			bitPattern = bitPattern + "."
+		} else if headerByte&(1<<uint(bit)) == 0 {
+			bitPattern = bitPattern + "0"
+		} else {
+			bitPattern = bitPattern + "1"
+		}
+	}
+
+	return bitPattern
+}
+
+func getHeaderByteString(headerByte byte) string {
+	return strings.ToUpper(hex.EncodeToString([]byte{headerByte}))
+}
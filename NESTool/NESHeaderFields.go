@@ -0,0 +1,656 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Editable header fields, in the order they're applied.  Console type
+// comes before the fields that depend on it.
+var NES_HEADER_FIELD_NAMES = []string{
+	"prg-rom-byte-size",
+	"prg-ram-size",
+	"prg-nvram-size",
+	"chr-rom-byte-size",
+	"chr-ram-size",
+	"chr-nvram-size",
+	"number-of-misc-roms",
+	"has-trainer",
+	"mirroring-type",
+	"four-screen",
+	"has-battery",
+	"console-type",
+	"extended-console-type",
+	"mapper-number",
+	"submapper-number",
+	"cpu-ppu-timing",
+	"vs-hardware-type",
+	"vs-ppu-type",
+	"default-expansion",
+	"vs-unisystem",
+	"playchoice-10",
+	"tv-system",
+}
+
+// Check whether a name is one of the editable header fields
+func IsNESHeaderFieldName(fieldName string) bool {
+	for index := range NES_HEADER_FIELD_NAMES {
+		if NES_HEADER_FIELD_NAMES[index] == fieldName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply a set of header field values to a ROM.  Fields are applied in
+// the order of NES_HEADER_FIELD_NAMES regardless of the order given.
+// They're applied to a copy of the headers first, so the ROM is only
+// changed if every field name and value is valid.
+func ApplyNESROMHeaderFields(rom *NESROM, fieldValues map[string]string) error {
+	for fieldName := range fieldValues {
+		if !IsNESHeaderFieldName(fieldName) {
+			return &NESROMError{Text: "Unknown header field: " + fieldName}
+		}
+	}
+
+	tempRom := *rom
+	if rom.Header20 != nil {
+		tempHeader20 := *rom.Header20
+		tempRom.Header20 = &tempHeader20
+	}
+
+	if rom.Header10 != nil {
+		tempHeader10 := *rom.Header10
+		tempRom.Header10 = &tempHeader10
+	}
+
+	for index := range NES_HEADER_FIELD_NAMES {
+		fieldValue, hasField := fieldValues[NES_HEADER_FIELD_NAMES[index]]
+		if !hasField {
+			continue
+		}
+
+		err := SetNESROMHeaderField(&tempRom, NES_HEADER_FIELD_NAMES[index], fieldValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	*rom = tempRom
+
+	return nil
+}
+
+// Set a single header field from its string value
+func SetNESROMHeaderField(rom *NESROM, fieldName string, fieldValue string) error {
+	if rom.Header20 == nil && rom.Header10 == nil {
+		return &NESROMError{Text: "No valid ROM found"}
+	}
+
+	fieldValue = strings.TrimSpace(fieldValue)
+
+	switch fieldName {
+	case "prg-rom-byte-size":
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, ^uint64(0))
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.PRGROMCalculatedSize = paramInt
+		} else {
+			if paramInt%(16*1024) != 0 || paramInt/(16*1024) > 255 {
+				return &NESROMError{Text: "For iNES ROMs, PRG ROM size must be a multiple of 16KB, up to 255 units"}
+			}
+
+			rom.Header10.PRGROMCalculatedSize = paramInt
+		}
+
+		return UpdateSizes(rom, PRG_CANONICAL_SIZE_CALCULATED, CHR_CANONICAL_SIZE_CALCULATED)
+	case "prg-ram-size":
+		if rom.Header20 != nil {
+			paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 15)
+			if err != nil {
+				return &NESROMError{Text: "For NES 2.0 ROMs, PRG RAM size is calculated as 64*2^X bytes, where X is 0-15"}
+			}
+
+			rom.Header20.PRGRAMSize = uint8(paramInt)
+		} else {
+			paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 255)
+			if err != nil {
+				return &NESROMError{Text: "For iNES ROMs, PRG RAM can have no more than 255 8KB units"}
+			}
+
+			rom.Header10.PRGRAMSize = uint8(paramInt)
+		}
+	case "prg-nvram-size":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "PRG NVRAM is only available in NES 2.0 headers"}
+		}
+
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 15)
+		if err != nil {
+			return &NESROMError{Text: "PRG NVRAM size is calculated as 64*2^X bytes, where X is 0-15"}
+		}
+
+		rom.Header20.PRGNVRAMSize = uint8(paramInt)
+	case "chr-rom-byte-size":
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, ^uint64(0))
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.CHRROMCalculatedSize = paramInt
+		} else {
+			if paramInt%(8*1024) != 0 || paramInt/(8*1024) > 255 {
+				return &NESROMError{Text: "For iNES ROMs, CHR ROM size must be a multiple of 8KB, up to 255 units"}
+			}
+
+			rom.Header10.CHRROMCalculatedSize = paramInt
+		}
+
+		return UpdateSizes(rom, PRG_CANONICAL_SIZE_CALCULATED, CHR_CANONICAL_SIZE_CALCULATED)
+	case "chr-ram-size":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "CHR RAM is only available in NES 2.0 headers"}
+		}
+
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 15)
+		if err != nil {
+			return &NESROMError{Text: "CHR RAM size is calculated as 64*2^X bytes, where X is 0-15"}
+		}
+
+		rom.Header20.CHRRAMSize = uint8(paramInt)
+	case "chr-nvram-size":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "CHR NVRAM is only available in NES 2.0 headers"}
+		}
+
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 15)
+		if err != nil {
+			return &NESROMError{Text: "CHR NVRAM size is calculated as 64*2^X bytes, where X is 0-15"}
+		}
+
+		rom.Header20.CHRNVRAMSize = uint8(paramInt)
+	case "number-of-misc-roms":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Misc ROMs are only available in NES 2.0 ROMs"}
+		}
+
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 3)
+		if err != nil {
+			return &NESROMError{Text: "ROM can have no more than 3 misc ROMs"}
+		}
+
+		rom.Header20.MiscROMs = uint8(paramInt)
+	case "has-trainer":
+		hasTrainer, err := parseHeaderFieldBool(fieldName, fieldValue, "true", "false")
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.Trainer = hasTrainer
+		} else {
+			rom.Header10.Trainer = hasTrainer
+		}
+	case "mirroring-type":
+		mirroringType, err := parseHeaderFieldBool(fieldName, fieldValue, "vertical", "horizontal")
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.MirroringType = mirroringType
+		} else {
+			rom.Header10.MirroringType = mirroringType
+		}
+	case "four-screen":
+		hasFourScreen, err := parseHeaderFieldBool(fieldName, fieldValue, "true", "false")
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.FourScreen = hasFourScreen
+		} else {
+			rom.Header10.FourScreen = hasFourScreen
+		}
+	case "has-battery":
+		hasBattery, err := parseHeaderFieldBool(fieldName, fieldValue, "true", "false")
+		if err != nil {
+			return err
+		}
+
+		if rom.Header20 != nil {
+			rom.Header20.Battery = hasBattery
+		} else {
+			rom.Header10.Battery = hasBattery
+		}
+	case "console-type":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Console Type is only available in NES 2.0 headers"}
+		}
+
+		consoleType, err := ParseConsoleType(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		if consoleType == CONSOLE_TYPE_VS_SYSTEM {
+			rom.Header20.ExtendedConsoleType = 0
+		} else if consoleType == CONSOLE_TYPE_EXTENDED {
+			rom.Header20.VsHardwareType = 0
+			rom.Header20.VsPPUType = 0
+		} else {
+			rom.Header20.ExtendedConsoleType = 0
+			rom.Header20.VsHardwareType = 0
+			rom.Header20.VsPPUType = 0
+		}
+
+		rom.Header20.ConsoleType = uint8(consoleType)
+	case "extended-console-type":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Extended Console Type is only available in NES 2.0 headers"}
+		}
+
+		if rom.Header20.ConsoleType != 3 {
+			return &NESROMError{Text: "Extended Console Type is only valid when Console Type is 3 (extended)"}
+		}
+
+		extendedConsoleType, err := ParseExtendedConsoleType(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		if extendedConsoleType < EXTENDED_CONSOLE_TYPE_DECIMAL_FAMICLONE {
+			return &NESROMError{Text: "Extended Console Type must be a value from 3-15"}
+		}
+
+		rom.Header20.ExtendedConsoleType = uint8(extendedConsoleType)
+	case "mapper-number":
+		if rom.Header20 != nil {
+			paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 4095)
+			if err != nil {
+				return &NESROMError{Text: "For NES 2.0 ROMs, mapper must be from 0-4095"}
+			}
+
+			rom.Header20.Mapper = uint16(paramInt)
+		} else {
+			paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 255)
+			if err != nil {
+				return &NESROMError{Text: "For iNES ROMs, mapper must be from 0-255"}
+			}
+
+			rom.Header10.Mapper = uint8(paramInt)
+		}
+	case "submapper-number":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Submappers are only available in NES 2.0 headers"}
+		}
+
+		paramInt, err := parseHeaderFieldUint(fieldName, fieldValue, 15)
+		if err != nil {
+			return &NESROMError{Text: "Submapper must be from 0-15"}
+		}
+
+		rom.Header20.SubMapper = uint8(paramInt)
+	case "cpu-ppu-timing":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "CPU/PPU Timing is only available in NES 2.0 headers"}
+		}
+
+		cpuPpuTiming, err := ParseCPUPPUTiming(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		rom.Header20.CPUPPUTiming = uint8(cpuPpuTiming)
+	case "vs-hardware-type":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Vs. Hardware Type is only available in NES 2.0 headers"}
+		}
+
+		if rom.Header20.ConsoleType != 1 {
+			return &NESROMError{Text: "Vs. Hardware Type is only valid when Console Type is 1 (vs-system)"}
+		}
+
+		vsHardwareType, err := ParseVsHardwareType(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		rom.Header20.VsHardwareType = uint8(vsHardwareType)
+	case "vs-ppu-type":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Vs. PPU Type is only available in NES 2.0 headers"}
+		}
+
+		if rom.Header20.ConsoleType != 1 {
+			return &NESROMError{Text: "Vs. PPU Type is only valid when Console Type is 1 (vs-system)"}
+		}
+
+		vsPpuType, err := ParseVsPPUType(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		rom.Header20.VsPPUType = uint8(vsPpuType)
+	case "default-expansion":
+		if rom.Header20 == nil {
+			return &NESROMError{Text: "Default Hardware Expansion is only available in NES 2.0 headers"}
+		}
+
+		defaultExpansion, err := ParseDefaultExpansion(fieldValue)
+		if err != nil {
+			return err
+		}
+
+		rom.Header20.DefaultExpansion = uint8(defaultExpansion)
+	case "vs-unisystem":
+		if rom.Header10 == nil {
+			return &NESROMError{Text: "Vs. Unisystem is only available in iNES headers"}
+		}
+
+		isVsUnisystem, err := parseHeaderFieldBool(fieldName, fieldValue, "true", "false")
+		if err != nil {
+			return err
+		}
+
+		rom.Header10.VsUnisystem = isVsUnisystem
+	case "playchoice-10":
+		if rom.Header10 == nil {
+			return &NESROMError{Text: "PlayChoice 10 is only available in iNES headers"}
+		}
+
+		isPlayChoice10, err := parseHeaderFieldBool(fieldName, fieldValue, "true", "false")
+		if err != nil {
+			return err
+		}
+
+		rom.Header10.PlayChoice10 = isPlayChoice10
+	case "tv-system":
+		if rom.Header10 == nil {
+			return &NESROMError{Text: "TV System is only available in iNES headers"}
+		}
+
+		tvSystem, err := parseHeaderFieldBool(fieldName, fieldValue, "pal", "ntsc")
+		if err != nil {
+			return err
+		}
+
+		rom.Header10.TVSystem = tvSystem
+	default:
+		return &NESROMError{Text: "Unknown header field: " + fieldName}
+	}
+
+	return nil
+}
+
+// Get a single header field as a string value, in the same form that
+// SetNESROMHeaderField accepts.  Fields which don't exist in the ROM's
+// header type return false.
+func GetNESROMHeaderField(rom *NESROM, fieldName string) (string, bool, error) {
+	if rom.Header20 == nil && rom.Header10 == nil {
+		return "", false, &NESROMError{Text: "No valid ROM found"}
+	}
+
+	if !IsNESHeaderFieldName(fieldName) {
+		return "", false, &NESROMError{Text: "Unknown header field: " + fieldName}
+	}
+
+	if rom.Header20 != nil {
+		switch fieldName {
+		case "prg-rom-byte-size":
+			return strconv.FormatUint(rom.Header20.PRGROMCalculatedSize, 10), true, nil
+		case "prg-ram-size":
+			return strconv.Itoa(int(rom.Header20.PRGRAMSize)), true, nil
+		case "prg-nvram-size":
+			return strconv.Itoa(int(rom.Header20.PRGNVRAMSize)), true, nil
+		case "chr-rom-byte-size":
+			return strconv.FormatUint(rom.Header20.CHRROMCalculatedSize, 10), true, nil
+		case "chr-ram-size":
+			return strconv.Itoa(int(rom.Header20.CHRRAMSize)), true, nil
+		case "chr-nvram-size":
+			return strconv.Itoa(int(rom.Header20.CHRNVRAMSize)), true, nil
+		case "number-of-misc-roms":
+			return strconv.Itoa(int(rom.Header20.MiscROMs)), true, nil
+		case "has-trainer":
+			return formatHeaderFieldBool(rom.Header20.Trainer, "true", "false"), true, nil
+		case "mirroring-type":
+			return formatHeaderFieldBool(rom.Header20.MirroringType, "vertical", "horizontal"), true, nil
+		case "four-screen":
+			return formatHeaderFieldBool(rom.Header20.FourScreen, "true", "false"), true, nil
+		case "has-battery":
+			return formatHeaderFieldBool(rom.Header20.Battery, "true", "false"), true, nil
+		case "console-type":
+			return ConsoleType(rom.Header20.ConsoleType).Name(), true, nil
+		case "extended-console-type":
+			return ExtendedConsoleType(rom.Header20.ExtendedConsoleType).Name(), true, nil
+		case "mapper-number":
+			return strconv.Itoa(int(rom.Header20.Mapper)), true, nil
+		case "submapper-number":
+			return strconv.Itoa(int(rom.Header20.SubMapper)), true, nil
+		case "cpu-ppu-timing":
+			return CPUPPUTiming(rom.Header20.CPUPPUTiming).Name(), true, nil
+		case "vs-hardware-type":
+			return VsHardwareType(rom.Header20.VsHardwareType).Name(), true, nil
+		case "vs-ppu-type":
+			return VsPPUType(rom.Header20.VsPPUType).Name(), true, nil
+		case "default-expansion":
+			return DefaultExpansion(rom.Header20.DefaultExpansion).Name(), true, nil
+		}
+
+		return "", false, nil
+	}
+
+	switch fieldName {
+	case "prg-rom-byte-size":
+		return strconv.FormatUint(rom.Header10.PRGROMCalculatedSize, 10), true, nil
+	case "prg-ram-size":
+		return strconv.Itoa(int(rom.Header10.PRGRAMSize)), true, nil
+	case "chr-rom-byte-size":
+		return strconv.FormatUint(rom.Header10.CHRROMCalculatedSize, 10), true, nil
+	case "has-trainer":
+		return formatHeaderFieldBool(rom.Header10.Trainer, "true", "false"), true, nil
+	case "mirroring-type":
+		return formatHeaderFieldBool(rom.Header10.MirroringType, "vertical", "horizontal"), true, nil
+	case "four-screen":
+		return formatHeaderFieldBool(rom.Header10.FourScreen, "true", "false"), true, nil
+	case "has-battery":
+		return formatHeaderFieldBool(rom.Header10.Battery, "true", "false"), true, nil
+	case "mapper-number":
+		return strconv.Itoa(int(rom.Header10.Mapper)), true, nil
+	case "vs-unisystem":
+		return formatHeaderFieldBool(rom.Header10.VsUnisystem, "true", "false"), true, nil
+	case "playchoice-10":
+		return formatHeaderFieldBool(rom.Header10.PlayChoice10, "true", "false"), true, nil
+	case "tv-system":
+		return formatHeaderFieldBool(rom.Header10.TVSystem, "pal", "ntsc"), true, nil
+	}
+
+	return "", false, nil
+}
+
+// Copy a single header field from another ROM.  Unlike
+// SetNESROMHeaderField, nothing else in the header is checked or
+// changed, so the Vs. System and extended console fields are copied as
+// they are whatever the console type.  When the header types differ,
+// the field is copied through its string value instead, apart from PRG
+// RAM size, which the two header types count in different units.
+// Fields which can't be copied between the ROMs' header types return
+// false.
+func CopyNESROMHeaderField(rom *NESROM, sourceRom *NESROM, fieldName string) (bool, error) {
+	if rom.Header20 == nil && rom.Header10 == nil || sourceRom.Header20 == nil && sourceRom.Header10 == nil {
+		return false, &NESROMError{Text: "No valid ROM found"}
+	}
+
+	if !IsNESHeaderFieldName(fieldName) {
+		return false, &NESROMError{Text: "Unknown header field: " + fieldName}
+	}
+
+	if rom.Header20 != nil && sourceRom.Header20 != nil {
+		header := rom.Header20
+		sourceHeader := sourceRom.Header20
+
+		switch fieldName {
+		case "prg-rom-byte-size":
+			header.PRGROMSize = sourceHeader.PRGROMSize
+			header.PRGROMSizeExponent = sourceHeader.PRGROMSizeExponent
+			header.PRGROMSizeMultiplier = sourceHeader.PRGROMSizeMultiplier
+			header.PRGROMCalculatedSize = sourceHeader.PRGROMCalculatedSize
+		case "prg-ram-size":
+			header.PRGRAMSize = sourceHeader.PRGRAMSize
+		case "prg-nvram-size":
+			header.PRGNVRAMSize = sourceHeader.PRGNVRAMSize
+		case "chr-rom-byte-size":
+			header.CHRROMSize = sourceHeader.CHRROMSize
+			header.CHRROMSizeExponent = sourceHeader.CHRROMSizeExponent
+			header.CHRROMSizeMultiplier = sourceHeader.CHRROMSizeMultiplier
+			header.CHRROMCalculatedSize = sourceHeader.CHRROMCalculatedSize
+		case "chr-ram-size":
+			header.CHRRAMSize = sourceHeader.CHRRAMSize
+		case "chr-nvram-size":
+			header.CHRNVRAMSize = sourceHeader.CHRNVRAMSize
+		case "number-of-misc-roms":
+			header.MiscROMs = sourceHeader.MiscROMs
+		case "has-trainer":
+			header.Trainer = sourceHeader.Trainer
+		case "mirroring-type":
+			header.MirroringType = sourceHeader.MirroringType
+		case "four-screen":
+			header.FourScreen = sourceHeader.FourScreen
+		case "has-battery":
+			header.Battery = sourceHeader.Battery
+		case "console-type":
+			header.ConsoleType = sourceHeader.ConsoleType
+		case "extended-console-type":
+			header.ExtendedConsoleType = sourceHeader.ExtendedConsoleType
+		case "mapper-number":
+			header.Mapper = sourceHeader.Mapper
+		case "submapper-number":
+			header.SubMapper = sourceHeader.SubMapper
+		case "cpu-ppu-timing":
+			header.CPUPPUTiming = sourceHeader.CPUPPUTiming
+		case "vs-hardware-type":
+			header.VsHardwareType = sourceHeader.VsHardwareType
+		case "vs-ppu-type":
+			header.VsPPUType = sourceHeader.VsPPUType
+		case "default-expansion":
+			header.DefaultExpansion = sourceHeader.DefaultExpansion
+		default:
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	if rom.Header10 != nil && sourceRom.Header10 != nil {
+		header := rom.Header10
+		sourceHeader := sourceRom.Header10
+
+		switch fieldName {
+		case "prg-rom-byte-size":
+			header.PRGROMSize = sourceHeader.PRGROMSize
+			header.PRGROMCalculatedSize = sourceHeader.PRGROMCalculatedSize
+		case "prg-ram-size":
+			header.PRGRAMSize = sourceHeader.PRGRAMSize
+		case "chr-rom-byte-size":
+			header.CHRROMSize = sourceHeader.CHRROMSize
+			header.CHRROMCalculatedSize = sourceHeader.CHRROMCalculatedSize
+		case "has-trainer":
+			header.Trainer = sourceHeader.Trainer
+		case "mirroring-type":
+			header.MirroringType = sourceHeader.MirroringType
+		case "four-screen":
+			header.FourScreen = sourceHeader.FourScreen
+		case "has-battery":
+			header.Battery = sourceHeader.Battery
+		case "mapper-number":
+			header.Mapper = sourceHeader.Mapper
+		case "vs-unisystem":
+			header.VsUnisystem = sourceHeader.VsUnisystem
+		case "playchoice-10":
+			header.PlayChoice10 = sourceHeader.PlayChoice10
+		case "tv-system":
+			header.TVSystem = sourceHeader.TVSystem
+		default:
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	if fieldName == "prg-ram-size" {
+		return false, nil
+	}
+
+	fieldValue, sourceHasField, err := GetNESROMHeaderField(sourceRom, fieldName)
+	if err != nil {
+		return false, err
+	}
+
+	_, hasField, err := GetNESROMHeaderField(rom, fieldName)
+	if err != nil {
+		return false, err
+	}
+
+	if !sourceHasField || !hasField {
+		return false, nil
+	}
+
+	return true, SetNESROMHeaderField(rom, fieldName, fieldValue)
+}
+
+func parseHeaderFieldUint(fieldName string, fieldValue string, maxValue uint64) (uint64, error) {
+	paramInt, err := strconv.ParseUint(fieldValue, 10, 64)
+	if err != nil || paramInt > maxValue {
+		return 0, &NESROMError{Text: fieldName + " must be a number from 0-" + strconv.FormatUint(maxValue, 10)}
+	}
+
+	return paramInt, nil
+}
+
+func parseHeaderFieldBool(fieldName string, fieldValue string, trueValue string, falseValue string) (bool, error) {
+	if fieldValue == trueValue {
+		return true, nil
+	} else if fieldValue == falseValue {
+		return false, nil
+	}
+
+	// Keep the usual {true|false} ordering for plain booleans
+	if trueValue == "true" {
+		return false, &NESROMError{Text: fieldName + " must be one of {" + trueValue + "|" + falseValue + "}"}
+	}
+
+	return false, &NESROMError{Text: fieldName + " must be one of {" + falseValue + "|" + trueValue + "}"}
+}
+
+func formatHeaderFieldBool(fieldValue bool, trueValue string, falseValue string) string {
+	if fieldValue {
+		return trueValue
+	}
+
+	return falseValue
+}
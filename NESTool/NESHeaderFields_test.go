@@ -0,0 +1,69 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"testing"
+)
+
+func TestApplyNESROMHeaderFields(t *testing.T) {
+	tests := []struct {
+		name              string
+		fieldValues       map[string]string
+		expectError       bool
+		expectedMapper    uint16
+		expectedSubMapper uint8
+		expectedTiming    uint8
+	}{
+		{name: "no fields", fieldValues: map[string]string{}, expectedMapper: 4, expectedSubMapper: 1},
+		{name: "several fields", fieldValues: map[string]string{"mapper-number": "5", "submapper-number": "2", "cpu-ppu-timing": "1"}, expectedMapper: 5, expectedSubMapper: 2, expectedTiming: 1},
+		{name: "unknown field", fieldValues: map[string]string{"mapper-number": "5", "mapper": "5"}, expectError: true},
+		{name: "bad value in a later field", fieldValues: map[string]string{"mapper-number": "5", "cpu-ppu-timing": "9"}, expectError: true},
+		{name: "bad value in an earlier field", fieldValues: map[string]string{"mapper-number": "5000", "cpu-ppu-timing": "1"}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rom := &NESROM{Header20: &NES20Header{Mapper: 4, SubMapper: 1}}
+
+			err := ApplyNESROMHeaderFields(rom, test.fieldValues)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				// Nothing is changed when any field can't be applied
+				if rom.Header20.Mapper != 4 || rom.Header20.SubMapper != 1 || rom.Header20.CPUPPUTiming != 0 {
+					t.Fatalf("expected the header to be unchanged, got mapper %d, submapper %d and timing %d", rom.Header20.Mapper, rom.Header20.SubMapper, rom.Header20.CPUPPUTiming)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if rom.Header20.Mapper != test.expectedMapper || rom.Header20.SubMapper != test.expectedSubMapper || rom.Header20.CPUPPUTiming != test.expectedTiming {
+				t.Fatalf("expected mapper %d, submapper %d and timing %d, got %d, %d and %d", test.expectedMapper, test.expectedSubMapper, test.expectedTiming, rom.Header20.Mapper, rom.Header20.SubMapper, rom.Header20.CPUPPUTiming)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://wiki.nesdev.com/w/index.php/INES#Variant_comparison
+// Header classification follows the detection procedure described
+// on nesdev, which is what most current emulators use.
+
+package NESTool
+
+import (
+	"bytes"
+)
+
+var (
+	NES_HEADER_TYPE_UNKNOWN      uint8 = 0
+	NES_HEADER_TYPE_ARCHAIC_INES uint8 = 1
+	NES_HEADER_TYPE_INES         uint8 = 2
+	NES_HEADER_TYPE_NES20        uint8 = 3
+	NES_HEADER_DISKDUDE                = "DiskDude!"
+)
+
+// Classify the header of a raw NES ROM file as archaic iNES, iNES 1.0,
+// or NES 2.0.  A header only counts as NES 2.0 if the ROM sizes it
+// declares fit in the file, and only counts as iNES 1.0 if bytes 12-15
+// are empty, since old tools tended to fill them with garbage.
+func GetNESHeaderType(inputFile []byte) uint8 {
+	if len(inputFile) < 16 || bytes.Compare(inputFile[0:4], []byte(NES_HEADER_MAGIC)) != 0 {
+		return NES_HEADER_TYPE_UNKNOWN
+	}
+
+	if inputFile[7]&0b00001100 == 0b00001000 && getNES20DeclaredFileSize(inputFile) <= uint64(len(inputFile)) {
+		return NES_HEADER_TYPE_NES20
+	}
+
+	if inputFile[7]&0b00001100 == 0b00000000 && inputFile[12] == 0 && inputFile[13] == 0 && inputFile[14] == 0 && inputFile[15] == 0 {
+		return NES_HEADER_TYPE_INES
+	}
+
+	return NES_HEADER_TYPE_ARCHAIC_INES
+}
+
+// Check for the "DiskDude!" signature left in bytes 7-15 by an old dumping tool
+func IsDiskDudeHeader(headerData []byte) bool {
+	if len(headerData) < 16 {
+		return false
+	}
+
+	return string(headerData[7:16]) == NES_HEADER_DISKDUDE
+}
+
+func GetNESHeaderTypeString(headerType uint8) string {
+	switch headerType {
+	case NES_HEADER_TYPE_ARCHAIC_INES:
+		return "Archaic iNES"
+	case NES_HEADER_TYPE_INES:
+		return "iNES"
+	case NES_HEADER_TYPE_NES20:
+		return "NES 2.0"
+	default:
+		return "Unknown"
+	}
+}
+
+// Clear the garbage bytes from an archaic iNES header and re-derive
+// the fields read from them.  Only the low nibble of the mapper is
+// trustworthy in these headers.  Returns whether anything was cleaned.
+func CleanNESROMHeader(rom *NESROM) bool {
+	if rom.HeaderType != NES_HEADER_TYPE_ARCHAIC_INES || rom.Header10 == nil {
+		return false
+	}
+
+	if rom.HeaderData != nil && len(rom.HeaderData) == 16 {
+		cleanHeaderData := make([]byte, 16)
+		copy(cleanHeaderData[0:7], rom.HeaderData[0:7])
+		rom.HeaderData = cleanHeaderData
+	}
+
+	rom.Header10.Mapper = rom.Header10.Mapper & 0b00001111
+	rom.Header10.VsUnisystem = false
+	rom.Header10.PlayChoice10 = false
+	rom.Header10.PRGRAMSize = 0
+	rom.Header10.TVSystem = false
+	rom.HeaderType = NES_HEADER_TYPE_INES
+
+	return true
+}
+
+// Get the size a file would need to be to hold everything an NES 2.0
+// header declares
+func getNES20DeclaredFileSize(inputFile []byte) uint64 {
+	var prgRomSize uint64
+	var chrRomSize uint64
+
+	// Exponent notation can declare sizes far beyond any real file
+	if (inputFile[9]&0b00001111 == 0b00001111 && inputFile[4]>>2 > 40) || (inputFile[9]&0b11110000 == 0b11110000 && inputFile[5]>>2 > 40) {
+		return ^uint64(0)
+	}
+
+	if inputFile[9]&0b00001111 != 0b00001111 {
+		prgRomSize = 16 * 1024 * (uint64(inputFile[4]) | (uint64(inputFile[9]&0b00001111) << 8))
+	} else {
+		prgRomSize = (1 << ((inputFile[4] & 0b11111100) >> 2)) * uint64(((inputFile[4]&0b00000011)*2)+1)
+	}
+
+	if inputFile[9]&0b11110000 != 0b11110000 {
+		chrRomSize = 8 * 1024 * (uint64(inputFile[5]) | (uint64((inputFile[9]&0b11110000)>>4) << 8))
+	} else {
+		chrRomSize = (1 << ((inputFile[5] & 0b11111100) >> 2)) * uint64(((inputFile[5]&0b00000011)*2)+1)
+	}
+
+	declaredSize := 16 + prgRomSize + chrRomSize
+	if inputFile[6]&0b00000100 == 0b00000100 {
+		declaredSize = declaredSize + 512
+	}
+
+	return declaredSize
+}
+
+// Get the PRG ROM, CHR ROM and trainer sizes a raw header declares,
+// and whether it has the NES 2.0 bits set.  This works without any ROM
+// data, so it can describe files that are too short to decode.  Unlike
+// DecodeNESROM, it doesn't fall back to iNES when the NES 2.0 sizes
+// don't fit in the file, since that's what it's used to check.
+func GetNESHeaderDeclaredSizes(headerData []byte) (uint64, uint64, uint64, bool) {
+	if len(headerData) < 16 || bytes.Compare(headerData[0:4], []byte(NES_HEADER_MAGIC)) != 0 {
+		return 0, 0, 0, false
+	}
+
+	var trainerSize uint64
+	if headerData[6]&0b00000100 == 0b00000100 {
+		trainerSize = 512
+	}
+
+	if (headerData[7]&NES_20_AND_MASK) != NES_20_AND_MASK || (headerData[7]|NES_20_OR_MASK) != NES_20_OR_MASK {
+		return 16 * 1024 * uint64(headerData[4]), 8 * 1024 * uint64(headerData[5]), trainerSize, false
+	}
+
+	prgRomSize := getNES20DeclaredROMSize(headerData[4], headerData[9]&0b00001111, 16*1024)
+	chrRomSize := getNES20DeclaredROMSize(headerData[5], (headerData[9]&0b11110000)>>4, 8*1024)
+
+	return prgRomSize, chrRomSize, trainerSize, true
+}
+
+// Exponents over 60 don't fit in 64 bits, so they're capped at the
+// largest size rather than wrapping around
+func getNES20DeclaredROMSize(sizeByte byte, sizeNibble byte, unitSize uint64) uint64 {
+	if sizeNibble != 0b00001111 {
+		return unitSize * (uint64(sizeByte) | (uint64(sizeNibble) << 8))
+	}
+
+	sizeExponent := (sizeByte & 0b11111100) >> 2
+	if sizeExponent > 60 {
+		return ^uint64(0)
+	}
+
+	return (1 << sizeExponent) * uint64(((sizeByte&0b00000011)*2)+1)
+}
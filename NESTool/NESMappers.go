@@ -0,0 +1,288 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://wiki.nesdev.com/w/index.php/Mapper
+// https://wiki.nesdev.com/w/index.php/NES_2.0_submappers
+// A registry of what's known about commonly used mappers.  It's not an
+// exhaustive list, so anything looking things up here needs to handle
+// mappers that aren't in it.
+
+package NESTool
+
+import (
+	"strconv"
+)
+
+type MapperInfo struct {
+	Number      uint16
+	Name        string
+	BoardFamily string
+	SubMappers  map[uint8]string
+
+	// Sizes in bytes the mapper's boards are known to use.  A nil list
+	// means any size.  A CHR ROM size of 0 means the boards use CHR RAM.
+	PRGROMSizes []uint64
+	CHRROMSizes []uint64
+
+	// NES 2.0 shift counts used when a header doesn't say otherwise
+	DefaultCHRRAMSize uint8
+	EEPROMSize        uint8
+
+	PRGRAMTypical    bool
+	BatterySupported bool
+
+	// nes20db mirroring codes, indexed by getMirroringIndex.  An empty
+	// code means the combination isn't valid for the mapper.
+	MirroringCodes [4]string
+}
+
+var (
+	MAPPER_MIRRORING_HORIZONTAL  = "H"
+	MAPPER_MIRRORING_VERTICAL    = "V"
+	MAPPER_MIRRORING_FOUR_SCREEN = "4"
+	MAPPER_MIRRORING_ONE_SCREEN  = "1"
+	MAPPER_MIRRORING_ONE_SCREEN0 = "0"
+)
+
+var defaultMirroringCodes = [4]string{MAPPER_MIRRORING_HORIZONTAL, MAPPER_MIRRORING_VERTICAL, MAPPER_MIRRORING_FOUR_SCREEN, ""}
+
+// Bus conflict submappers shared by the simple discrete logic boards
+var busConflictSubMappers = map[uint8]string{
+	0: "Unspecified bus conflicts",
+	1: "No bus conflicts",
+	2: "AND-type bus conflicts",
+}
+
+var mapperRegistry = map[uint16]*MapperInfo{
+	0:   {Name: "NROM", BoardFamily: "Nintendo NROM", PRGROMSizes: []uint64{16384, 32768}, CHRROMSizes: []uint64{0, 8192}, DefaultCHRRAMSize: 7},
+	1:   {Name: "MMC1", BoardFamily: "Nintendo SxROM", SubMappers: map[uint8]string{0: "Normal", 5: "SEROM/SHROM/SH1ROM (fixed PRG ROM)"}, PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: append([]uint64{0}, getMapperSizes(8192, 131072)...), DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	2:   {Name: "UxROM", BoardFamily: "Nintendo UxROM", SubMappers: busConflictSubMappers, PRGROMSizes: getMapperSizes(32768, 4194304), CHRROMSizes: []uint64{0, 8192}, DefaultCHRRAMSize: 7},
+	3:   {Name: "CNROM", BoardFamily: "Nintendo CNROM", SubMappers: busConflictSubMappers, PRGROMSizes: []uint64{16384, 32768}, CHRROMSizes: getMapperSizes(8192, 2097152), DefaultCHRRAMSize: 7},
+	4:   {Name: "MMC3", BoardFamily: "Nintendo TxROM", SubMappers: map[uint8]string{0: "MMC3C", 1: "MMC6", 3: "MC-ACC", 4: "MMC3A"}, PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: append([]uint64{0}, getMapperSizes(8192, 262144)...), DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	5:   {Name: "MMC5", BoardFamily: "Nintendo ExROM", PRGROMSizes: getMapperSizes(32768, 1048576), CHRROMSizes: append([]uint64{0}, getMapperSizes(8192, 1048576)...), DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	7:   {Name: "AxROM", BoardFamily: "Nintendo AxROM", SubMappers: busConflictSubMappers, PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 7},
+	9:   {Name: "MMC2", BoardFamily: "Nintendo PxROM", PRGROMSizes: getMapperSizes(32768, 131072), CHRROMSizes: getMapperSizes(8192, 131072), DefaultCHRRAMSize: 7},
+	10:  {Name: "MMC4", BoardFamily: "Nintendo FxROM", PRGROMSizes: getMapperSizes(65536, 262144), CHRROMSizes: getMapperSizes(8192, 131072), DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	11:  {Name: "Color Dreams", BoardFamily: "Color Dreams", PRGROMSizes: getMapperSizes(32768, 131072), CHRROMSizes: getMapperSizes(8192, 131072), DefaultCHRRAMSize: 7},
+	13:  {Name: "CPROM", BoardFamily: "Nintendo CPROM", PRGROMSizes: []uint64{32768}, CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 8},
+	16:  {Name: "Bandai FCG", BoardFamily: "Bandai FCG", SubMappers: map[uint8]string{0: "Unspecified", 4: "FCG-1/FCG-2", 5: "LZ93D50 with optional 24C02 EEPROM"}, DefaultCHRRAMSize: 7, EEPROMSize: 2, BatterySupported: true},
+	19:  {Name: "Namco 129/163", BoardFamily: "Namco", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	21:  {Name: "VRC4a/VRC4c", BoardFamily: "Konami VRC2/VRC4", SubMappers: map[uint8]string{0: "Unspecified", 1: "VRC4a", 2: "VRC4c"}, DefaultCHRRAMSize: 7, BatterySupported: true},
+	22:  {Name: "VRC2a", BoardFamily: "Konami VRC2/VRC4", DefaultCHRRAMSize: 7},
+	23:  {Name: "VRC2b/VRC4e/VRC4f", BoardFamily: "Konami VRC2/VRC4", SubMappers: map[uint8]string{0: "Unspecified", 1: "VRC4f", 2: "VRC4e", 3: "VRC2b"}, DefaultCHRRAMSize: 7, BatterySupported: true},
+	24:  {Name: "VRC6a", BoardFamily: "Konami VRC6", DefaultCHRRAMSize: 7},
+	25:  {Name: "VRC2c/VRC4b/VRC4d", BoardFamily: "Konami VRC2/VRC4", SubMappers: map[uint8]string{0: "Unspecified", 1: "VRC4b", 2: "VRC4d", 3: "VRC2c"}, DefaultCHRRAMSize: 7, BatterySupported: true},
+	26:  {Name: "VRC6b", BoardFamily: "Konami VRC6", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	28:  {Name: "Action 53", BoardFamily: "Homebrew", CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 9},
+	30:  {Name: "UNROM 512", BoardFamily: "Homebrew", PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 9, BatterySupported: true, MirroringCodes: [4]string{MAPPER_MIRRORING_HORIZONTAL, MAPPER_MIRRORING_VERTICAL, MAPPER_MIRRORING_ONE_SCREEN, MAPPER_MIRRORING_FOUR_SCREEN}},
+	34:  {Name: "BNROM/NINA-001", BoardFamily: "Nintendo BNROM/AVE NINA-001", SubMappers: map[uint8]string{0: "Unspecified", 1: "NINA-001", 2: "BNROM"}, PRGROMSizes: getMapperSizes(32768, 4194304), CHRROMSizes: append([]uint64{0}, getMapperSizes(8192, 65536)...), DefaultCHRRAMSize: 7},
+	66:  {Name: "GxROM", BoardFamily: "Nintendo GxROM", PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: getMapperSizes(8192, 131072), DefaultCHRRAMSize: 7},
+	68:  {Name: "Sunsoft-4", BoardFamily: "Sunsoft", SubMappers: map[uint8]string{0: "Normal", 1: "Nantettatte!! Baseball (licensing IC)"}, DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	69:  {Name: "Sunsoft FME-7", BoardFamily: "Sunsoft", PRGROMSizes: getMapperSizes(32768, 524288), CHRROMSizes: append([]uint64{0}, getMapperSizes(8192, 262144)...), DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	71:  {Name: "Codemasters", BoardFamily: "Camerica/Codemasters", SubMappers: map[uint8]string{0: "Hardwired mirroring", 1: "Fire Hawk (mapper-controlled one-screen mirroring)"}, CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 7},
+	73:  {Name: "VRC3", BoardFamily: "Konami VRC3", DefaultCHRRAMSize: 7, PRGRAMTypical: true},
+	75:  {Name: "VRC1", BoardFamily: "Konami VRC1", DefaultCHRRAMSize: 7},
+	80:  {Name: "Taito X1-005", BoardFamily: "Taito", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	82:  {Name: "Taito X1-017", BoardFamily: "Taito", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	85:  {Name: "VRC7", BoardFamily: "Konami VRC7", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	111: {Name: "GTROM", BoardFamily: "Homebrew", CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 9},
+	118: {Name: "TxSROM", BoardFamily: "Nintendo TxSROM", DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	119: {Name: "TQROM", BoardFamily: "Nintendo TQROM", DefaultCHRRAMSize: 7, PRGRAMTypical: true},
+	159: {Name: "Bandai LZ93D50 with 24C01", BoardFamily: "Bandai FCG", DefaultCHRRAMSize: 7, EEPROMSize: 1, BatterySupported: true},
+	206: {Name: "Namco 118/DxROM", BoardFamily: "Namco/Nintendo DxROM", DefaultCHRRAMSize: 7},
+	210: {Name: "Namco 175/340", BoardFamily: "Namco", SubMappers: map[uint8]string{0: "Unspecified", 1: "Namco 175", 2: "Namco 340"}, DefaultCHRRAMSize: 7, PRGRAMTypical: true, BatterySupported: true},
+	218: {Name: "Magic Floor", BoardFamily: "Homebrew", CHRROMSizes: []uint64{0}, DefaultCHRRAMSize: 7, MirroringCodes: [4]string{MAPPER_MIRRORING_HORIZONTAL, MAPPER_MIRRORING_VERTICAL, MAPPER_MIRRORING_ONE_SCREEN0, MAPPER_MIRRORING_ONE_SCREEN}},
+}
+
+func init() {
+	for mapper := range mapperRegistry {
+		mapperRegistry[mapper].Number = mapper
+		if mapperRegistry[mapper].MirroringCodes[0] == "" {
+			mapperRegistry[mapper].MirroringCodes = defaultMirroringCodes
+		}
+	}
+}
+
+// Get what's known about a mapper, or nil if it isn't in the registry
+func GetMapperInfo(mapper uint16) *MapperInfo {
+	return mapperRegistry[mapper]
+}
+
+func GetMapperName(mapper uint16) string {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil {
+		return "Unknown"
+	}
+
+	return mapperInfo.Name
+}
+
+// Submapper 0 is always valid, so it's only named when the registry says so
+func GetSubMapperName(mapper uint16, subMapper uint8) string {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil || mapperInfo.SubMappers == nil {
+		return ""
+	}
+
+	return mapperInfo.SubMappers[subMapper]
+}
+
+// Check whether a submapper is defined for a mapper.  Only mappers
+// with submappers listed in the registry can be checked, so any
+// submapper of any other mapper counts as known.
+func IsKnownSubMapper(mapper uint16, subMapper uint8) bool {
+	if subMapper == 0 {
+		return true
+	}
+
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil || mapperInfo.SubMappers == nil {
+		return true
+	}
+
+	_, isKnown := mapperInfo.SubMappers[subMapper]
+	return isKnown
+}
+
+func IsValidPRGROMSizeForMapper(mapper uint16, size uint64) bool {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil {
+		return true
+	}
+
+	return isMapperSizeListed(mapperInfo.PRGROMSizes, size)
+}
+
+func IsValidCHRROMSizeForMapper(mapper uint16, size uint64) bool {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil {
+		return true
+	}
+
+	return isMapperSizeListed(mapperInfo.CHRROMSizes, size)
+}
+
+// Get the CHR RAM size to assume for a mapper with no CHR ROM, as an
+// NES 2.0 shift count.  Unknown mappers get the usual 8 KiB.
+func GetDefaultCHRRAMSizeForMapper(mapper uint16) uint8 {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil || mapperInfo.DefaultCHRRAMSize == 0 {
+		return 7
+	}
+
+	return mapperInfo.DefaultCHRRAMSize
+}
+
+// Get the nes20db mirroring code for a mapper and header mirroring
+// bits.  Returns false if the combination isn't valid for the mapper.
+func GetNES20DBMirroring(mapper uint16, mirroringType bool, fourScreen bool) (string, bool) {
+	mirroringCodes := defaultMirroringCodes
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo != nil {
+		mirroringCodes = mapperInfo.MirroringCodes
+	}
+
+	mirroringCode := mirroringCodes[getMirroringIndex(mirroringType, fourScreen)]
+	return mirroringCode, mirroringCode != ""
+}
+
+// Get the header mirroring bits for a mapper and nes20db mirroring
+// code.  Returns false if the code isn't valid for the mapper.
+func GetMirroringFromNES20DB(mapper uint16, mirroringCode string) (bool, bool, bool) {
+	mirroringCodes := defaultMirroringCodes
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo != nil {
+		mirroringCodes = mapperInfo.MirroringCodes
+	}
+
+	for index := range mirroringCodes {
+		if mirroringCodes[index] != "" && mirroringCodes[index] == mirroringCode {
+			return index&0b01 != 0, index&0b10 != 0, true
+		}
+	}
+
+	return false, false, false
+}
+
+// Describe what the mirroring bits mean for a mapper
+func GetMirroringString(mapper uint16, mirroringType bool, fourScreen bool) string {
+	mirroringCode, isValid := GetNES20DBMirroring(mapper, mirroringType, fourScreen)
+	if !isValid {
+		return "Invalid for mapper " + strconv.Itoa(int(mapper))
+	}
+
+	switch mirroringCode {
+	case MAPPER_MIRRORING_HORIZONTAL:
+		return "Horizontal or mapper-controlled"
+	case MAPPER_MIRRORING_VERTICAL:
+		return "Vertical"
+	case MAPPER_MIRRORING_FOUR_SCREEN:
+		return "Four-screen"
+	case MAPPER_MIRRORING_ONE_SCREEN:
+		return "One-screen"
+	case MAPPER_MIRRORING_ONE_SCREEN0:
+		return "One-screen (nametable 0)"
+	default:
+		return "Unknown"
+	}
+}
+
+// Check whether a mapper gives the mirroring bits a meaning other than
+// the usual horizontal, vertical and four-screen
+func HasMapperSpecificMirroring(mapper uint16) bool {
+	mapperInfo := GetMapperInfo(mapper)
+	return mapperInfo != nil && mapperInfo.MirroringCodes != defaultMirroringCodes
+}
+
+func getMirroringIndex(mirroringType bool, fourScreen bool) int {
+	mirroringIndex := 0
+	if mirroringType {
+		mirroringIndex = mirroringIndex | 0b01
+	}
+
+	if fourScreen {
+		mirroringIndex = mirroringIndex | 0b10
+	}
+
+	return mirroringIndex
+}
+
+func isMapperSizeListed(sizes []uint64, size uint64) bool {
+	if sizes == nil {
+		return true
+	}
+
+	for index := range sizes {
+		if sizes[index] == size {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get every power of two size from minSize to maxSize
+func getMapperSizes(minSize uint64, maxSize uint64) []uint64 {
+	sizes := make([]uint64, 0)
+	for size := minSize; size <= maxSize; size = size << 1 {
+		sizes = append(sizes, size)
+	}
+
+	return sizes
+}
@@ -0,0 +1,50 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"testing"
+)
+
+func TestIsKnownSubMapper(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapper    uint16
+		subMapper uint8
+		expected  bool
+	}{
+		{name: "submapper 0 of a listed mapper", mapper: 1, subMapper: 0, expected: true},
+		{name: "listed submapper", mapper: 1, subMapper: 5, expected: true},
+		{name: "unlisted submapper of a mapper with submappers", mapper: 1, subMapper: 3, expected: false},
+		{name: "shared bus conflict submappers", mapper: 2, subMapper: 2, expected: true},
+		{name: "past the shared bus conflict submappers", mapper: 2, subMapper: 3, expected: false},
+		{name: "registered mapper without submappers", mapper: 0, subMapper: 1, expected: true},
+		{name: "mapper missing from the registry", mapper: 4000, subMapper: 7, expected: true},
+		{name: "submapper 0 of a mapper missing from the registry", mapper: 4000, subMapper: 0, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if IsKnownSubMapper(test.mapper, test.subMapper) != test.expected {
+				t.Fatalf("expected %v for mapper %d submapper %d", test.expected, test.mapper, test.subMapper)
+			}
+		})
+	}
+}
@@ -149,6 +149,26 @@ type NESROM struct {
 	CHRROMData   []byte
 	MiscROMData  []byte
 	HeaderData   []byte
+	UNIFVersion  uint32
+	UNIFChunks   []*UNIFChunk
+	UNIFDumper   *UNIFDumperInfo
+	HeaderType   uint8
+	HeaderSource string
+}
+
+type UNIFChunk struct {
+	ID    string
+	Size  uint64
+	CRC32 uint32
+	Data  []byte
+}
+
+type UNIFDumperInfo struct {
+	DumperName   string
+	DumpDay      uint8
+	DumpMonth    uint8
+	DumpYear     uint16
+	DumpingAgent string
 }
 
 type NESROMError struct {
@@ -159,6 +179,48 @@ func (r *NESROMError) Error() string {
 	return r.Text
 }
 
+func (dumper *UNIFDumperInfo) String() string {
+	returnString := ""
+
+	if dumper.DumperName != "" {
+		returnString = returnString + "UNIF Dumper Name: " + dumper.DumperName + "\n"
+	}
+
+	if dumper.GetDumpDateString() != "" {
+		returnString = returnString + "UNIF Dump Date: " + dumper.GetDumpDateString() + "\n"
+	}
+
+	if dumper.DumpingAgent != "" {
+		returnString = returnString + "UNIF Dumping Agent: " + dumper.DumpingAgent + "\n"
+	}
+
+	return returnString
+}
+
+// Get the dump date as YYYY-MM-DD, or an empty string if it wasn't recorded
+func (dumper *UNIFDumperInfo) GetDumpDateString() string {
+	if dumper.DumpYear == 0 && dumper.DumpMonth == 0 && dumper.DumpDay == 0 {
+		return ""
+	}
+
+	yearString := strconv.Itoa(int(dumper.DumpYear))
+	for len(yearString) < 4 {
+		yearString = "0" + yearString
+	}
+
+	monthString := strconv.Itoa(int(dumper.DumpMonth))
+	if len(monthString) < 2 {
+		monthString = "0" + monthString
+	}
+
+	dayString := strconv.Itoa(int(dumper.DumpDay))
+	if len(dayString) < 2 {
+		dayString = "0" + dayString
+	}
+
+	return yearString + "-" + monthString + "-" + dayString
+}
+
 func (rom *NESROM) String() string {
 	returnString := ""
 
@@ -170,6 +232,14 @@ func (rom *NESROM) String() string {
 		return ""
 	}
 
+	if rom.HeaderType != NES_HEADER_TYPE_UNKNOWN {
+		returnString = returnString + "ROM Header Type: " + GetNESHeaderTypeString(rom.HeaderType)
+		if IsDiskDudeHeader(rom.HeaderData) {
+			returnString = returnString + " (DiskDude!)"
+		}
+		returnString = returnString + "\n"
+	}
+
 	if rom.Name != "" {
 		returnString = returnString + "ROM Name: " + rom.Name + "\n"
 	} else if rom.Filename != "" {
@@ -188,6 +258,10 @@ func (rom *NESROM) String() string {
 	returnString = returnString + "ROM SHA1: " + strings.ToUpper(hex.EncodeToString(rom.SHA1[:])) + "\n"
 	returnString = returnString + "ROM SHA256: " + strings.ToUpper(hex.EncodeToString(rom.SHA256[:])) + "\n"
 
+	if rom.UNIFDumper != nil {
+		returnString = returnString + rom.UNIFDumper.String()
+	}
+
 	if rom.Header20 != nil {
 		returnString = returnString + "PRG ROM Size: " + strconv.Itoa(int(rom.Header20.PRGROMCalculatedSize)) + " bytes\n"
 
@@ -319,34 +393,34 @@ func (rom *NESROM) String() string {
 			returnString = returnString + "Hard-wired Four Screen Mode: Yes\n"
 		}
 
+		if HasMapperSpecificMirroring(rom.Header20.Mapper) {
+			returnString = returnString + "Mapper-specific Mirroring: " + GetMirroringString(rom.Header20.Mapper, rom.Header20.MirroringType, rom.Header20.FourScreen) + "\n"
+		}
+
 		if rom.Header20.ConsoleType < 3 {
 			if rom.Header20.ConsoleType == 0 {
 				returnString = returnString + "Console Type: Regular NES/Famicom/Dendy\n"
 			} else if rom.Header20.ConsoleType == 1 {
 				returnString = returnString + "Console Type: Nintendo Vs. System\n"
-				returnString = returnString + "Vs. PPU Type: " + getVsPPUTypeString(rom.Header20.VsPPUType) + "\n"
-				returnString = returnString + "Vs. System Type: " + getVsSystemTypeString(rom.Header20.VsHardwareType) + "\n"
+				returnString = returnString + "Vs. PPU Type: " + VsPPUType(rom.Header20.VsPPUType).String() + "\n"
+				returnString = returnString + "Vs. System Type: " + VsHardwareType(rom.Header20.VsHardwareType).String() + "\n"
 			} else {
 				returnString = returnString + "Console Type: Playchoice 10\n"
 			}
 		} else {
-			returnString = returnString + "Console Type: " + getConsoleTypeString(rom.Header20.ExtendedConsoleType) + "\n"
+			returnString = returnString + "Console Type: " + ExtendedConsoleType(rom.Header20.ExtendedConsoleType).String() + "\n"
 		}
 
-		returnString = returnString + "Mapper: " + strconv.Itoa(int(rom.Header20.Mapper)) + "\n"
-		returnString = returnString + "Submapper: " + strconv.Itoa(int(rom.Header20.SubMapper)) + "\n"
-
-		if rom.Header20.CPUPPUTiming == 0 {
-			returnString = returnString + "CPU/PPU Timing: RP2C02 (\"NTSC NES\")\n"
-		} else if rom.Header20.CPUPPUTiming == 1 {
-			returnString = returnString + "CPU/PPU Timing: RP2C07 (\"Licensed PAL NES\")\n"
-		} else if rom.Header20.CPUPPUTiming == 2 {
-			returnString = returnString + "CPU/PPU Timing: Multiple-region\n"
-		} else if rom.Header20.CPUPPUTiming == 3 {
-			returnString = returnString + "CPU/PPU Timing: UMC 6527P (\"Dendy\")\n"
+		returnString = returnString + "Mapper: " + strconv.Itoa(int(rom.Header20.Mapper)) + getMapperDescriptionString(rom.Header20.Mapper) + "\n"
+		returnString = returnString + "Submapper: " + strconv.Itoa(int(rom.Header20.SubMapper))
+		if GetSubMapperName(rom.Header20.Mapper, rom.Header20.SubMapper) != "" {
+			returnString = returnString + " (" + GetSubMapperName(rom.Header20.Mapper, rom.Header20.SubMapper) + ")"
 		}
+		returnString = returnString + "\n"
+
+		returnString = returnString + "CPU/PPU Timing: " + CPUPPUTiming(rom.Header20.CPUPPUTiming).String() + "\n"
 
-		returnString = returnString + "Default Expansion Device: " + getDefaultExpansionDeviceString(rom.Header20.DefaultExpansion) + "\n"
+		returnString = returnString + "Default Expansion Device: " + DefaultExpansion(rom.Header20.DefaultExpansion).String() + "\n"
 
 		if rom.HeaderData != nil {
 			returnString = returnString + "ROM Header (Existing):   " + strings.ToUpper(hex.EncodeToString(rom.HeaderData)) + "\n"
@@ -418,7 +492,7 @@ func (rom *NESROM) String() string {
 				returnString = returnString + "Mirroring Type: Vertical (horizontal arrangement) (CIRAM A10 = PPU A10)\n"
 			}
 		} else {
-			returnString = returnString + "Mirroring Type: N/A (Four-Screen VRAM)"
+			returnString = returnString + "Mirroring Type: N/A (Four-Screen VRAM)\n"
 		}
 
 		if !rom.Header10.Battery {
@@ -439,7 +513,11 @@ func (rom *NESROM) String() string {
 			returnString = returnString + "Playchoice 10: Yes\n"
 		}
 
-		returnString = returnString + "Mapper: " + strconv.Itoa(int(rom.Header10.Mapper)) + "\n"
+		if HasMapperSpecificMirroring(uint16(rom.Header10.Mapper)) {
+			returnString = returnString + "Mapper-specific Mirroring: " + GetMirroringString(uint16(rom.Header10.Mapper), rom.Header10.MirroringType, rom.Header10.FourScreen) + "\n"
+		}
+
+		returnString = returnString + "Mapper: " + strconv.Itoa(int(rom.Header10.Mapper)) + getMapperDescriptionString(uint16(rom.Header10.Mapper)) + "\n"
 
 		if !rom.Header10.TVSystem {
 			returnString = returnString + "TV System: NTSC\n"
@@ -494,7 +572,11 @@ func DecodeNESROM(inputFile []byte, enableInes bool, preserveTrainer bool, relat
 		return romData, &NESROMError{Text: "Unable to find NES magic."}
 	}
 
-	if (inputFile[7]&NES_20_AND_MASK) != NES_20_AND_MASK || (inputFile[7]|NES_20_OR_MASK) != NES_20_OR_MASK {
+	romData.HeaderType = GetNESHeaderType(inputFile)
+
+	// A header with the NES 2.0 bits set whose sizes don't fit in the file
+	// is an archaic iNES header with garbage in byte 7, so it's decoded as iNES
+	if romData.HeaderType == NES_HEADER_TYPE_ARCHAIC_INES || (inputFile[7]&NES_20_AND_MASK) != NES_20_AND_MASK || (inputFile[7]|NES_20_OR_MASK) != NES_20_OR_MASK {
 		if !enableInes {
 			return romData, &NESROMError{Text: "Not an NES 2.0 ROM."}
 		} else {
@@ -675,7 +757,8 @@ func EncodeNESROM(romModel *NESROM, enableInes bool, truncateRom bool, preserveT
 			rawRomBytes = romModel.ROMData
 		}
 	} else if headerVersion == 1 {
-		if truncateRom && romModel.Header20.MiscROMs == 0 {
+		// iNES has no misc ROMs, so anything after the CHR ROM can go
+		if truncateRom {
 			rawRomBytes = romModel.ROMData[0:(romModel.Header10.PRGROMCalculatedSize + romModel.Header10.CHRROMCalculatedSize)]
 		} else {
 			rawRomBytes = romModel.ROMData
@@ -896,7 +979,8 @@ func FactorRomSize(romSize uint64, romType uint64) (uint16, uint8, uint8) {
 }
 
 // Update size metadata based on the byte slice size, the total size of the segment in metadata, or the
-// factored exponential size of the segment in metadata
+// factored exponential size of the segment in metadata.  For the factored size, a size in plain units
+// takes priority over the exponent form, the same as when the header is encoded.
 func UpdateSizes(nesRom *NESROM, prgCanonicalSize uint64, chrCanonicalSize uint64) error {
 	if nesRom.ROMData != nil {
 		nesRom.Size = uint64(len(nesRom.ROMData))
@@ -912,7 +996,13 @@ func UpdateSizes(nesRom *NESROM, prgCanonicalSize uint64, chrCanonicalSize uint6
 		}
 
 		if prgCanonicalSize == PRG_CANONICAL_SIZE_FACTORED {
-			nesRom.Header20.PRGROMCalculatedSize = (1 << nesRom.Header20.PRGROMSizeExponent) * uint64((nesRom.Header20.PRGROMSizeMultiplier*2)+1)
+			if nesRom.Header20.PRGROMSize > 0 {
+				nesRom.Header20.PRGROMCalculatedSize = 16 * 1024 * uint64(nesRom.Header20.PRGROMSize)
+			} else if nesRom.Header20.PRGROMSizeExponent > 0 || nesRom.Header20.PRGROMSizeMultiplier > 0 {
+				nesRom.Header20.PRGROMCalculatedSize = (1 << nesRom.Header20.PRGROMSizeExponent) * uint64((nesRom.Header20.PRGROMSizeMultiplier*2)+1)
+			} else {
+				nesRom.Header20.PRGROMCalculatedSize = 0
+			}
 		}
 
 		if chrCanonicalSize == CHR_CANONICAL_SIZE_ROM && nesRom.CHRROMData != nil {
@@ -924,7 +1014,13 @@ func UpdateSizes(nesRom *NESROM, prgCanonicalSize uint64, chrCanonicalSize uint6
 		}
 
 		if chrCanonicalSize == CHR_CANONICAL_SIZE_FACTORED {
-			nesRom.Header20.CHRROMCalculatedSize = (1 << nesRom.Header20.CHRROMSizeExponent) * uint64((nesRom.Header20.CHRROMSizeMultiplier*2)+1)
+			if nesRom.Header20.CHRROMSize > 0 {
+				nesRom.Header20.CHRROMCalculatedSize = 8 * 1024 * uint64(nesRom.Header20.CHRROMSize)
+			} else if nesRom.Header20.CHRROMSizeExponent > 0 || nesRom.Header20.CHRROMSizeMultiplier > 0 {
+				nesRom.Header20.CHRROMCalculatedSize = (1 << nesRom.Header20.CHRROMSizeExponent) * uint64((nesRom.Header20.CHRROMSizeMultiplier*2)+1)
+			} else {
+				nesRom.Header20.CHRROMCalculatedSize = 0
+			}
 		}
 
 		if nesRom.Header20.MiscROMs > 0 && nesRom.MiscROMData != nil && len(nesRom.MiscROMData) > 0 {
@@ -1175,194 +1271,12 @@ func getSplitRomData(inputData []byte, prgRomSize uint64, chrRomSize uint64) ([]
 	return prgRomData, chrRomData, miscRomData, nil
 }
 
-func getConsoleTypeString(consoleType uint8) string {
-	switch consoleType {
-	case 3:
-		return "Regular Famiclone, but with CPU that supports Decimal Mode (e.g. Bit Corporation Creator)"
-	case 4:
-		return "V.R. Technology VT01 with monochrome palette"
-	case 5:
-		return "V.R. Technology VT01 with red/cyan STN palette"
-	case 6:
-		return "V.R. Technology VT02"
-	case 7:
-		return "V.R. Technology VT03"
-	case 8:
-		return "V.R. Technology VT09"
-	case 9:
-		return "V.R. Technology VT32"
-	case 10:
-		return "V.R. Technology VT369"
-	case 11:
-		return "UMC UM6578"
-	default:
-		return "Unknown/Undefined"
-	}
-}
-
-func getVsPPUTypeString(vsPpuType uint8) string {
-	switch vsPpuType {
-	case 0:
-		return "RP2C03B"
-	case 1:
-		return "RP2C03G"
-	case 2:
-		return "RP2C04-0001"
-	case 3:
-		return "RP2C04-0002"
-	case 4:
-		return "RP2C04-0003"
-	case 5:
-		return "RP2C04-0004"
-	case 6:
-		return "RC2C03B"
-	case 7:
-		return "RC2C03C"
-	case 8:
-		return "RC2C05-01 ($2002 AND $?? =$1B)"
-	case 9:
-		return "RC2C05-02 ($2002 AND $3F =$3D)"
-	case 10:
-		return "RC2C05-03 ($2002 AND $1F =$1C)"
-	case 11:
-		return "RC2C05-04 ($2002 AND $1F =$1B)"
-	case 12:
-		return "RC2C05-05 ($2002 AND $1F =unknown)"
-	default:
-		return "Unknown/Undefined"
-	}
-}
-
-func getVsSystemTypeString(vsSystemType uint8) string {
-	switch vsSystemType {
-	case 0:
-		return "Vs. Unisystem (normal)"
-	case 1:
-		return "Vs. Unisystem (RBI Baseball protection)"
-	case 2:
-		return "Vs. Unisystem (TKO Boxing protection)"
-	case 3:
-		return "Vs. Unisystem (Super Xevious protection)"
-	case 4:
-		return "Vs. Unisystem (Vs. Ice Climber Japan protection)"
-	case 5:
-		return "Vs. Dual System (normal)"
-	case 6:
-		return "Vs. Dual System (Raid on Bungeling Bay protection)"
-	default:
-		return "Unknown/Undefined"
+// Get the name and board family of a mapper for display, if it's known
+func getMapperDescriptionString(mapper uint16) string {
+	mapperInfo := GetMapperInfo(mapper)
+	if mapperInfo == nil {
+		return ""
 	}
-}
 
-func getDefaultExpansionDeviceString(defaultExpansion uint8) string {
-	switch defaultExpansion {
-	case 0:
-		return "Unspecified"
-	case 1:
-		return "Standard NES/Famicom controllers"
-	case 2:
-		return "NES Four Score/Satellite with two additional standard controllers"
-	case 3:
-		return "Famicom Four Players Adapter with two additional standard controllers"
-	case 4:
-		return "Vs. System"
-	case 5:
-		return "Vs. System with reversed inputs"
-	case 6:
-		return "Vs. Pinball (Japan)"
-	case 7:
-		return "Vs. Zapper"
-	case 8:
-		return "Zapper ($4017)"
-	case 9:
-		return "Two Zappers"
-	case 10:
-		return "Bandai Hyper Shot Lightgun"
-	case 11:
-		return "Power Pad Side A"
-	case 12:
-		return "Power Pad Side B"
-	case 13:
-		return "Family Trainer Side A"
-	case 14:
-		return "Family Trainer Side B"
-	case 15:
-		return "Arkanoid Vaus Controller (NES)"
-	case 16:
-		return "Arkanoid Vaus Controller (Famicom)"
-	case 17:
-		return "Two Vaus Controllers plus Famicom Data Recorder"
-	case 18:
-		return "Konami Hyper Shot Controller"
-	case 19:
-		return "Coconuts Pachinko Controller"
-	case 20:
-		return "Exciting Boxing Punching Bag (Blowup Doll)"
-	case 21:
-		return "Jissen Mahjong Controller"
-	case 22:
-		return "Party Tap"
-	case 23:
-		return "Oeka Kids Tablet"
-	case 24:
-		return "Sunsoft Barcode Battler"
-	case 25:
-		return "Miracle Piano Keyboard"
-	case 26:
-		return "Pokkun Moguraa (Whack-a-Mole Mat and Mallet)"
-	case 27:
-		return "Top Rider (Inflatable Bicycle)"
-	case 28:
-		return "Double-Fisted (Requires or allows use of two controllers by one player)"
-	case 29:
-		return "Famicom 3D System"
-	case 30:
-		return "Doremikko Keyboard"
-	case 31:
-		return "R.O.B. Gyro Set"
-	case 32:
-		return "Famicom Data Recorder (don't emulate keyboard)"
-	case 33:
-		return "ASCII Turbo File"
-	case 34:
-		return "IGS Storage Battle Box"
-	case 35:
-		return "Family BASIC Keyboard plus Famicom Data Recorder"
-	case 36:
-		return "Dongda PEC-586 Keyboard"
-	case 37:
-		return "Bit Corp. Bit-79 Keyboard"
-	case 38:
-		return "Subor Keyboard"
-	case 39:
-		return "Subor Keyboard plus mouse (3x8-bit protocol)"
-	case 40:
-		return "Subor Keyboard plus mouse (24-bit protocol)"
-	case 41:
-		return "SNES Mouse ($4017.d0)"
-	case 42:
-		return "Multicart"
-	case 43:
-		return "Two SNES controllers replacing the two standard NES controllers"
-	case 44:
-		return "RacerMate Bicycle"
-	case 45:
-		return "U-Force"
-	case 46:
-		return "R.O.B. Stack-Up"
-	case 47:
-		return "City Patrolman Lightgun"
-	case 48:
-		return "Sharp C1 Cassette Interface"
-	case 49:
-		return "Standard Controller with swapped Left-Right/Up-Down/B-A"
-	case 50:
-		return "Excalibor Sudoku Pad"
-	case 51:
-		return "ABL Pinball"
-	case 52:
-		return "Golden Nugget Casino extra buttons"
-	default:
-		return "Unknown/Undefined"
-	}
+	return " (" + mapperInfo.Name + ", " + mapperInfo.BoardFamily + ")"
 }
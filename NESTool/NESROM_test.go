@@ -0,0 +1,115 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package NESTool
+
+import (
+	"testing"
+)
+
+// Build a headered ROM with a 16 KiB PRG ROM, an 8 KiB CHR ROM, and the
+// given bytes 7-15 of the header
+func getTestNESROMFile(headerTail []byte) []byte {
+	romFile := make([]byte, 16+16384+8192)
+	copy(romFile[0:4], []byte(NES_HEADER_MAGIC))
+	romFile[4] = 1
+	romFile[5] = 1
+	copy(romFile[7:16], headerTail)
+
+	return romFile
+}
+
+func TestUpdateSizesFactored(t *testing.T) {
+	tests := []struct {
+		name               string
+		header             *NES20Header
+		expectedPRGROMSize uint64
+		expectedCHRROMSize uint64
+	}{
+		{name: "sizes in units", header: &NES20Header{PRGROMSize: 2, CHRROMSize: 1}, expectedPRGROMSize: 32768, expectedCHRROMSize: 8192},
+		{name: "exponent form", header: &NES20Header{PRGROMSizeExponent: 10, PRGROMSizeMultiplier: 1, CHRROMSizeExponent: 9}, expectedPRGROMSize: 3072, expectedCHRROMSize: 512},
+		{name: "units before exponent form", header: &NES20Header{PRGROMSize: 1, PRGROMSizeExponent: 10, CHRROMSize: 2, CHRROMSizeExponent: 9}, expectedPRGROMSize: 16384, expectedCHRROMSize: 16384},
+		{name: "no sizes", header: &NES20Header{}, expectedPRGROMSize: 0, expectedCHRROMSize: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nesRom := &NESROM{Header20: test.header}
+
+			err := UpdateSizes(nesRom, PRG_CANONICAL_SIZE_FACTORED, CHR_CANONICAL_SIZE_FACTORED)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if nesRom.Header20.PRGROMCalculatedSize != test.expectedPRGROMSize {
+				t.Fatalf("expected a PRG ROM size of %d, got %d", test.expectedPRGROMSize, nesRom.Header20.PRGROMCalculatedSize)
+			}
+
+			if nesRom.Header20.CHRROMCalculatedSize != test.expectedCHRROMSize {
+				t.Fatalf("expected a CHR ROM size of %d, got %d", test.expectedCHRROMSize, nesRom.Header20.CHRROMCalculatedSize)
+			}
+		})
+	}
+}
+
+func TestDecodeNESROMHeaderType(t *testing.T) {
+	tests := []struct {
+		name               string
+		romFile            []byte
+		enableInes         bool
+		expectError        bool
+		expectedHeaderType uint8
+		expectedNES20      bool
+	}{
+		{name: "NES 2.0", romFile: getTestNESROMFile([]byte{0x08}), expectedHeaderType: NES_HEADER_TYPE_NES20, expectedNES20: true},
+		{name: "iNES", romFile: getTestNESROMFile(nil), enableInes: true, expectedHeaderType: NES_HEADER_TYPE_INES},
+		{name: "archaic iNES", romFile: getTestNESROMFile([]byte("DiskDude!")), enableInes: true, expectedHeaderType: NES_HEADER_TYPE_ARCHAIC_INES},
+		{name: "archaic iNES with NES 2.0 bits", romFile: getTestNESROMFile([]byte("H garbage")), enableInes: true, expectedHeaderType: NES_HEADER_TYPE_ARCHAIC_INES},
+		{name: "archaic iNES with NES 2.0 bits and iNES disabled", romFile: getTestNESROMFile([]byte("H garbage")), expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nesRom, err := DecodeNESROM(test.romFile, test.enableInes, false, "")
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if nesRom.HeaderType != test.expectedHeaderType {
+				t.Fatalf("expected header type %s, got %s", GetNESHeaderTypeString(test.expectedHeaderType), GetNESHeaderTypeString(nesRom.HeaderType))
+			}
+
+			if (nesRom.Header20 != nil) != test.expectedNES20 || (nesRom.Header10 != nil) == test.expectedNES20 {
+				t.Fatalf("expected NES 2.0 decoding to be %t", test.expectedNES20)
+			}
+
+			if test.expectedHeaderType == NES_HEADER_TYPE_ARCHAIC_INES && !CleanNESROMHeader(nesRom) {
+				t.Fatalf("expected the archaic header to be cleaned")
+			}
+		})
+	}
+}
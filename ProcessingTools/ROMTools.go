@@ -25,6 +25,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -241,6 +242,7 @@ func UpdateNESROM(targetRom *NESTool.NESROM, templateRom *NESTool.NESROM, trunca
 	targetRom.SHA1 = templateRom.SHA1
 	targetRom.MD5 = templateRom.MD5
 	targetRom.CRC32 = templateRom.CRC32
+	targetRom.HeaderSource = templateRom.HeaderSource
 
 	if truncateRom {
 		NESTool.TruncateROMDataAndSections(targetRom)
@@ -296,6 +298,39 @@ func MatchFDSROM(testRom *FDSTool.FDSArchiveFile, romList map[string]*FDSTool.FD
 		}
 	}
 
+	// Fall back to normalized hashes, if both sides have them, so copies that
+	// were rewritten by a disk writer still match
+	if testRom.HasNormalizedHashes() {
+		romKeys := make([]string, 0)
+		for key := range romList {
+			romKeys = append(romKeys, key)
+		}
+
+		sort.Strings(romKeys)
+
+		for _, key := range romKeys {
+			if !romList[key].HasNormalizedHashes() {
+				continue
+			}
+
+			if hashTypeTests&HASH_TYPE_SHA256 > 0 && romList[key].NormalizedSHA256 == testRom.NormalizedSHA256 {
+				return romList[key], nil
+			}
+
+			if hashTypeTests&HASH_TYPE_SHA1 > 0 && romList[key].NormalizedSHA1 == testRom.NormalizedSHA1 {
+				return romList[key], nil
+			}
+
+			if hashTypeTests&HASH_TYPE_MD5 > 0 && romList[key].NormalizedMD5 == testRom.NormalizedMD5 {
+				return romList[key], nil
+			}
+
+			if hashTypeTests&HASH_TYPE_CRC32 > 0 && romList[key].NormalizedCRC32 == testRom.NormalizedCRC32 {
+				return romList[key], nil
+			}
+		}
+	}
+
 	testRomCrc32Bytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(testRomCrc32Bytes, testRom.CRC32)
 	return nil, errors.New("No match found for FDS ROM: " + testRom.Name + "\nCRC32: " + strings.ToUpper(hex.EncodeToString(testRomCrc32Bytes)) + "\nSHA1: " + strings.ToUpper(hex.EncodeToString(testRom.SHA1[:])) + "\nSHA256: " + strings.ToUpper(hex.EncodeToString(testRom.SHA256[:])))
@@ -314,6 +349,8 @@ func UpdateFDSROM(targetRom *FDSTool.FDSArchiveFile, templateRom *FDSTool.FDSArc
 		targetRom.RelativePath = templateRom.RelativePath
 	}
 
+	targetRom.HeaderSource = templateRom.HeaderSource
+
 	return nil
 }
 
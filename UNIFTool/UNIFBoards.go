@@ -0,0 +1,296 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// https://www.nesdev.org/wiki/UNIF_to_NES_2.0_Mapping
+// Board names and the mappers they correspond to are taken from
+// the list above and the board list in FCEUX's UNIF loader.
+
+package UNIFTool
+
+import (
+	"NES20Tool/NESTool"
+	"strings"
+)
+
+type UNIFBoard struct {
+	Name       string
+	Mapper     uint16
+	SubMapper  uint8
+	WorkRAM    uint8
+	CHRRAMSize uint8
+}
+
+// Work RAM and CHR RAM sizes use the same shift counts as the NES 2.0
+// header (64 << n bytes).  Work RAM is treated as battery-backed if the
+// ROM has a BATR chunk.
+var unifBoards = []UNIFBoard{
+	{Name: "NES-NROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-NROM-128", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-NROM-256", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-HROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-RROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-RROM-128", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-RTROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-STROM", Mapper: 0, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-UNROM", Mapper: 2, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-UOROM", Mapper: 2, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-CNROM", Mapper: 3, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-AMROM", Mapper: 7, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-ANROM", Mapper: 7, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-AN1ROM", Mapper: 7, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-AOROM", Mapper: 7, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-CPROM", Mapper: 13, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 8},
+	{Name: "NES-BNROM", Mapper: 34, SubMapper: 2, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-GNROM", Mapper: 66, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-MHROM", Mapper: 66, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-NTBROM", Mapper: 68, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "SUNSOFT_UNROM", Mapper: 93, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-SAROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-SBROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SCROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SC1ROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SEROM", Mapper: 1, SubMapper: 5, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SFROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SGROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-SHROM", Mapper: 1, SubMapper: 5, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SH1ROM", Mapper: 1, SubMapper: 5, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SIROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-SJROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-SKROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-SLROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SL1ROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SL2ROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SL3ROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SLRROM", Mapper: 1, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-SNROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 7},
+	{Name: "NES-SOROM", Mapper: 1, SubMapper: 0, WorkRAM: 8, CHRRAMSize: 7},
+	{Name: "NES-SUROM", Mapper: 1, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 7},
+	{Name: "NES-SXROM", Mapper: 1, SubMapper: 0, WorkRAM: 9, CHRRAMSize: 7},
+	{Name: "NES-PNROM", Mapper: 9, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-PEEOROM", Mapper: 9, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-FJROM", Mapper: 10, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-FKROM", Mapper: 10, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-TBROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TEROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TFROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TGROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-TKROM", Mapper: 4, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-TLROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TL1ROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TL2ROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TNROM", Mapper: 4, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 7},
+	{Name: "NES-TR1ROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-TSROM", Mapper: 4, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-TVROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TXROM", Mapper: 4, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-HKROM", Mapper: 4, SubMapper: 1, WorkRAM: 4, CHRRAMSize: 0},
+	{Name: "NES-TLSROM", Mapper: 118, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-TKSROM", Mapper: 118, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-TQROM", Mapper: 119, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "NES-DEROM", Mapper: 206, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-DE1ROM", Mapper: 206, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-DEIROM", Mapper: 206, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-DRROM", Mapper: 206, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-EKROM", Mapper: 5, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "NES-ELROM", Mapper: 5, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "NES-ETROM", Mapper: 5, SubMapper: 0, WorkRAM: 8, CHRRAMSize: 0},
+	{Name: "NES-EWROM", Mapper: 5, SubMapper: 0, WorkRAM: 9, CHRRAMSize: 0},
+	{Name: "NES-EVENT", Mapper: 105, SubMapper: 0, WorkRAM: 7, CHRRAMSize: 0},
+	{Name: "UNROM-512-8", Mapper: 30, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 7},
+	{Name: "UNROM-512-16", Mapper: 30, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 8},
+	{Name: "UNROM-512-32", Mapper: 30, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 9},
+	{Name: "COOLGIRL", Mapper: 342, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-10-24-C-A1", Mapper: 327, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-11160", Mapper: 299, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-12-IN-1", Mapper: 331, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-158B", Mapper: 258, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-190in1", Mapper: 300, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-22211", Mapper: 132, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-411120-C", Mapper: 287, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-42in1ResetSwitch", Mapper: 233, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-60311C", Mapper: 289, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-603-5052", Mapper: 238, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-64in1NoRepeat", Mapper: 314, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-70in1", Mapper: 236, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-70in1B", Mapper: 236, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-80013-B", Mapper: 274, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-810544-C-A1", Mapper: 261, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-8157", Mapper: 301, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-8237", Mapper: 215, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-8237A", Mapper: 215, SubMapper: 1, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-830118C", Mapper: 348, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-A65AS", Mapper: 285, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-A9746", Mapper: 219, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-AC08", Mapper: 42, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-AX-40G", Mapper: 527, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-AX5705", Mapper: 530, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-BB", Mapper: 108, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-BJ-56", Mapper: 526, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-BMW8544", Mapper: 292, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-BS-5", Mapper: 286, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-CC-21", Mapper: 27, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-CITYFIGHT", Mapper: 266, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-CTC-09", Mapper: 335, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-D1038", Mapper: 59, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-DREAMTECH01", Mapper: 521, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-DRIPGAME", Mapper: 284, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-EDU2000", Mapper: 329, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-EH8813A", Mapper: 519, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-F-15", Mapper: 259, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "FARID_SLROM_8-IN-1", Mapper: 323, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "FARID_UNROM_8-IN-1", Mapper: 324, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-FK23C", Mapper: 176, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-FK23CA", Mapper: 176, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-FS304", Mapper: 162, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-G-146", Mapper: 349, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-Ghostbusters63in1", Mapper: 226, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-GK-192", Mapper: 58, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-GKCXIN1", Mapper: 288, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-GN-45", Mapper: 366, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-GS-2004", Mapper: 283, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-GS-2013", Mapper: 283, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-H2288", Mapper: 123, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-HPxx", Mapper: 260, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-K-3046", Mapper: 336, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KOF97", Mapper: 263, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "KONAMI-QTAI", Mapper: 547, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7010", Mapper: 554, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7012", Mapper: 346, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7013B", Mapper: 312, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7016", Mapper: 306, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7017", Mapper: 303, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7030", Mapper: 347, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7031", Mapper: 305, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7032", Mapper: 142, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7037", Mapper: 307, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-KS7057", Mapper: 302, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-LH32", Mapper: 125, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-LH53", Mapper: 535, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-MALISB", Mapper: 325, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BTL-MARIO1-MALEE2", Mapper: 55, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-N625092", Mapper: 221, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-NovelDiamond9999999in1", Mapper: 201, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-NTD-03", Mapper: 290, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-RESETTXROM", Mapper: 313, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-RT-01", Mapper: 328, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-0036", Mapper: 149, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-0037", Mapper: 148, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-016-1M", Mapper: 146, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-72007", Mapper: 145, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-72008", Mapper: 133, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-9602B", Mapper: 513, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SA-NROM", Mapper: 143, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-SA005-A", Mapper: 338, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-Sachen-74LS374N", Mapper: 150, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-Sachen-8259A", Mapper: 141, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-Sachen-8259B", Mapper: 138, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-Sachen-8259C", Mapper: 139, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-Sachen-8259D", Mapper: 137, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SHERO", Mapper: 262, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SL1632", Mapper: 14, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-SMB2J", Mapper: 304, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-Super24in1SC03", Mapper: 176, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-SuperHIK8in1", Mapper: 45, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-T-230", Mapper: 529, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-T-262", Mapper: 265, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-TC-U01-1.5M", Mapper: 147, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-TEK90", Mapper: 90, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-TF1201", Mapper: 298, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-TH2131-1", Mapper: 308, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-TJ-03", Mapper: 341, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "BMC-WS", Mapper: 332, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+	{Name: "UNL-YOKO", Mapper: 264, SubMapper: 0, WorkRAM: 0, CHRRAMSize: 0},
+}
+
+// Look up a UNIF board by its MAPR name.  The NES-, HVC-, UNL-, BMC-
+// and BTL- prefixes are ignored, as is case.  Returns nil if the
+// board isn't known.
+func GetUNIFBoard(boardName string) *UNIFBoard {
+	tempName := getBareUNIFBoardName(boardName)
+
+	for index := range unifBoards {
+		if getBareUNIFBoardName(unifBoards[index].Name) == tempName {
+			tempBoard := unifBoards[index]
+			return &tempBoard
+		}
+	}
+
+	return nil
+}
+
+// Find the UNIF board that best fits an NES 2.0 header, preferring
+// boards with the same submapper and RAM sizes.  Returns nil if no
+// known board uses the mapper.
+func GetUNIFBoardForHeader(header *NESTool.NES20Header, hasChrRom bool) *UNIFBoard {
+	workRam := header.PRGRAMSize
+	if header.Battery {
+		workRam = header.PRGNVRAMSize
+	}
+
+	var chrRam uint8 = 0
+	if !hasChrRom {
+		chrRam = header.CHRRAMSize
+	}
+
+	var bestBoard *UNIFBoard
+	bestScore := -1
+
+	for index := range unifBoards {
+		if unifBoards[index].Mapper != header.Mapper {
+			continue
+		}
+
+		score := 0
+		if unifBoards[index].SubMapper == header.SubMapper {
+			score = score + 4
+		}
+
+		if unifBoards[index].WorkRAM == workRam {
+			score = score + 2
+		}
+
+		// Boards listed without CHR RAM are CHR ROM boards
+		if hasChrRom && unifBoards[index].CHRRAMSize == 0 {
+			score = score + 1
+		} else if !hasChrRom && unifBoards[index].CHRRAMSize == chrRam {
+			score = score + 1
+		}
+
+		if score > bestScore {
+			tempBoard := unifBoards[index]
+			bestBoard = &tempBoard
+			bestScore = score
+		}
+	}
+
+	return bestBoard
+}
+
+func getBareUNIFBoardName(boardName string) string {
+	tempName := strings.ToUpper(strings.TrimSpace(strings.TrimRight(boardName, "\x00")))
+
+	for _, prefix := range []string{"NES-", "HVC-", "UNL-", "BMC-", "BTL-"} {
+		if strings.HasPrefix(tempName, prefix) {
+			return tempName[len(prefix):]
+		}
+	}
+
+	return tempName
+}
@@ -0,0 +1,397 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package UNIFTool
+
+import (
+	"NES20Tool/NESTool"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// UNIF chunks in the order they appear in the file.  Chunk IDs can
+// repeat, in which case the last chunk with a given ID is the one
+// used when decoding the ROM.
+type UNIFChunkList []*NESTool.UNIFChunk
+
+// Get the last chunk with a given ID, or nil if there isn't one
+func (chunks UNIFChunkList) Get(chunkId string) *NESTool.UNIFChunk {
+	var tempChunk *NESTool.UNIFChunk
+
+	for index := range chunks {
+		if chunks[index].ID == chunkId {
+			tempChunk = chunks[index]
+		}
+	}
+
+	return tempChunk
+}
+
+// Get every chunk with a given ID, in file order
+func (chunks UNIFChunkList) GetAll(chunkId string) []*NESTool.UNIFChunk {
+	tempChunks := make([]*NESTool.UNIFChunk, 0)
+
+	for index := range chunks {
+		if chunks[index].ID == chunkId {
+			tempChunks = append(tempChunks, chunks[index])
+		}
+	}
+
+	return tempChunks
+}
+
+// Get the data for the last chunk with a given ID, or nil if there isn't one
+func (chunks UNIFChunkList) GetData(chunkId string) []byte {
+	tempChunk := chunks.Get(chunkId)
+	if tempChunk == nil {
+		return nil
+	}
+
+	return tempChunk.Data
+}
+
+// MAPR: board name
+func (chunks UNIFChunkList) BoardName() string {
+	return getUNIFString(chunks.GetData("MAPR"))
+}
+
+// NAME: game name
+func (chunks UNIFChunkList) Name() string {
+	return getUNIFString(chunks.GetData("NAME"))
+}
+
+// READ: free-form comments
+func (chunks UNIFChunkList) Readme() string {
+	return getUNIFString(chunks.GetData("READ"))
+}
+
+// DINF: dumper name, dump date and dumping agent
+func (chunks UNIFChunkList) DumperInfo() *NESTool.UNIFDumperInfo {
+	chunkData := chunks.GetData("DINF")
+	if chunkData == nil {
+		return nil
+	}
+
+	return DecodeUNIFDumperInfo(chunkData)
+}
+
+// TVCI: 0 for NTSC, 1 for PAL, 2 for either
+func (chunks UNIFChunkList) TVSystem() (uint8, bool) {
+	return getUNIFByte(chunks.GetData("TVCI"))
+}
+
+// CTRL: bitfield of supported controllers
+func (chunks UNIFChunkList) Controllers() (uint8, bool) {
+	return getUNIFByte(chunks.GetData("CTRL"))
+}
+
+// MIRR: mirroring type
+func (chunks UNIFChunkList) Mirroring() (uint8, bool) {
+	return getUNIFByte(chunks.GetData("MIRR"))
+}
+
+// BATR: the presence of the chunk means the board has a battery
+func (chunks UNIFChunkList) HasBattery() bool {
+	return chunks.Get("BATR") != nil
+}
+
+// VROR: the presence of the chunk means CHR ROM is treated as RAM
+func (chunks UNIFChunkList) HasVRAMOverride() bool {
+	return chunks.Get("VROR") != nil
+}
+
+// Decode a DINF chunk.  The chunk is a 100-byte dumper name, then
+// the day, month and little-endian year of the dump, then a 100-byte
+// dumping agent name.  Truncated chunks are decoded as far as possible.
+func DecodeUNIFDumperInfo(chunkData []byte) *NESTool.UNIFDumperInfo {
+	dumperInfo := &NESTool.UNIFDumperInfo{}
+
+	dumperInfo.DumperName = getUNIFString(chunkData[0:minUNIFOffset(100, len(chunkData))])
+
+	if len(chunkData) >= 104 {
+		dumperInfo.DumpDay = chunkData[100]
+		dumperInfo.DumpMonth = chunkData[101]
+		dumperInfo.DumpYear = binary.LittleEndian.Uint16(chunkData[102:104])
+		dumperInfo.DumpingAgent = getUNIFString(chunkData[104:minUNIFOffset(204, len(chunkData))])
+	}
+
+	return dumperInfo
+}
+
+// Encode dumper information as a 204-byte DINF chunk
+func EncodeUNIFDumperInfo(dumperInfo *NESTool.UNIFDumperInfo) []byte {
+	chunkData := make([]byte, 204)
+
+	// Leave room for the terminating null in each string
+	copy(chunkData[0:99], []byte(dumperInfo.DumperName))
+	chunkData[100] = dumperInfo.DumpDay
+	chunkData[101] = dumperInfo.DumpMonth
+	binary.LittleEndian.PutUint16(chunkData[102:104], dumperInfo.DumpYear)
+	copy(chunkData[104:203], []byte(dumperInfo.DumpingAgent))
+
+	return chunkData
+}
+
+// Get the NES 2.0 CPU/PPU timing for a TVCI value
+func GetCPUPPUTimingForUNIFTVSystem(tvSystem uint8) (uint8, bool) {
+	switch tvSystem {
+	case UNIF_TV_SYSTEM_NTSC:
+		return 0, true
+	case UNIF_TV_SYSTEM_PAL:
+		return 1, true
+	case UNIF_TV_SYSTEM_DUAL:
+		return 2, true
+	default:
+		return 0, false
+	}
+}
+
+// Get the TVCI value for an NES 2.0 CPU/PPU timing.  Dendy timing
+// has no TVCI equivalent.
+func GetUNIFTVSystemForCPUPPUTiming(cpuPpuTiming uint8) (uint8, bool) {
+	switch cpuPpuTiming {
+	case 0:
+		return UNIF_TV_SYSTEM_NTSC, true
+	case 1:
+		return UNIF_TV_SYSTEM_PAL, true
+	case 2:
+		return UNIF_TV_SYSTEM_DUAL, true
+	default:
+		return 0, false
+	}
+}
+
+func minUNIFOffset(offset int, dataLength int) int {
+	if dataLength < offset {
+		return dataLength
+	}
+
+	return offset
+}
+
+// PRG0 through PRGF
+func (chunks UNIFChunkList) PRGData(chunkIndex int) []byte {
+	return chunks.GetData("PRG" + getUNIFChunkIndexString(chunkIndex))
+}
+
+// CHR0 through CHRF
+func (chunks UNIFChunkList) CHRData(chunkIndex int) []byte {
+	return chunks.GetData("CHR" + getUNIFChunkIndexString(chunkIndex))
+}
+
+// PCK0 through PCKF
+func (chunks UNIFChunkList) PRGChecksum(chunkIndex int) (uint32, bool) {
+	return chunks.getChecksum("PCK" + getUNIFChunkIndexString(chunkIndex))
+}
+
+// CCK0 through CCKF
+func (chunks UNIFChunkList) CHRChecksum(chunkIndex int) (uint32, bool) {
+	return chunks.getChecksum("CCK" + getUNIFChunkIndexString(chunkIndex))
+}
+
+func (chunks UNIFChunkList) getChecksum(chunkId string) (uint32, bool) {
+	chunkData := chunks.GetData(chunkId)
+	if len(chunkData) < 4 {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint32(chunkData[0:4]), true
+}
+
+func (chunks UNIFChunkList) String() string {
+	returnString := "UNIF Chunks: " + strconv.Itoa(len(chunks)) + "\n"
+
+	for index := range chunks {
+		returnString = returnString + "  " + chunks[index].ID + ": " + strconv.FormatUint(chunks[index].Size, 10) + " bytes"
+
+		chunkValue := GetUNIFChunkValueString(chunks[index])
+		if chunkValue != "" {
+			returnString = returnString + ", " + chunkValue
+		}
+
+		if !IsKnownUNIFChunkID(chunks[index].ID) {
+			returnString = returnString + " (unknown chunk)"
+		} else if chunks.Get(chunks[index].ID) != chunks[index] {
+			returnString = returnString + " (duplicate, superseded by a later chunk)"
+		}
+
+		returnString = returnString + "\n"
+	}
+
+	return returnString
+}
+
+// Get a short, human-readable description of a chunk's contents.
+// ROM chunks are described by their CRC32 rather than their data.
+func GetUNIFChunkValueString(chunk *NESTool.UNIFChunk) string {
+	if IsUNIFROMChunk(chunk.ID) {
+		crc32Bytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc32Bytes, chunk.CRC32)
+		return "CRC32 " + strings.ToUpper(hex.EncodeToString(crc32Bytes))
+	}
+
+	switch chunk.ID {
+	case "MAPR", "NAME", "READ":
+		return getUNIFString(chunk.Data)
+	case "DINF":
+		dumperInfo := DecodeUNIFDumperInfo(chunk.Data)
+		return strings.TrimSpace(strings.Join([]string{dumperInfo.DumperName, dumperInfo.GetDumpDateString(), dumperInfo.DumpingAgent}, " "))
+	case "PCK0", "PCK1", "PCK2", "PCK3", "PCK4", "PCK5", "PCK6", "PCK7", "PCK8", "PCK9", "PCKA", "PCKB", "PCKC", "PCKD", "PCKE", "PCKF",
+		"CCK0", "CCK1", "CCK2", "CCK3", "CCK4", "CCK5", "CCK6", "CCK7", "CCK8", "CCK9", "CCKA", "CCKB", "CCKC", "CCKD", "CCKE", "CCKF":
+		checksum, hasChecksum := UNIFChunkList{chunk}.getChecksum(chunk.ID)
+		if !hasChecksum {
+			return ""
+		}
+
+		crc32Bytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc32Bytes, checksum)
+		return strings.ToUpper(hex.EncodeToString(crc32Bytes))
+	case "MIRR":
+		mirroring, hasMirroring := getUNIFByte(chunk.Data)
+		if !hasMirroring {
+			return ""
+		}
+
+		return getUNIFMirroringString(mirroring)
+	case "TVCI":
+		tvSystem, hasTvSystem := getUNIFByte(chunk.Data)
+		if !hasTvSystem {
+			return ""
+		}
+
+		return GetUNIFTVSystemString(tvSystem)
+	case "CTRL":
+		controllers, hasControllers := getUNIFByte(chunk.Data)
+		if !hasControllers {
+			return ""
+		}
+
+		return getUNIFControllersString(controllers)
+	default:
+		if len(chunk.Data) > 0 && len(chunk.Data) <= 16 {
+			return strings.ToUpper(hex.EncodeToString(chunk.Data))
+		}
+
+		return ""
+	}
+}
+
+// Whether a chunk ID is defined by any version of the UNIF specification
+func IsKnownUNIFChunkID(chunkId string) bool {
+	return IsValidChunkNameForUnifVersion(UNIF_ENCODE_VERSION, chunkId)
+}
+
+// PRGn and CHRn chunks hold ROM data
+func IsUNIFROMChunk(chunkId string) bool {
+	if len(chunkId) != 4 || (chunkId[0:3] != "PRG" && chunkId[0:3] != "CHR") {
+		return false
+	}
+
+	return strings.Contains("0123456789ABCDEF", chunkId[3:4])
+}
+
+func getUNIFChunkIndexString(chunkIndex int) string {
+	return strings.ToUpper(strconv.FormatInt(int64(chunkIndex), 16))
+}
+
+// Strings in UNIF chunks are null-terminated
+func getUNIFString(chunkData []byte) string {
+	nullIndex := strings.IndexByte(string(chunkData), '\x00')
+	if nullIndex >= 0 {
+		return string(chunkData[0:nullIndex])
+	}
+
+	return string(chunkData)
+}
+
+func getUNIFByte(chunkData []byte) (uint8, bool) {
+	if len(chunkData) < 1 {
+		return 0, false
+	}
+
+	return chunkData[0], true
+}
+
+func getUNIFMirroringString(mirroring uint8) string {
+	switch mirroring {
+	case 0:
+		return "Horizontal"
+	case 1:
+		return "Vertical"
+	case 2:
+		return "Single-screen ($2000)"
+	case 3:
+		return "Single-screen ($2400)"
+	case 4:
+		return "Four-screen"
+	case 5:
+		return "Mapper-controlled"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func GetUNIFTVSystemString(tvSystem uint8) string {
+	switch tvSystem {
+	case UNIF_TV_SYSTEM_NTSC:
+		return "NTSC"
+	case UNIF_TV_SYSTEM_PAL:
+		return "PAL"
+	case UNIF_TV_SYSTEM_DUAL:
+		return "NTSC or PAL"
+	default:
+		return "Unknown/Undefined"
+	}
+}
+
+func getUNIFControllersString(controllers uint8) string {
+	controllerNames := make([]string, 0)
+
+	if controllers&UNIF_CONTROLLER_JOYPAD > 0 {
+		controllerNames = append(controllerNames, "Joypad")
+	}
+
+	if controllers&UNIF_CONTROLLER_ZAPPER > 0 {
+		controllerNames = append(controllerNames, "Zapper")
+	}
+
+	if controllers&UNIF_CONTROLLER_ROB > 0 {
+		controllerNames = append(controllerNames, "R.O.B.")
+	}
+
+	if controllers&UNIF_CONTROLLER_ARKANOID > 0 {
+		controllerNames = append(controllerNames, "Arkanoid Controller")
+	}
+
+	if controllers&UNIF_CONTROLLER_POWER_PAD > 0 {
+		controllerNames = append(controllerNames, "Power Pad")
+	}
+
+	if controllers&UNIF_CONTROLLER_FOUR_SCORE > 0 {
+		controllerNames = append(controllerNames, "Four Score")
+	}
+
+	if len(controllerNames) == 0 {
+		return "None"
+	}
+
+	return strings.Join(controllerNames, ", ")
+}
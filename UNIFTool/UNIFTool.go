@@ -32,7 +32,22 @@ import (
 )
 
 var (
-	UNIF_MAGIC = "UNIF"
+	UNIF_MAGIC                        = "UNIF"
+	UNIF_DUMPING_AGENT                = "NES20Tool"
+	UNIF_ENCODE_VERSION        uint32 = 7
+	UNIF_ROM_CHUNK_SIZE        uint64 = 524288
+	UNIF_MIRRORING_HORIZONTAL  uint8  = 0
+	UNIF_MIRRORING_VERTICAL    uint8  = 1
+	UNIF_MIRRORING_FOUR_SCREEN uint8  = 4
+	UNIF_CONTROLLER_JOYPAD     uint8  = 0b00000001
+	UNIF_CONTROLLER_ZAPPER     uint8  = 0b00000010
+	UNIF_CONTROLLER_ROB        uint8  = 0b00000100
+	UNIF_CONTROLLER_ARKANOID   uint8  = 0b00001000
+	UNIF_CONTROLLER_POWER_PAD  uint8  = 0b00010000
+	UNIF_CONTROLLER_FOUR_SCORE uint8  = 0b00100000
+	UNIF_TV_SYSTEM_NTSC        uint8  = 0
+	UNIF_TV_SYSTEM_PAL         uint8  = 1
+	UNIF_TV_SYSTEM_DUAL        uint8  = 2
 )
 
 // Read a byte slice and copy the PRG, CHR, and base ROMs
@@ -60,14 +75,22 @@ func DecodeUNIFROM(inputFile []byte) (*NESTool.NESROM, error) {
 
 	tempRom := &NESTool.NESROM{}
 
-	if IsValidChunkNameForUnifVersion(unifVersion, "NAME") && unifChunks["NAME"] != nil {
-		tempRom.Name = string(unifChunks["NAME"][0 : len(unifChunks["NAME"])-1])
+	if IsValidChunkNameForUnifVersion(unifVersion, "NAME") && unifChunks.Get("NAME") != nil {
+		tempRom.Name = unifChunks.Name()
 	}
 
 	tempRom.PRGROMData = prgRomData
 	tempRom.CHRROMData = chrRomData
 	tempRom.ROMData = append(prgRomData, chrRomData...)
 	tempRom.Header20 = &NESTool.NES20Header{}
+	tempRom.UNIFVersion = unifVersion
+	tempRom.UNIFChunks = unifChunks
+
+	if IsValidChunkNameForUnifVersion(unifVersion, "DINF") {
+		tempRom.UNIFDumper = unifChunks.DumperInfo()
+	}
+
+	populateHeaderFromChunks(tempRom.Header20, unifChunks, unifVersion, len(chrRomData) == 0)
 
 	err = NESTool.UpdateSizes(tempRom, NESTool.PRG_CANONICAL_SIZE_ROM, NESTool.CHR_CANONICAL_SIZE_ROM)
 	if err != nil {
@@ -82,6 +105,238 @@ func DecodeUNIFROM(inputFile []byte) (*NESTool.NESROM, error) {
 	return tempRom, nil
 }
 
+// Turn an NESROM struct into a UNIF file.  UNIF has no place for
+// trainers or miscellaneous ROMs, so those are dropped.
+func EncodeUNIFROM(romModel *NESTool.NESROM) ([]byte, error) {
+	if romModel.PRGROMData == nil || len(romModel.PRGROMData) == 0 {
+		return nil, &NESTool.NESROMError{Text: "No PRG ROM data to encode in UNIF ROM."}
+	}
+
+	var header *NESTool.NES20Header
+	if romModel.Header20 != nil {
+		header = romModel.Header20
+	} else if romModel.Header10 != nil {
+		header = &NESTool.NES20Header{}
+		header.Mapper = uint16(romModel.Header10.Mapper)
+		header.MirroringType = romModel.Header10.MirroringType
+		header.FourScreen = romModel.Header10.FourScreen
+		header.Battery = romModel.Header10.Battery
+		if romModel.Header10.TVSystem {
+			header.CPUPPUTiming = 1
+		}
+		if header.Battery {
+			header.PRGNVRAMSize = 7
+		}
+		if len(romModel.CHRROMData) == 0 {
+			header.CHRRAMSize = 7
+		}
+	} else {
+		return nil, &NESTool.NESROMError{Text: "No header available to encode UNIF ROM."}
+	}
+
+	board := GetUNIFBoardForHeader(header, len(romModel.CHRROMData) > 0)
+	if board == nil {
+		return nil, &NESTool.NESROMError{Text: "No UNIF board is known for mapper " + strconv.Itoa(int(header.Mapper)) + "."}
+	}
+
+	unifBytes := make([]byte, 0)
+	unifBytes = append(unifBytes, []byte(UNIF_MAGIC)...)
+
+	versionBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(versionBytes, UNIF_ENCODE_VERSION)
+	unifBytes = append(unifBytes, versionBytes...)
+	unifBytes = append(unifBytes, make([]byte, 24)...)
+
+	unifBytes = append(unifBytes, encodeUNIFChunk("MAPR", append([]byte(board.Name), '\x00'))...)
+
+	if romModel.Name != "" {
+		unifBytes = append(unifBytes, encodeUNIFChunk("NAME", append([]byte(romModel.Name), '\x00'))...)
+	}
+
+	// Keep the original dumper name and date when they're known,
+	// but this tool is what's producing the file now
+	dumperInfo := &NESTool.UNIFDumperInfo{}
+	if romModel.UNIFDumper != nil {
+		dumperInfo.DumperName = romModel.UNIFDumper.DumperName
+		dumperInfo.DumpDay = romModel.UNIFDumper.DumpDay
+		dumperInfo.DumpMonth = romModel.UNIFDumper.DumpMonth
+		dumperInfo.DumpYear = romModel.UNIFDumper.DumpYear
+	}
+	dumperInfo.DumpingAgent = UNIF_DUMPING_AGENT
+	unifBytes = append(unifBytes, encodeUNIFChunk("DINF", EncodeUNIFDumperInfo(dumperInfo))...)
+
+	tvSystem, hasTvSystem := GetUNIFTVSystemForCPUPPUTiming(header.CPUPPUTiming)
+	if hasTvSystem {
+		unifBytes = append(unifBytes, encodeUNIFChunk("TVCI", []byte{tvSystem})...)
+	}
+
+	controllers := getUNIFControllers(header.DefaultExpansion)
+	if controllers != 0 {
+		unifBytes = append(unifBytes, encodeUNIFChunk("CTRL", []byte{controllers})...)
+	}
+
+	mirroring := UNIF_MIRRORING_HORIZONTAL
+	if header.FourScreen {
+		mirroring = UNIF_MIRRORING_FOUR_SCREEN
+	} else if header.MirroringType {
+		mirroring = UNIF_MIRRORING_VERTICAL
+	}
+
+	unifBytes = append(unifBytes, encodeUNIFChunk("MIRR", []byte{mirroring})...)
+
+	if header.Battery {
+		unifBytes = append(unifBytes, encodeUNIFChunk("BATR", []byte{'\x01'})...)
+	}
+
+	unifBytes = append(unifBytes, encodeUNIFRomChunks(romModel.PRGROMData, "PRG", "PCK")...)
+
+	if len(romModel.CHRROMData) > 0 {
+		unifBytes = append(unifBytes, encodeUNIFRomChunks(romModel.CHRROMData, "CHR", "CCK")...)
+	}
+
+	return unifBytes, nil
+}
+
+// Encode a single UNIF chunk with its ID and length
+func encodeUNIFChunk(chunkId string, chunkData []byte) []byte {
+	chunkBytes := make([]byte, 8)
+	copy(chunkBytes[0:4], []byte(chunkId))
+	binary.LittleEndian.PutUint32(chunkBytes[4:8], uint32(len(chunkData)))
+
+	return append(chunkBytes, chunkData...)
+}
+
+// Split a PRG or CHR ROM into numbered chunks, each followed by a
+// CRC32 chunk.  There can only be 16 chunks of each type, so the
+// chunk size grows for ROMs too large to fit otherwise.
+func encodeUNIFRomChunks(romData []byte, romType string, checksumType string) []byte {
+	chunkSize := UNIF_ROM_CHUNK_SIZE
+	romSize := uint64(len(romData))
+
+	if romSize > chunkSize*16 {
+		chunkSize = (romSize + 15) / 16
+	}
+
+	romBytes := make([]byte, 0)
+
+	for i := 0; uint64(i)*chunkSize < romSize; i++ {
+		chunkStart := uint64(i) * chunkSize
+		chunkEnd := chunkStart + chunkSize
+		if chunkEnd > romSize {
+			chunkEnd = romSize
+		}
+
+		chunkStr := strings.ToUpper(strconv.FormatInt(int64(i), 16))
+
+		crc32Bytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(crc32Bytes, crc32.ChecksumIEEE(romData[chunkStart:chunkEnd]))
+
+		romBytes = append(romBytes, encodeUNIFChunk(romType+chunkStr, romData[chunkStart:chunkEnd])...)
+		romBytes = append(romBytes, encodeUNIFChunk(checksumType+chunkStr, crc32Bytes)...)
+	}
+
+	return romBytes
+}
+
+// Get the UNIF controller flags for an NES 2.0 default expansion device
+func getUNIFControllers(defaultExpansion uint8) uint8 {
+	switch defaultExpansion {
+	case 0x01:
+		return UNIF_CONTROLLER_JOYPAD
+	case 0x02:
+		return UNIF_CONTROLLER_JOYPAD | UNIF_CONTROLLER_FOUR_SCORE
+	case 0x08:
+		return UNIF_CONTROLLER_JOYPAD | UNIF_CONTROLLER_ZAPPER
+	case 0x0b, 0x0c:
+		return UNIF_CONTROLLER_JOYPAD | UNIF_CONTROLLER_POWER_PAD
+	case 0x0f:
+		return UNIF_CONTROLLER_JOYPAD | UNIF_CONTROLLER_ARKANOID
+	case 0x1f:
+		return UNIF_CONTROLLER_JOYPAD | UNIF_CONTROLLER_ROB
+	default:
+		return 0
+	}
+}
+
+// Fill in the NES 2.0 header fields that can be inferred from the
+// MAPR, MIRR, BATR, TVCI and CTRL chunks
+func populateHeaderFromChunks(header *NESTool.NES20Header, unifChunks UNIFChunkList, unifVersion uint32, usesChrRam bool) {
+	if IsValidChunkNameForUnifVersion(unifVersion, "BATR") && unifChunks.HasBattery() {
+		header.Battery = true
+	}
+
+	var workRam uint8 = 0
+	var chrRam uint8 = 0
+
+	if IsValidChunkNameForUnifVersion(unifVersion, "MAPR") && unifChunks.Get("MAPR") != nil {
+		board := GetUNIFBoard(unifChunks.BoardName())
+		if board != nil {
+			header.Mapper = board.Mapper
+			header.SubMapper = board.SubMapper
+			workRam = board.WorkRAM
+			chrRam = board.CHRRAMSize
+		}
+	}
+
+	// Battery-backed boards almost always have 8 KiB of it
+	if header.Battery && workRam == 0 {
+		workRam = 7
+	}
+
+	if header.Battery {
+		header.PRGNVRAMSize = workRam
+	} else {
+		header.PRGRAMSize = workRam
+	}
+
+	// Boards without CHR ROM default to 8 KiB of CHR RAM
+	if usesChrRam {
+		if chrRam == 0 {
+			chrRam = 7
+		}
+
+		header.CHRRAMSize = chrRam
+	}
+
+	mirroring, hasMirroring := unifChunks.Mirroring()
+	if IsValidChunkNameForUnifVersion(unifVersion, "MIRR") && hasMirroring {
+		switch mirroring {
+		case UNIF_MIRRORING_HORIZONTAL:
+			header.MirroringType = false
+		case UNIF_MIRRORING_VERTICAL:
+			header.MirroringType = true
+		case UNIF_MIRRORING_FOUR_SCREEN:
+			header.FourScreen = true
+		}
+	}
+
+	tvSystem, hasTvSystem := unifChunks.TVSystem()
+	if IsValidChunkNameForUnifVersion(unifVersion, "TVCI") && hasTvSystem {
+		cpuPpuTiming, hasCpuPpuTiming := GetCPUPPUTimingForUNIFTVSystem(tvSystem)
+		if hasCpuPpuTiming {
+			header.CPUPPUTiming = cpuPpuTiming
+		}
+	}
+
+	// Only the most specific controller can be represented in the header
+	controllers, hasControllers := unifChunks.Controllers()
+	if IsValidChunkNameForUnifVersion(unifVersion, "CTRL") && hasControllers {
+		if controllers&UNIF_CONTROLLER_FOUR_SCORE > 0 {
+			header.DefaultExpansion = 0x02
+		} else if controllers&UNIF_CONTROLLER_POWER_PAD > 0 {
+			header.DefaultExpansion = 0x0b
+		} else if controllers&UNIF_CONTROLLER_ARKANOID > 0 {
+			header.DefaultExpansion = 0x0f
+		} else if controllers&UNIF_CONTROLLER_ROB > 0 {
+			header.DefaultExpansion = 0x1f
+		} else if controllers&UNIF_CONTROLLER_ZAPPER > 0 {
+			header.DefaultExpansion = 0x08
+		} else if controllers&UNIF_CONTROLLER_JOYPAD > 0 {
+			header.DefaultExpansion = 0x01
+		}
+	}
+}
+
 // Validate UNIF header
 func IsValidUNIFROM(inputData []byte) (bool, error) {
 	// UNIF header
@@ -103,14 +358,14 @@ func GetUNIFVersion(inputData []byte) (uint32, error) {
 	return binary.LittleEndian.Uint32(inputData[4:8]), nil
 }
 
-// Get each of the UNIF chunks in the file
-func GetUNIFChunks(inputData []byte) (map[string][]byte, error) {
+// Get each of the UNIF chunks in the file, in the order they appear
+func GetUNIFChunks(inputData []byte) (UNIFChunkList, error) {
 	_, err := IsValidUNIFROM(inputData)
 	if err != nil {
 		return nil, err
 	}
 
-	unifChunks := make(map[string][]byte, 0)
+	unifChunks := make(UNIFChunkList, 0)
 
 	// Skipping the four-byte UNIF version number and the reserved bytes
 	romPosition := uint64(32)
@@ -121,7 +376,7 @@ func GetUNIFChunks(inputData []byte) (map[string][]byte, error) {
 			return nil, err
 		}
 
-		unifChunks[chunkId] = chunkData
+		unifChunks = append(unifChunks, &NESTool.UNIFChunk{ID: chunkId, Size: uint64(len(chunkData)), CRC32: crc32.ChecksumIEEE(chunkData), Data: chunkData})
 
 		romPosition = tempPosition
 	}
@@ -173,7 +428,7 @@ func getNextUNIFChunk(inputData []byte, position uint64) (string, []byte, uint64
 }
 
 // Build a PRG or CHR ROM from a map of UNIF chunks
-func getRomData(unifChunks map[string][]byte, unifVersion uint32, romType string, checksumType string) ([]byte, error) {
+func getRomData(unifChunks UNIFChunkList, unifVersion uint32, romType string, checksumType string) ([]byte, error) {
 	romData := make([]byte, 0)
 
 	// PRG and CHR chunks are named PRG0, PRG1, etc. through PRGF.
@@ -185,16 +440,16 @@ func getRomData(unifChunks map[string][]byte, unifVersion uint32, romType string
 	// we can only hard fail if one exists for a chunk and is a mismatch.
 	for i := 0; i < 16; i++ {
 		chunkStr := strings.ToUpper(strconv.FormatInt(int64(i), 16))
-		if IsValidChunkNameForUnifVersion(unifVersion, romType+chunkStr) && unifChunks[romType+chunkStr] != nil {
-			if IsValidChunkNameForUnifVersion(unifVersion, checksumType+chunkStr) && unifChunks[checksumType+chunkStr] != nil {
-				calculatedCrc32 := crc32.ChecksumIEEE(unifChunks[romType+chunkStr])
-				referenceCrc32 := binary.LittleEndian.Uint32(unifChunks[checksumType+chunkStr])
+		if IsValidChunkNameForUnifVersion(unifVersion, romType+chunkStr) && unifChunks.Get(romType+chunkStr) != nil {
+			chunkData := unifChunks.GetData(romType + chunkStr)
 
-				if calculatedCrc32 != referenceCrc32 {
+			if IsValidChunkNameForUnifVersion(unifVersion, checksumType+chunkStr) {
+				referenceCrc32, hasChecksum := unifChunks.getChecksum(checksumType + chunkStr)
+				if hasChecksum && crc32.ChecksumIEEE(chunkData) != referenceCrc32 {
 					return nil, &NESTool.NESROMError{Text: "Checksum mismatch for chunk " + romType + chunkStr}
 				}
 			}
-			romData = append(romData, unifChunks[romType+chunkStr]...)
+			romData = append(romData, chunkData...)
 		}
 	}
 
@@ -305,6 +560,10 @@ func GetValidChunkNamesForUnifVersion(unifVersion uint32) []string {
 		chunkNames = append(chunkNames, "MIRR")
 	}
 
+	if unifVersion >= 6 {
+		chunkNames = append(chunkNames, "TVCI")
+	}
+
 	if unifVersion >= 7 {
 		chunkNames = append(chunkNames, "CTRL")
 	}
@@ -0,0 +1,284 @@
+/*
+   Copyright 2021-2022, Christopher Gelatt
+
+   This file is part of NESTool.
+
+   NESTool is free software: you can redistribute it and/or modify
+   it under the terms of the GNU Affero General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   NESTool is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU Affero General Public License for more details.
+
+   You should have received a copy of the GNU Affero General Public License
+   along with NESTool.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package UNIFTool
+
+import (
+	"NES20Tool/NESTool"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// Build a UNIF file with the given version and pre-encoded chunks
+func getTestUNIFFile(unifVersion uint32, chunks ...[]byte) []byte {
+	unifBytes := make([]byte, 32)
+	copy(unifBytes[0:4], []byte(UNIF_MAGIC))
+	binary.LittleEndian.PutUint32(unifBytes[4:8], unifVersion)
+
+	for index := range chunks {
+		unifBytes = append(unifBytes, chunks[index]...)
+	}
+
+	return unifBytes
+}
+
+func getTestUNIFChecksumChunk(chunkId string, chunkData []byte) []byte {
+	crc32Bytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(crc32Bytes, crc32.ChecksumIEEE(chunkData))
+
+	return encodeUNIFChunk(chunkId, crc32Bytes)
+}
+
+func getTestUNIFData(size int, value byte) []byte {
+	return bytes.Repeat([]byte{value}, size)
+}
+
+func TestDecodeUNIFROM(t *testing.T) {
+	prg0 := getTestUNIFData(16384, 0x10)
+	prg1 := getTestUNIFData(16384, 0x11)
+	chr0 := getTestUNIFData(8192, 0x20)
+
+	tests := []struct {
+		name                 string
+		unifFile             []byte
+		expectError          bool
+		expectedPRGROMData   []byte
+		expectedCHRROMData   []byte
+		expectedMapper       uint16
+		expectedCPUPPUTiming uint8
+		expectedName         string
+	}{
+		{
+			name:               "PRG and CHR chunks",
+			unifFile:           getTestUNIFFile(7, encodeUNIFChunk("MAPR", []byte("NES-CNROM\x00")), encodeUNIFChunk("PRG0", prg0), encodeUNIFChunk("CHR0", chr0)),
+			expectedPRGROMData: prg0,
+			expectedCHRROMData: chr0,
+			expectedMapper:     3,
+		},
+		{
+			name:               "PRG chunks out of order",
+			unifFile:           getTestUNIFFile(7, encodeUNIFChunk("PRG1", prg1), encodeUNIFChunk("PRG0", prg0)),
+			expectedPRGROMData: append(append([]byte{}, prg0...), prg1...),
+			expectedCHRROMData: []byte{},
+		},
+		{
+			name:               "repeated chunk uses the last one",
+			unifFile:           getTestUNIFFile(7, encodeUNIFChunk("NAME", []byte("First\x00")), encodeUNIFChunk("PRG0", prg1), encodeUNIFChunk("NAME", []byte("Second\x00")), encodeUNIFChunk("PRG0", prg0)),
+			expectedPRGROMData: prg0,
+			expectedCHRROMData: []byte{},
+			expectedName:       "Second",
+		},
+		{
+			name:               "matching checksum",
+			unifFile:           getTestUNIFFile(7, encodeUNIFChunk("PRG0", prg0), getTestUNIFChecksumChunk("PCK0", prg0)),
+			expectedPRGROMData: prg0,
+			expectedCHRROMData: []byte{},
+		},
+		{
+			name:        "mismatched checksum",
+			unifFile:    getTestUNIFFile(7, encodeUNIFChunk("PRG0", prg0), getTestUNIFChecksumChunk("PCK0", prg1)),
+			expectError: true,
+		},
+		{
+			name:               "checksum ignored before version 5",
+			unifFile:           getTestUNIFFile(4, encodeUNIFChunk("PRG0", prg0), getTestUNIFChecksumChunk("PCK0", prg1)),
+			expectedPRGROMData: prg0,
+			expectedCHRROMData: []byte{},
+		},
+		{
+			name:                 "PAL TV system",
+			unifFile:             getTestUNIFFile(7, encodeUNIFChunk("PRG0", prg0), encodeUNIFChunk("TVCI", []byte{UNIF_TV_SYSTEM_PAL})),
+			expectedPRGROMData:   prg0,
+			expectedCHRROMData:   []byte{},
+			expectedCPUPPUTiming: 1,
+		},
+		{
+			name:               "TV system ignored before version 6",
+			unifFile:           getTestUNIFFile(5, encodeUNIFChunk("PRG0", prg0), encodeUNIFChunk("TVCI", []byte{UNIF_TV_SYSTEM_PAL})),
+			expectedPRGROMData: prg0,
+			expectedCHRROMData: []byte{},
+		},
+		{
+			name:        "not a UNIF file",
+			unifFile:    append([]byte("NES\x1a"), make([]byte, 32)...),
+			expectError: true,
+		},
+		{
+			name:        "chunk length past the end of the file",
+			unifFile:    getTestUNIFFile(7, encodeUNIFChunk("PRG0", prg0)[0:1024]),
+			expectError: true,
+		},
+		{
+			name:        "truncated chunk header",
+			unifFile:    getTestUNIFFile(7, []byte("PRG")),
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rom, err := DecodeUNIFROM(test.unifFile)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(rom.PRGROMData, test.expectedPRGROMData) {
+				t.Fatalf("expected %d bytes of PRG ROM, got %d", len(test.expectedPRGROMData), len(rom.PRGROMData))
+			}
+
+			if !bytes.Equal(rom.CHRROMData, test.expectedCHRROMData) {
+				t.Fatalf("expected %d bytes of CHR ROM, got %d", len(test.expectedCHRROMData), len(rom.CHRROMData))
+			}
+
+			if rom.Header20.Mapper != test.expectedMapper {
+				t.Fatalf("expected mapper %d, got %d", test.expectedMapper, rom.Header20.Mapper)
+			}
+
+			if rom.Header20.CPUPPUTiming != test.expectedCPUPPUTiming {
+				t.Fatalf("expected CPU/PPU timing %d, got %d", test.expectedCPUPPUTiming, rom.Header20.CPUPPUTiming)
+			}
+
+			if rom.Name != test.expectedName {
+				t.Fatalf("expected name %q, got %q", test.expectedName, rom.Name)
+			}
+		})
+	}
+}
+
+func TestGetUNIFChunks(t *testing.T) {
+	unifFile := getTestUNIFFile(7, encodeUNIFChunk("MAPR", []byte("NES-NROM\x00")), encodeUNIFChunk("READ", []byte("One\x00")), encodeUNIFChunk("PRG0", []byte{1}), encodeUNIFChunk("READ", []byte("Two\x00")))
+
+	unifChunks, err := GetUNIFChunks(unifFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedIds := []string{"MAPR", "READ", "PRG0", "READ"}
+	if len(unifChunks) != len(expectedIds) {
+		t.Fatalf("expected %d chunks, got %d", len(expectedIds), len(unifChunks))
+	}
+
+	for index := range expectedIds {
+		if unifChunks[index].ID != expectedIds[index] {
+			t.Fatalf("expected chunk %d to be %s, got %s", index, expectedIds[index], unifChunks[index].ID)
+		}
+	}
+
+	if len(unifChunks.GetAll("READ")) != 2 {
+		t.Fatalf("expected 2 READ chunks, got %d", len(unifChunks.GetAll("READ")))
+	}
+
+	if unifChunks.Readme() != "Two" {
+		t.Fatalf("expected the last READ chunk, got %q", unifChunks.Readme())
+	}
+
+	if unifChunks.Get("CHR0") != nil {
+		t.Fatalf("expected no CHR0 chunk")
+	}
+}
+
+func TestDecodeUNIFDumperInfo(t *testing.T) {
+	fullInfo := make([]byte, 204)
+	copy(fullInfo[0:100], []byte("Dumper"))
+	fullInfo[100] = 17
+	fullInfo[101] = 6
+	binary.LittleEndian.PutUint16(fullInfo[102:104], 2004)
+	copy(fullInfo[104:204], []byte("Agent"))
+
+	tests := []struct {
+		name     string
+		dinf     []byte
+		expected NESTool.UNIFDumperInfo
+	}{
+		{name: "full chunk", dinf: fullInfo, expected: NESTool.UNIFDumperInfo{DumperName: "Dumper", DumpDay: 17, DumpMonth: 6, DumpYear: 2004, DumpingAgent: "Agent"}},
+		{name: "truncated agent", dinf: fullInfo[0:106], expected: NESTool.UNIFDumperInfo{DumperName: "Dumper", DumpDay: 17, DumpMonth: 6, DumpYear: 2004, DumpingAgent: "Ag"}},
+		{name: "truncated date", dinf: fullInfo[0:102], expected: NESTool.UNIFDumperInfo{DumperName: "Dumper"}},
+		{name: "truncated name", dinf: fullInfo[0:3], expected: NESTool.UNIFDumperInfo{DumperName: "Dum"}},
+		{name: "empty chunk", dinf: []byte{}, expected: NESTool.UNIFDumperInfo{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dumperInfo := DecodeUNIFDumperInfo(test.dinf)
+			if *dumperInfo != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, *dumperInfo)
+			}
+		})
+	}
+}
+
+func TestEncodeUNIFROM(t *testing.T) {
+	romModel := &NESTool.NESROM{
+		Name:       "Test",
+		PRGROMData: getTestUNIFData(32768, 0x10),
+		CHRROMData: getTestUNIFData(8192, 0x20),
+		Header20:   &NESTool.NES20Header{Mapper: 3, MirroringType: true, CPUPPUTiming: 2},
+		UNIFDumper: &NESTool.UNIFDumperInfo{DumperName: "Dumper", DumpDay: 17, DumpMonth: 6, DumpYear: 2004, DumpingAgent: "Agent"},
+	}
+
+	unifFile, err := EncodeUNIFROM(romModel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rom, err := DecodeUNIFROM(unifFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rom.UNIFVersion != UNIF_ENCODE_VERSION {
+		t.Fatalf("expected UNIF version %d, got %d", UNIF_ENCODE_VERSION, rom.UNIFVersion)
+	}
+
+	if rom.Name != romModel.Name {
+		t.Fatalf("expected name %q, got %q", romModel.Name, rom.Name)
+	}
+
+	if !bytes.Equal(rom.PRGROMData, romModel.PRGROMData) || !bytes.Equal(rom.CHRROMData, romModel.CHRROMData) {
+		t.Fatalf("ROM data changed in the round trip")
+	}
+
+	if rom.Header20.Mapper != 3 || !rom.Header20.MirroringType || rom.Header20.CPUPPUTiming != 2 {
+		t.Fatalf("expected mapper 3, vertical mirroring and timing 2, got mapper %d, mirroring %t and timing %d", rom.Header20.Mapper, rom.Header20.MirroringType, rom.Header20.CPUPPUTiming)
+	}
+
+	if rom.UNIFDumper == nil || rom.UNIFDumper.DumperName != "Dumper" || rom.UNIFDumper.DumpYear != 2004 || rom.UNIFDumper.DumpingAgent != UNIF_DUMPING_AGENT {
+		t.Fatalf("unexpected dumper info: %+v", rom.UNIFDumper)
+	}
+
+	_, err = EncodeUNIFROM(&NESTool.NESROM{PRGROMData: romModel.PRGROMData, Header20: &NESTool.NES20Header{Mapper: 4095}})
+	if err == nil {
+		t.Fatalf("expected an error for an unknown mapper, got none")
+	}
+
+	_, err = EncodeUNIFROM(&NESTool.NESROM{Header20: &NESTool.NES20Header{}})
+	if err == nil {
+		t.Fatalf("expected an error for a ROM without PRG ROM, got none")
+	}
+}